@@ -77,7 +77,7 @@ func (tr *KafkaTracer) StartConsumeSpan(msg Message) ddtrace.Span {
 	}
 	// kafka supports headers, so try to extract a span context
 	carrier := MessageCarrier{msg: msg}
-	if spanctx, err := tracer.Extract(carrier); err == nil {
+	if spanctx, err := ExtractSpanContext(carrier); err == nil {
 		// If there are span links as a result of context extraction, add them as a StartSpanOption
 		if linksCtx, ok := spanctx.(ddtrace.SpanContextWithLinks); ok && linksCtx.SpanLinks() != nil {
 			opts = append(opts, tracer.WithSpanLinks(linksCtx.SpanLinks()))
@@ -86,6 +86,6 @@ func (tr *KafkaTracer) StartConsumeSpan(msg Message) ddtrace.Span {
 	}
 	span, _ := tracer.StartSpanFromContext(tr.ctx, tr.consumerSpanName, opts...)
 	// reinject the span context so consumers can pick it up
-	tracer.Inject(span.Context(), carrier)
+	injectSpanContext(span.Context(), carrier, tr.binaryPropagation)
 	return span
 }
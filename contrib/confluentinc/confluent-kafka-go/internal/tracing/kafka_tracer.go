@@ -30,6 +30,7 @@ type KafkaTracer struct {
 	groupID             string
 	tagFns              map[string]func(msg Message) interface{}
 	dsmEnabled          bool
+	binaryPropagation   bool
 	ckgoVersion         CKGoVersion
 	librdKafkaVersion   int
 }
@@ -140,3 +141,13 @@ func WithDataStreams() Option {
 		tr.dsmEnabled = true
 	}
 }
+
+// WithBinaryPropagation propagates the span context in the compact binary form of
+// tracer.InjectBinary, in a single message header, instead of text headers. Consumers
+// must be instrumented by a version of this package able to extract it, which is done
+// regardless of this option.
+func WithBinaryPropagation() Option {
+	return func(tr *KafkaTracer) {
+		tr.binaryPropagation = true
+	}
+}
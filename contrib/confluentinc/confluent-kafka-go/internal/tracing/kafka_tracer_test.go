@@ -9,6 +9,7 @@ import (
 	"math"
 	"testing"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 
 	"github.com/stretchr/testify/assert"
@@ -65,3 +66,63 @@ func TestAnalyticsSettings(t *testing.T) {
 		assert.Equal(t, 0.2, tr.analyticsRate)
 	})
 }
+
+type testMessage struct {
+	headers []Header
+}
+
+func (*testMessage) GetValue() []byte                  { return nil }
+func (*testMessage) GetKey() []byte                    { return nil }
+func (m *testMessage) GetHeaders() []Header            { return m.headers }
+func (m *testMessage) SetHeaders(h []Header)           { m.headers = h }
+func (*testMessage) GetTopicPartition() TopicPartition { return testTopicPartition{} }
+func (m *testMessage) Unwrap() any                     { return m }
+
+type testTopicPartition struct{}
+
+func (testTopicPartition) GetTopic() string    { return "topic" }
+func (testTopicPartition) GetPartition() int32 { return 0 }
+func (testTopicPartition) GetOffset() int64    { return 0 }
+func (testTopicPartition) GetError() error     { return nil }
+
+func TestBinaryPropagation(t *testing.T) {
+	tracer.Start(tracer.WithLogStartup(false))
+	defer tracer.Stop()
+
+	msg := new(testMessage)
+	pspan := NewKafkaTracer(0, 0, WithBinaryPropagation()).StartProduceSpan(msg)
+	defer pspan.Finish()
+
+	carrier := NewMessageCarrier(msg)
+	assert.Len(t, msg.headers, 1)
+	assert.NotNil(t, carrier.Binary())
+	ctx, err := ExtractSpanContext(carrier)
+	assert.NoError(t, err)
+	assert.Equal(t, pspan.Context().SpanID(), ctx.SpanID())
+
+	// the consumer extracts the binary context, and replaces it with its own
+	// in text headers
+	csp := NewKafkaTracer(0, 0).StartConsumeSpan(msg)
+	defer csp.Finish()
+	assert.Equal(t, pspan.Context().TraceID(), csp.Context().TraceID())
+	assert.Nil(t, carrier.Binary())
+	ctx, err = ExtractSpanContext(carrier)
+	assert.NoError(t, err)
+	assert.Equal(t, csp.Context().SpanID(), ctx.SpanID())
+}
+
+func TestBinaryPropagationCorrupted(t *testing.T) {
+	tracer.Start(tracer.WithLogStartup(false))
+	defer tracer.Stop()
+
+	// a corrupted binary context is ignored in favor of the text headers
+	msg := new(testMessage)
+	carrier := NewMessageCarrier(msg)
+	span := tracer.StartSpan("kafka.produce")
+	defer span.Finish()
+	assert.NoError(t, tracer.Inject(span.Context(), carrier))
+	carrier.SetBinary([]byte{1, 2, 3})
+	ctx, err := ExtractSpanContext(carrier)
+	assert.NoError(t, err)
+	assert.Equal(t, span.Context().SpanID(), ctx.SpanID())
+}
@@ -5,7 +5,11 @@
 
 package tracing
 
-import "gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+import (
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
 
 // A MessageCarrier implements TextMapReader/TextMapWriter for extracting/injecting traces on a kafka.msg
 type MessageCarrier struct {
@@ -28,6 +32,37 @@ func (c MessageCarrier) ForeachKey(handler func(key, val string) error) error {
 	return nil
 }
 
+// SetBinary sets the binary form of a span context, as encoded by tracer.InjectBinary.
+func (c MessageCarrier) SetBinary(data []byte) {
+	c.Set(tracer.BinaryContextKey, string(data))
+}
+
+// Binary returns the binary form of a span context, as encoded by tracer.InjectBinary,
+// or nil if the message doesn't hold one.
+func (c MessageCarrier) Binary() []byte {
+	for _, h := range c.msg.GetHeaders() {
+		if h.GetKey() == tracer.BinaryContextKey {
+			return h.GetValue()
+		}
+	}
+	return nil
+}
+
+// removeBinary removes the binary form of a span context from the message.
+func (c MessageCarrier) removeBinary() {
+	if c.Binary() == nil {
+		return
+	}
+	headers := c.msg.GetHeaders()
+	for i := 0; i < len(headers); i++ {
+		if headers[i].GetKey() == tracer.BinaryContextKey {
+			headers = append(headers[:i], headers[i+1:]...)
+			i--
+		}
+	}
+	c.msg.SetHeaders(headers)
+}
+
 // Set implements TextMapWriter
 func (c MessageCarrier) Set(key, val string) {
 	headers := c.msg.GetHeaders()
@@ -48,3 +83,32 @@ func (c MessageCarrier) Set(key, val string) {
 func NewMessageCarrier(msg Message) MessageCarrier {
 	return MessageCarrier{msg: msg}
 }
+
+// ExtractSpanContext extracts the span context of the message from its binary form
+// if the message holds a valid one, and from its text headers otherwise.
+func ExtractSpanContext(carrier MessageCarrier) (ddtrace.SpanContext, error) {
+	if data := carrier.Binary(); data != nil {
+		ctx, err := tracer.ExtractBinary(data)
+		if err == nil {
+			return ctx, nil
+		}
+		log.Debug("contrib/confluentinc/confluent-kafka-go: Failed to extract the binary span context, falling back to the text headers: %v", err)
+	}
+	return tracer.Extract(carrier)
+}
+
+// injectSpanContext injects ctx in the message, in binary form if binary is set
+// and ctx supports it, and in text headers otherwise.
+func injectSpanContext(ctx ddtrace.SpanContext, carrier MessageCarrier, binary bool) error {
+	if binary {
+		if data, err := tracer.InjectBinary(ctx); err == nil {
+			if data != nil {
+				carrier.SetBinary(data)
+			}
+			return nil
+		}
+	}
+	// a stale binary context would take precedence over the text headers
+	carrier.removeBinary()
+	return tracer.Inject(ctx, carrier)
+}
@@ -67,7 +67,7 @@ func (tr *KafkaTracer) StartProduceSpan(msg Message) ddtrace.Span {
 	}
 	// if there's a span context in the headers, use that as the parent
 	carrier := NewMessageCarrier(msg)
-	if spanctx, err := tracer.Extract(carrier); err == nil {
+	if spanctx, err := ExtractSpanContext(carrier); err == nil {
 		// If there are span links as a result of context extraction, add them as a StartSpanOption
 		if linksCtx, ok := spanctx.(ddtrace.SpanContextWithLinks); ok && linksCtx.SpanLinks() != nil {
 			opts = append(opts, tracer.WithSpanLinks(linksCtx.SpanLinks()))
@@ -76,7 +76,7 @@ func (tr *KafkaTracer) StartProduceSpan(msg Message) ddtrace.Span {
 	}
 	span, _ := tracer.StartSpanFromContext(tr.ctx, tr.producerSpanName, opts...)
 	// inject the span context so consumers can pick it up
-	tracer.Inject(span.Context(), carrier)
+	injectSpanContext(span.Context(), carrier, tr.binaryPropagation)
 	return span
 }
 
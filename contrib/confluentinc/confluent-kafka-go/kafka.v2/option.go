@@ -48,3 +48,9 @@ func WithConfig(cm *kafka.ConfigMap) Option {
 
 // WithDataStreams enables the Data Streams monitoring product features: https://www.datadoghq.com/product/data-streams-monitoring/
 var WithDataStreams = tracing.WithDataStreams
+
+// WithBinaryPropagation propagates the span context in the compact binary form of
+// tracer.InjectBinary, in a single message header, instead of text headers. Consumers
+// must be instrumented by a version of this package able to extract it, which is done
+// regardless of this option. Use ExtractSpanContext to extract it from messages.
+var WithBinaryPropagation = tracing.WithBinaryPropagation
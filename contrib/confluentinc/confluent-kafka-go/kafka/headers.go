@@ -9,6 +9,7 @@ import (
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/contrib/confluentinc/confluent-kafka-go/internal/tracing"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 )
 
 // A MessageCarrier injects and extracts traces from a kafka.Message.
@@ -18,3 +19,9 @@ type MessageCarrier = tracing.MessageCarrier
 func NewMessageCarrier(msg *kafka.Message) MessageCarrier {
 	return tracing.NewMessageCarrier(wrapMessage(msg))
 }
+
+// ExtractSpanContext retrieves the SpanContext from a kafka.Message, whether it
+// was propagated in text headers or with WithBinaryPropagation.
+func ExtractSpanContext(msg *kafka.Message) (ddtrace.SpanContext, error) {
+	return tracing.ExtractSpanContext(NewMessageCarrier(msg))
+}
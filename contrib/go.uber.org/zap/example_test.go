@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package zap_test
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	zaptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/go.uber.org/zap"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+func ExampleWrapCore() {
+	// start the DataDog tracer
+	tracer.Start()
+	defer tracer.Stop()
+
+	// create the application logger
+	logger, _ := zap.NewProduction(zap.WrapCore(zaptrace.WrapCore))
+	defer logger.Sync()
+
+	// start a new span
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "ExampleWrapCore")
+	defer span.Finish()
+
+	// log a message passing the context containing span information
+	logger.Info("this is a log with tracing information", zaptrace.Context(ctx))
+}
+
+func ExampleTraceFields() {
+	// start the DataDog tracer
+	tracer.Start()
+	defer tracer.Stop()
+
+	// create the application logger
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	// start a new span
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "ExampleTraceFields")
+	defer span.Finish()
+
+	// log a message with the tracing information of the span
+	logger.Info("this is a log with tracing information", zaptrace.TraceFields(ctx)...)
+}
@@ -0,0 +1,124 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+// Package zap provides functions to correlate logs and traces using the go.uber.org/zap package (https://github.com/uber-go/zap).
+package zap // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/go.uber.org/zap"
+
+import (
+	"context"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/logtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const componentName = "go.uber.org/zap"
+
+func init() {
+	telemetry.LoadIntegration(componentName)
+	tracer.MarkIntegrationImported("go.uber.org/zap")
+}
+
+// contextKey is the key of the field returned by Context.
+const contextKey = "dd.context"
+
+// Context returns a field holding ctx, which the cores returned by WrapCore
+// replace with the trace fields of the span found in ctx. Other cores ignore it.
+//
+//	logger.Info("message", zaptrace.Context(ctx))
+//	logger.With(zaptrace.Context(ctx)).Info("message")
+func Context(ctx context.Context) zap.Field {
+	return zap.Field{Key: contextKey, Type: zapcore.SkipType, Interface: ctx}
+}
+
+// TraceFields returns the fields correlating a log with the span found in ctx:
+// dd.trace_id, dd.span_id, dd.service, dd.env and dd.version. It returns nil if
+// there's no span in ctx. It can be used with any logger:
+//
+//	logger.Info("message", zaptrace.TraceFields(ctx)...)
+func TraceFields(ctx context.Context) []zap.Field {
+	f, ok := logtrace.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	var fields []zap.Field
+	f.ForEach(func(k, v string) {
+		fields = append(fields, zap.String(k, v))
+	})
+	return fields
+}
+
+// WrapCore returns a core adding the trace fields to the entries written to c.
+// The span is the one found in the context of the Context field of the entry or
+// of the logger, or the active span when automatic instrumentation is enabled.
+func WrapCore(c zapcore.Core) zapcore.Core {
+	if _, ok := c.(interface{ DDTraceCorrelated() }); ok {
+		return c
+	}
+	return &core{Core: c}
+}
+
+// NewLogger returns a logger writing to the core c wrapped with WrapCore.
+func NewLogger(c zapcore.Core, opts ...zap.Option) *zap.Logger {
+	return zap.New(WrapCore(c), opts...)
+}
+
+type core struct {
+	zapcore.Core
+	ctx context.Context // context of the Context field added with With, if any
+}
+
+var _ zapcore.Core = (*core)(nil)
+
+// DDTraceCorrelated marks the core as already adding the trace fields, so that
+// it isn't wrapped twice, including by automatic instrumentation.
+func (*core) DDTraceCorrelated() {}
+
+// With adds the fields to the wrapped core, keeping the context of the last
+// Context field.
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	ctx := c.ctx
+	if fctx, ok := contextOf(fields); ok {
+		ctx = fctx
+	}
+	return &core{Core: c.Core.With(fields), ctx: ctx}
+}
+
+// Check adds c to the checked entry if the entry's level is enabled.
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write writes the entry with its fields and the trace fields to the wrapped core.
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ctx := c.ctx
+	if fctx, ok := contextOf(fields); ok {
+		ctx = fctx
+	}
+	if tf := TraceFields(ctx); len(tf) > 0 {
+		fields = append(fields[:len(fields):len(fields)], tf...)
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// contextOf returns the context of the last Context field of fields.
+func contextOf(fields []zapcore.Field) (context.Context, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		f := fields[i]
+		if f.Type != zapcore.SkipType || f.Key != contextKey {
+			continue
+		}
+		if ctx, ok := f.Interface.(context.Context); ok {
+			return ctx, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package zap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	internallog "gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+func newTestLogger(b *bytes.Buffer) *zap.Logger {
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	return NewLogger(zapcore.NewCore(enc, zapcore.AddSync(b), zapcore.DebugLevel))
+}
+
+func logEntries(t *testing.T, b *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		t.Log(line)
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestWrapCore(t *testing.T) {
+	t.Setenv("DD_ENV", "prod")
+	t.Setenv("DD_VERSION", "1.2.3")
+	tracer.Start(tracer.WithService("svc"), tracer.WithLogger(internallog.DiscardLogger{}))
+	defer tracer.Stop()
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "test")
+	defer span.Finish()
+	traceID := strconv.FormatUint(span.Context().TraceID(), 10)
+	spanID := strconv.FormatUint(span.Context().SpanID(), 10)
+
+	t.Run("context-field", func(t *testing.T) {
+		var b bytes.Buffer
+		logger := newTestLogger(&b)
+		logger.Info("with span", Context(ctx), zap.String("key", "value"))
+		logger.Info("without span", Context(context.Background()))
+		logger.Info("without context")
+
+		entries := logEntries(t, &b)
+		require.Len(t, entries, 3)
+		assert.Equal(t, traceID, entries[0][ext.LogKeyTraceID])
+		assert.Equal(t, spanID, entries[0][ext.LogKeySpanID])
+		assert.Equal(t, "svc", entries[0][ext.LogKeyService])
+		assert.Equal(t, "prod", entries[0][ext.LogKeyEnv])
+		assert.Equal(t, "1.2.3", entries[0][ext.LogKeyVersion])
+		assert.Equal(t, "value", entries[0]["key"])
+		assert.NotContains(t, entries[0], contextKey)
+		for _, e := range entries[1:] {
+			assert.NotContains(t, e, ext.LogKeyTraceID)
+			assert.NotContains(t, e, ext.LogKeySpanID)
+		}
+	})
+
+	t.Run("with", func(t *testing.T) {
+		var b bytes.Buffer
+		logger := newTestLogger(&b).With(Context(ctx))
+		logger.Info("with span")
+		logger.Info("without span", Context(context.Background()))
+
+		entries := logEntries(t, &b)
+		require.Len(t, entries, 2)
+		assert.Equal(t, traceID, entries[0][ext.LogKeyTraceID])
+		assert.Equal(t, spanID, entries[0][ext.LogKeySpanID])
+		assert.NotContains(t, entries[1], ext.LogKeyTraceID)
+	})
+
+	t.Run("level", func(t *testing.T) {
+		var b bytes.Buffer
+		enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+		logger := NewLogger(zapcore.NewCore(enc, zapcore.AddSync(&b), zapcore.InfoLevel))
+		logger.Debug("disabled", Context(ctx))
+		assert.Zero(t, b.Len())
+	})
+
+	t.Run("wrapped-once", func(t *testing.T) {
+		c := WrapCore(zapcore.NewNopCore())
+		assert.Same(t, c, WrapCore(c))
+	})
+}
+
+func TestTraceFields(t *testing.T) {
+	tracer.Start(tracer.WithLogger(internallog.DiscardLogger{}))
+	defer tracer.Stop()
+
+	assert.Nil(t, TraceFields(context.Background()))
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "test", tracer.WithSpanID(1234))
+	defer span.Finish()
+	var b bytes.Buffer
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	logger := zap.New(zapcore.NewCore(enc, zapcore.AddSync(&b), zapcore.DebugLevel))
+	logger.Info("message", TraceFields(ctx)...)
+
+	entries := logEntries(t, &b)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "1234", entries[0][ext.LogKeyTraceID])
+	assert.Equal(t, "1234", entries[0][ext.LogKeySpanID])
+}
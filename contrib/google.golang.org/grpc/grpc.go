@@ -19,6 +19,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
 
 	"google.golang.org/grpc/codes"
@@ -70,7 +71,7 @@ func startSpanFromContext(
 		tracer.Tag(ext.RPCService, methodElements[0]),
 	)
 	md, _ := metadata.FromIncomingContext(ctx) // nil is ok
-	if sctx, err := tracer.Extract(grpcutil.MDCarrier(md)); err == nil {
+	if sctx, err := extractSpanContext(md); err == nil {
 		// If there are span links as a result of context extraction, add them as a StartSpanOption
 		if linksCtx, ok := sctx.(ddtrace.SpanContextWithLinks); ok && linksCtx.SpanLinks() != nil {
 			opts = append(opts, tracer.WithSpanLinks(linksCtx.SpanLinks()))
@@ -80,6 +81,19 @@ func startSpanFromContext(
 	return tracer.StartSpanFromContext(ctx, operation, opts...)
 }
 
+// extractSpanContext extracts the span context from the binary metadata set by
+// WithBinaryPropagation if present and valid, and from the text metadata otherwise.
+func extractSpanContext(md metadata.MD) (ddtrace.SpanContext, error) {
+	if v := md.Get(tracer.BinaryContextKey); len(v) > 0 {
+		ctx, err := tracer.ExtractBinary([]byte(v[0]))
+		if err == nil {
+			return ctx, nil
+		}
+		log.Debug("contrib/google.golang.org/grpc: Failed to extract the binary span context, falling back to the text metadata: %v", err)
+	}
+	return tracer.Extract(grpcutil.MDCarrier(md))
+}
+
 // finishWithError applies finish option and a tag with gRPC status code, disregarding OK, EOF and Canceled errors.
 func finishWithError(span ddtrace.Span, err error, cfg *config) {
 	if errors.Is(err, io.EOF) || errors.Is(err, context.Canceled) {
@@ -18,6 +18,7 @@ import (
 	"testing"
 	"time"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/google.golang.org/internal/grpcutil"
 	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/lists"
 	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/namingschematest"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
@@ -1330,3 +1331,41 @@ func TestIssue2050(t *testing.T) {
 		return
 	}
 }
+
+func TestBinaryPropagation(t *testing.T) {
+	tracer.Start(tracer.WithLogger(testLogger{t}), tracer.WithHTTPClient(&http.Client{Transport: &roundTripper{
+		assertSpanFromRequest: func(*http.Request) {},
+	}}))
+	defer tracer.Stop()
+
+	rig, err := newRig(true, WithBinaryPropagation())
+	require.NoError(t, err)
+	defer rig.Close()
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "a", tracer.ServiceName("b"))
+	defer span.Finish()
+	_, err = rig.client.Ping(ctx, &FixtureRequest{Name: "pass"})
+	require.NoError(t, err)
+
+	md := rig.fixtureServer.lastRequestMetadata.Load().(metadata.MD)
+	assert.Empty(t, md.Get(tracer.DefaultTraceIDHeader))
+	bin := md.Get(tracer.BinaryContextKey)
+	require.Len(t, bin, 1)
+
+	// the server extracts the context of the client span
+	sctx, err := extractSpanContext(md)
+	require.NoError(t, err)
+	assert.Equal(t, span.Context().TraceID(), sctx.TraceID())
+	assert.NotEqual(t, span.Context().SpanID(), sctx.SpanID())
+
+	// a corrupted binary context is ignored in favor of the text metadata
+	md = metadata.Pairs(tracer.BinaryContextKey, "\x01\x02\x03")
+	require.NoError(t, tracer.Inject(span.Context(), grpcutil.MDCarrier(md)))
+	sctx, err = extractSpanContext(md)
+	require.NoError(t, err)
+	assert.Equal(t, span.Context().SpanID(), sctx.SpanID())
+}
+
+type testLogger struct{ t *testing.T }
+
+func (l testLogger) Log(msg string) { l.t.Log(msg) }
@@ -38,6 +38,7 @@ type config struct {
 	ignoredMetadata     map[string]struct{}
 	withRequestTags     bool
 	withErrorDetailTags bool
+	binaryPropagation   bool
 	spanOpts            []ddtrace.StartSpanOption
 	tags                map[string]interface{}
 }
@@ -59,6 +60,7 @@ func defaults(cfg *config) {
 		"x-datadog-trace-id":          {},
 		"x-datadog-parent-id":         {},
 		"x-datadog-sampling-priority": {},
+		tracer.BinaryContextKey:       {},
 	}
 }
 
@@ -203,6 +205,16 @@ func WithErrorDetailTags() Option {
 	}
 }
 
+// WithBinaryPropagation specifies whether the span context should be propagated
+// to servers in the compact binary form of tracer.InjectBinary, as binary gRPC
+// metadata, instead of text metadata. Servers must be instrumented by a version
+// of this package able to extract it, which is done regardless of this option.
+func WithBinaryPropagation() Option {
+	return func(cfg *config) {
+		cfg.binaryPropagation = true
+	}
+}
+
 // WithCustomTag will attach the value to the span tagged by the key.
 func WithCustomTag(key string, value interface{}) Option {
 	return func(cfg *config) {
@@ -38,7 +38,7 @@ func (h *clientStatsHandler) TagRPC(ctx context.Context, rti *stats.RPCTagInfo)
 		h.cfg.serviceName,
 		spanOpts...,
 	)
-	ctx = injectSpanIntoContext(ctx)
+	ctx = injectSpanIntoContext(ctx, h.cfg)
 	return ctx
 }
 
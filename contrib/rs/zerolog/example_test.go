@@ -0,0 +1,27 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package zerolog
+
+import (
+	"context"
+	"os"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/rs/zerolog"
+)
+
+func ExampleDDContextLogHook() {
+	// Ensure your tracer is started and stopped
+	// Setup zerolog, do this once at the beginning of your program
+	logger := zerolog.New(os.Stdout).Hook(DDContextLogHook{})
+
+	span, sctx := tracer.StartSpanFromContext(context.Background(), "mySpan")
+	defer span.Finish()
+
+	// Pass the current span context to the logger
+	logger.Info().Ctx(sctx).Msg("Completed some work!")
+}
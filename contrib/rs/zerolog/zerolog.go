@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+// Package zerolog provides a log/span correlation hook for the rs/zerolog package (https://github.com/rs/zerolog).
+package zerolog // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/rs/zerolog"
+
+import (
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/logtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
+
+	"github.com/rs/zerolog"
+)
+
+const componentName = "rs/zerolog"
+
+func init() {
+	telemetry.LoadIntegration(componentName)
+	tracer.MarkIntegrationImported("github.com/rs/zerolog")
+}
+
+// DDContextLogHook ensures that any span in the context of the event, as set with
+// zerolog.Event.Ctx or zerolog.Context.Ctx, is correlated to log output.
+type DDContextLogHook struct{}
+
+var _ zerolog.Hook = DDContextLogHook{}
+
+// Run implements zerolog.Hook, adding the trace fields of the span found in the
+// context of e: dd.trace_id, dd.span_id, dd.service, dd.env and dd.version.
+func (DDContextLogHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	f, ok := logtrace.FromContext(e.GetCtx())
+	if !ok {
+		return
+	}
+	f.ForEach(func(k, v string) {
+		e.Str(k, v)
+	})
+}
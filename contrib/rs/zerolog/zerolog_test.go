@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package zerolog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	internallog "gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	t.Setenv("DD_ENV", "prod")
+	t.Setenv("DD_VERSION", "1.2.3")
+	tracer.Start(tracer.WithService("svc"), tracer.WithLogger(internallog.DiscardLogger{}))
+	defer tracer.Stop()
+	span, sctx := tracer.StartSpanFromContext(context.Background(), "testSpan", tracer.WithSpanID(1234))
+	defer span.Finish()
+
+	var b bytes.Buffer
+	logger := zerolog.New(&b).Hook(DDContextLogHook{})
+
+	t.Run("event", func(t *testing.T) {
+		defer b.Reset()
+		logger.Info().Ctx(sctx).Msg("message")
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+		assert.Equal(t, "1234", entry[ext.LogKeyTraceID])
+		assert.Equal(t, "1234", entry[ext.LogKeySpanID])
+		assert.Equal(t, "svc", entry[ext.LogKeyService])
+		assert.Equal(t, "prod", entry[ext.LogKeyEnv])
+		assert.Equal(t, "1.2.3", entry[ext.LogKeyVersion])
+	})
+
+	t.Run("logger", func(t *testing.T) {
+		defer b.Reset()
+		l := logger.With().Ctx(sctx).Logger()
+		l.Info().Msg("message")
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+		assert.Equal(t, "1234", entry[ext.LogKeyTraceID])
+		assert.Equal(t, "1234", entry[ext.LogKeySpanID])
+	})
+
+	t.Run("no-span", func(t *testing.T) {
+		defer b.Reset()
+		logger.Info().Msg("message")
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+		assert.NotContains(t, entry, ext.LogKeyTraceID)
+		assert.NotContains(t, entry, ext.LogKeySpanID)
+	})
+}
@@ -10,10 +10,10 @@ import (
 
 	"gopkg.in/DataDog/dd-trace-go.v1/contrib/segmentio/kafka.go.v0/internal/tracing"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
-	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 )
 
-// ExtractSpanContext retrieves the SpanContext from a kafka.Message
+// ExtractSpanContext retrieves the SpanContext from a kafka.Message, whether it
+// was propagated in text headers or with WithBinaryPropagation.
 func ExtractSpanContext(msg kafka.Message) (ddtrace.SpanContext, error) {
-	return tracer.Extract(tracing.NewMessageCarrier(wrapMessage(&msg)))
+	return tracing.ExtractSpanContext(tracing.NewMessageCarrier(wrapMessage(&msg)))
 }
@@ -21,6 +21,7 @@ type Tracer struct {
 	producerSpanName    string
 	analyticsRate       float64
 	dataStreamsEnabled  bool
+	binaryPropagation   bool
 	kafkaCfg            KafkaConfig
 }
 
@@ -87,3 +88,13 @@ func WithDataStreams() Option {
 		tr.dataStreamsEnabled = true
 	}
 }
+
+// WithBinaryPropagation propagates the span context in the compact binary form of
+// tracer.InjectBinary, in a single message header, instead of text headers. Readers
+// must be instrumented by a version of this package able to extract it, which is done
+// regardless of this option.
+func WithBinaryPropagation() Option {
+	return func(tr *Tracer) {
+		tr.binaryPropagation = true
+	}
+}
@@ -6,9 +6,11 @@
 package tracing
 
 import (
+	"context"
 	"math"
 	"testing"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 
 	"github.com/stretchr/testify/assert"
@@ -57,3 +59,62 @@ func TestAnalyticsSettings(t *testing.T) {
 		assert.True(t, cfg.dataStreamsEnabled)
 	})
 }
+
+type testMessage struct {
+	headers []Header
+}
+
+func (*testMessage) GetValue() []byte        { return nil }
+func (*testMessage) GetKey() []byte          { return nil }
+func (m *testMessage) GetHeaders() []Header  { return m.headers }
+func (m *testMessage) SetHeaders(h []Header) { m.headers = h }
+func (*testMessage) GetTopic() string        { return "topic" }
+func (*testMessage) GetPartition() int       { return 0 }
+func (*testMessage) GetOffset() int64        { return 0 }
+
+type testWriter struct{}
+
+func (testWriter) GetTopic() string { return "topic" }
+
+func TestBinaryPropagation(t *testing.T) {
+	tracer.Start(tracer.WithLogStartup(false))
+	defer tracer.Stop()
+
+	msg := new(testMessage)
+	producer := NewTracer(KafkaConfig{}, WithBinaryPropagation())
+	pspan := producer.StartProduceSpan(context.Background(), testWriter{}, msg)
+	defer pspan.Finish()
+
+	carrier := NewMessageCarrier(msg)
+	assert.Len(t, msg.headers, 1)
+	assert.NotNil(t, carrier.Binary())
+	ctx, err := ExtractSpanContext(carrier)
+	assert.NoError(t, err)
+	assert.Equal(t, pspan.Context().SpanID(), ctx.SpanID())
+
+	// the consumer extracts the binary context, and replaces it with its own
+	// in text headers
+	csp := NewTracer(KafkaConfig{}).StartConsumeSpan(context.Background(), msg)
+	defer csp.Finish()
+	assert.Equal(t, pspan.Context().TraceID(), csp.Context().TraceID())
+	assert.Nil(t, carrier.Binary())
+	ctx, err = ExtractSpanContext(carrier)
+	assert.NoError(t, err)
+	assert.Equal(t, csp.Context().SpanID(), ctx.SpanID())
+}
+
+func TestBinaryPropagationCorrupted(t *testing.T) {
+	tracer.Start(tracer.WithLogStartup(false))
+	defer tracer.Stop()
+
+	// a corrupted binary context is ignored in favor of the text headers
+	msg := new(testMessage)
+	carrier := NewMessageCarrier(msg)
+	span := tracer.StartSpan("kafka.produce")
+	defer span.Finish()
+	assert.NoError(t, tracer.Inject(span.Context(), carrier))
+	carrier.SetBinary([]byte{1, 2, 3})
+	ctx, err := ExtractSpanContext(carrier)
+	assert.NoError(t, err)
+	assert.Equal(t, span.Context().SpanID(), ctx.SpanID())
+}
@@ -49,7 +49,7 @@ func (tr *Tracer) StartConsumeSpan(ctx context.Context, msg Message) ddtrace.Spa
 	}
 	// kafka supports headers, so try to extract a span context
 	carrier := NewMessageCarrier(msg)
-	if spanctx, err := tracer.Extract(carrier); err == nil {
+	if spanctx, err := ExtractSpanContext(carrier); err == nil {
 		// If there are span links as a result of context extraction, add them as a StartSpanOption
 		if linksCtx, ok := spanctx.(ddtrace.SpanContextWithLinks); ok && linksCtx.SpanLinks() != nil {
 			opts = append(opts, tracer.WithSpanLinks(linksCtx.SpanLinks()))
@@ -58,7 +58,7 @@ func (tr *Tracer) StartConsumeSpan(ctx context.Context, msg Message) ddtrace.Spa
 	}
 	span, _ := tracer.StartSpanFromContext(ctx, tr.consumerSpanName, opts...)
 	// reinject the span context so consumers can pick it up
-	if err := tracer.Inject(span.Context(), carrier); err != nil {
+	if err := injectSpanContext(span.Context(), carrier, tr.binaryPropagation); err != nil {
 		log.Debug("contrib/segmentio/kafka.go.v0: Failed to inject span context into carrier in reader, %v", err)
 	}
 	return span
@@ -85,7 +85,7 @@ func (tr *Tracer) StartProduceSpan(ctx context.Context, writer Writer, msg Messa
 	opts = append(opts, spanOpts...)
 	carrier := NewMessageCarrier(msg)
 	span, _ := tracer.StartSpanFromContext(ctx, tr.producerSpanName, opts...)
-	if err := tracer.Inject(span.Context(), carrier); err != nil {
+	if err := injectSpanContext(span.Context(), carrier, tr.binaryPropagation); err != nil {
 		log.Debug("contrib/segmentio/kafka.go.v0: Failed to inject span context into carrier in writer, %v", err)
 	}
 	return span
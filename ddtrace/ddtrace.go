@@ -42,6 +42,72 @@ type SpanContextWithLinks interface {
 	SpanLinks() []SpanLink
 }
 
+// SpanWithEvents represents a Span which can record span events.
+type SpanWithEvents interface {
+	Span
+
+	// AddEvent records an event named name, which occurred at the given time, on
+	// the span. Attribute values can be strings, booleans, integers, floats, or
+	// slices of these types; values of other types are recorded as strings.
+	AddEvent(name string, timestamp time.Time, attributes map[string]interface{})
+}
+
+// SpanWithExceptions represents a Span which can record errors as exception events.
+type SpanWithExceptions interface {
+	Span
+
+	// RecordException records err, and each error it wraps, as exception span events.
+	RecordException(err error, opts ...ExceptionOption)
+}
+
+// ReadOnlySpan represents a Span whose properties can be read back, for instance
+// in tests or span processors. Its methods are safe for concurrent use with the
+// methods modifying the span.
+type ReadOnlySpan interface {
+	// OperationName returns the operation name of the span.
+	OperationName() string
+
+	// ServiceName returns the service name of the span.
+	ServiceName() string
+
+	// ResourceName returns the resource name of the span.
+	ResourceName() string
+
+	// SpanType returns the type of the span, such as "web" or "db".
+	SpanType() string
+
+	// StartTime returns the time when the span started.
+	StartTime() time.Time
+
+	// Elapsed returns the duration of the span, or zero if it isn't finished.
+	Elapsed() time.Duration
+
+	// StringTags returns a copy of the tags of the span holding string values.
+	StringTags() map[string]string
+
+	// NumericTags returns a copy of the tags of the span holding numeric values.
+	NumericTags() map[string]float64
+
+	// Links returns a copy of the span links of the span.
+	Links() []SpanLink
+
+	// IsError reports whether the span is marked as an error.
+	IsError() bool
+
+	// Context returns the SpanContext of the span.
+	Context() SpanContext
+}
+
+// SpanEvent represents an event which occurred during the lifetime of a span.
+type SpanEvent struct {
+	// Name is the name of the event.
+	Name string
+	// Time is the time at which the event occurred.
+	Time time.Time
+	// Attributes holds the attributes describing the event.
+	Attributes map[string]interface{}
+}
+
 // Tracer specifies an implementation of the Datadog tracer which allows starting
 // and propagating spans. The official implementation if exposed as functions
 // within the "tracer" package.
@@ -130,6 +196,28 @@ type FinishConfig struct {
 	SkipStackFrames uint
 }
 
+// ExceptionOption is a configuration option that can be used with a SpanWithExceptions'
+// RecordException method.
+type ExceptionOption func(cfg *ExceptionConfig)
+
+// ExceptionConfig holds the configuration for recording an exception. It is usually passed
+// around by reference to one or more ExceptionOption functions which shape it into its
+// final form.
+type ExceptionConfig struct {
+	// Time is the time at which the exception occurred. Implementations should use the
+	// current time when Time.IsZero().
+	Time time.Time
+
+	// Attributes holds additional attributes to record on the event of the outermost error.
+	Attributes map[string]interface{}
+
+	// NoStack will prevent the stack trace of the call from being recorded.
+	NoStack bool
+
+	// SkipStackFrames specifies the offset at which to start reporting stack frames from the stack.
+	SkipStackFrames uint
+}
+
 // StartSpanConfig holds the configuration for starting a new span. It is usually passed
 // around by reference to one or more StartSpanOption functions which shape it into its
 // final form.
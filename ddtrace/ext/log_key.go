@@ -10,4 +10,10 @@ const (
 	LogKeyTraceID = "dd.trace_id"
 	// LogKeySpanID is used by log integrations to correlate logs with a given span.
 	LogKeySpanID = "dd.span_id"
+	// LogKeyService is used by log integrations to report the service of a given span.
+	LogKeyService = "dd.service"
+	// LogKeyEnv is used by log integrations to report the environment of a given span.
+	LogKeyEnv = "dd.env"
+	// LogKeyVersion is used by log integrations to report the version of a given span.
+	LogKeyVersion = "dd.version"
 )
@@ -7,6 +7,7 @@ package internal // import "gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
 
 import (
 	"sync/atomic"
+	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 )
@@ -64,6 +65,7 @@ func (NoopTracer) Inject(_ ddtrace.SpanContext, _ interface{}) error { return ni
 func (NoopTracer) Stop() {}
 
 var _ ddtrace.Span = (*NoopSpan)(nil)
+var _ ddtrace.SpanWithEvents = (*NoopSpan)(nil)
 
 // NoopSpan is an implementation of ddtrace.Span that is a no-op.
 type NoopSpan struct{}
@@ -83,6 +85,9 @@ func (NoopSpan) SetBaggageItem(_, _ string) {}
 // Finish implements ddtrace.Span.
 func (NoopSpan) Finish(_ ...ddtrace.FinishOption) {}
 
+// AddEvent implements ddtrace.SpanWithEvents.
+func (NoopSpan) AddEvent(_ string, _ time.Time, _ map[string]interface{}) {}
+
 // Tracer implements ddtrace.Span.
 func (NoopSpan) Tracer() ddtrace.Tracer { return NoopTracer{} }
 
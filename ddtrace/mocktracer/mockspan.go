@@ -17,6 +17,11 @@ import (
 
 var _ ddtrace.Span = (*mockspan)(nil)
 var _ Span = (*mockspan)(nil)
+var _ ddtrace.SpanWithEvents = (*mockspan)(nil)
+var _ ddtrace.SpanWithExceptions = (*mockspan)(nil)
+var _ ddtrace.ReadOnlySpan = (*mockspan)(nil)
+var _ tracer.SpanWithLinks = (*mockspan)(nil)
+var _ tracer.SpanWithAddLink = (*mockspan)(nil)
 
 // Span is an interface that allows querying a span returned by the mock tracer.
 type Span interface {
@@ -50,6 +55,9 @@ type Span interface {
 	// Links returns the span's span links.
 	Links() []ddtrace.SpanLink
 
+	// Events returns the events recorded on the span.
+	Events() []ddtrace.SpanEvent
+
 	// Stringer allows pretty-printing the span's fields for debugging.
 	fmt.Stringer
 
@@ -99,6 +107,7 @@ func newSpan(t *mocktracer, operationName string, cfg *ddtrace.StartSpanConfig)
 	for k, v := range cfg.Tags {
 		s.SetTag(k, v)
 	}
+	s.links = append(s.links, cfg.SpanLinks...)
 	return s
 }
 
@@ -115,6 +124,7 @@ type mockspan struct {
 	context   *spanContext
 	tracer    *mocktracer
 	links     []ddtrace.SpanLink
+	events    []ddtrace.SpanEvent
 }
 
 // SetTag sets a given tag on the span.
@@ -299,18 +309,212 @@ func (s *mockspan) Root() tracer.Span {
 	return root
 }
 
-// Links returns the span's span links.
+// Links returns a copy of the span's span links.
 func (s *mockspan) Links() []ddtrace.SpanLink {
 	s.RLock()
 	defer s.RUnlock()
-	return s.links
+	if len(s.links) == 0 {
+		return nil
+	}
+	links := make([]ddtrace.SpanLink, len(s.links))
+	for i, l := range s.links {
+		links[i] = l
+		if l.Attributes != nil {
+			links[i].Attributes = make(map[string]string, len(l.Attributes))
+			for k, v := range l.Attributes {
+				links[i].Attributes[k] = v
+			}
+		}
+	}
+	return links
 }
 
+// AddSpanLink appends the given link to the span's span links.
 func (s *mockspan) AddSpanLink(link ddtrace.SpanLink) {
+	s.Lock()
+	defer s.Unlock()
+	s.links = append(s.links, link)
+}
+
+// AddLink links the span to the span of ctx, with the given attributes, as the
+// tracer does.
+func (s *mockspan) AddLink(ctx ddtrace.SpanContext, attrs map[string]string) {
+	if ctx == nil {
+		return
+	}
+	link := tracer.SpanLinkFromContext(ctx, attrs)
+	s.Lock()
+	defer s.Unlock()
+	if s.finished {
+		return
+	}
 	s.links = append(s.links, link)
 }
 
+// AddEvent records an event on the span.
+func (s *mockspan) AddEvent(name string, timestamp time.Time, attributes map[string]interface{}) {
+	s.Lock()
+	defer s.Unlock()
+	if s.finished {
+		return
+	}
+	attrs := make(map[string]interface{}, len(attributes))
+	for k, v := range attributes {
+		attrs[k] = v
+	}
+	s.events = append(s.events, ddtrace.SpanEvent{Name: name, Time: timestamp, Attributes: attrs})
+}
+
+// RecordException records err as an event named "exception", holding the type
+// and message of err in the exception.type and exception.message attributes,
+// along with the attributes of the options. Unlike the tracer, the errors wrapped
+// by err and the stack trace are not recorded.
+func (s *mockspan) RecordException(err error, opts ...ddtrace.ExceptionOption) {
+	if err == nil {
+		return
+	}
+	var cfg ddtrace.ExceptionConfig
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+	if cfg.Time.IsZero() {
+		cfg.Time = time.Now()
+	}
+	attrs := make(map[string]interface{}, len(cfg.Attributes)+2)
+	for k, v := range cfg.Attributes {
+		attrs[k] = v
+	}
+	attrs["exception.type"] = fmt.Sprintf("%T", err)
+	attrs["exception.message"] = err.Error()
+	s.AddEvent("exception", cfg.Time, attrs)
+}
+
+// Events returns the events recorded on the span.
+func (s *mockspan) Events() []ddtrace.SpanEvent {
+	s.RLock()
+	defer s.RUnlock()
+	return s.events
+}
+
 // Integration returns the component from which the mockspan was created.
 func (s *mockspan) Integration() string {
 	return s.integration
 }
+
+// ServiceName returns the value of the service name tag.
+func (s *mockspan) ServiceName() string { return s.stringTag(ext.ServiceName) }
+
+// ResourceName returns the value of the resource name tag.
+func (s *mockspan) ResourceName() string { return s.stringTag(ext.ResourceName) }
+
+// SpanType returns the value of the span type tag.
+func (s *mockspan) SpanType() string { return s.stringTag(ext.SpanType) }
+
+func (s *mockspan) stringTag(k string) string {
+	s.RLock()
+	defer s.RUnlock()
+	if v, ok := s.tags[k]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// Elapsed returns the duration of the span, or zero if it isn't finished.
+func (s *mockspan) Elapsed() time.Duration {
+	s.RLock()
+	defer s.RUnlock()
+	if !s.finished {
+		return 0
+	}
+	return s.finishTime.Sub(s.startTime)
+}
+
+// StringTags returns the tags holding non-numeric values, formatted as strings,
+// other than the service name, resource name, span type and error tags which
+// have their own accessors.
+func (s *mockspan) StringTags() map[string]string {
+	s.RLock()
+	defer s.RUnlock()
+	meta := make(map[string]string)
+	for k, v := range s.tags {
+		if isReservedTag(k) {
+			continue
+		}
+		if _, ok := toFloat64(v); ok {
+			continue
+		}
+		meta[k] = fmt.Sprint(v)
+	}
+	return meta
+}
+
+// NumericTags returns the tags holding numeric values.
+func (s *mockspan) NumericTags() map[string]float64 {
+	s.RLock()
+	defer s.RUnlock()
+	metrics := make(map[string]float64)
+	for k, v := range s.tags {
+		if isReservedTag(k) {
+			continue
+		}
+		if f, ok := toFloat64(v); ok {
+			metrics[k] = f
+		}
+	}
+	return metrics
+}
+
+// IsError reports whether the error tag is set to true or to an error.
+func (s *mockspan) IsError() bool {
+	s.RLock()
+	defer s.RUnlock()
+	switch v := s.tags[ext.Error].(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	default:
+		return true
+	}
+}
+
+// isReservedTag reports whether the tag k is stored in a dedicated field of the
+// tracer's spans, rather than in its tags.
+func isReservedTag(k string) bool {
+	switch k {
+	case ext.ServiceName, ext.ResourceName, ext.SpanType, ext.Error:
+		return true
+	}
+	return false
+}
+
+// toFloat64 returns the value of v as a float64, if v is numeric.
+func toFloat64(v interface{}) (float64, bool) {
+	switch i := v.(type) {
+	case float64:
+		return i, true
+	case float32:
+		return float64(i), true
+	case int:
+		return float64(i), true
+	case int8:
+		return float64(i), true
+	case int16:
+		return float64(i), true
+	case int32:
+		return float64(i), true
+	case int64:
+		return float64(i), true
+	case uint:
+		return float64(i), true
+	case uint8:
+		return float64(i), true
+	case uint16:
+		return float64(i), true
+	case uint32:
+		return float64(i), true
+	case uint64:
+		return float64(i), true
+	}
+	return 0, false
+}
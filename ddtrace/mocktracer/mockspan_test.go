@@ -139,6 +139,88 @@ func TestSpanFinishTime(t *testing.T) {
 	assert.Equal(finishTime, s.FinishTime())
 }
 
+func TestSpanEvents(t *testing.T) {
+	s := basicSpan("http.request")
+	now := time.Now()
+	attrs := map[string]interface{}{"k": "v"}
+	s.AddEvent("evt", now, attrs)
+	attrs["k"] = "changed"
+	s.Finish()
+	s.AddEvent("late", now, nil)
+
+	assert.Equal(t, []ddtrace.SpanEvent{{Name: "evt", Time: now, Attributes: map[string]interface{}{"k": "v"}}}, s.Events())
+}
+
+func TestSpanRecordException(t *testing.T) {
+	s := basicSpan("http.request")
+	now := time.Now()
+	s.RecordException(errors.New("boom"), tracer.ExceptionTime(now), tracer.ExceptionAttributes(map[string]interface{}{"k": "v"}))
+	s.RecordException(nil)
+
+	assert.Equal(t, []ddtrace.SpanEvent{{Name: "exception", Time: now, Attributes: map[string]interface{}{
+		"k":                 "v",
+		"exception.type":    "*errors.errorString",
+		"exception.message": "boom",
+	}}}, s.Events())
+}
+
+func TestSpanReadOnly(t *testing.T) {
+	start := time.Now()
+	s := newSpan(&mocktracer{}, "http.request", &ddtrace.StartSpanConfig{
+		StartTime: start,
+		Tags: map[string]interface{}{
+			ext.ServiceName: "svc",
+			ext.SpanType:    "web",
+			"k":             "v",
+			"n":             2,
+			"b":             true,
+		},
+	})
+	s.AddSpanLink(ddtrace.SpanLink{SpanID: 1, TraceID: 2, Attributes: map[string]string{"k": "v"}})
+	var ro ddtrace.ReadOnlySpan = s
+
+	assert := assert.New(t)
+	assert.Equal("http.request", ro.OperationName())
+	assert.Equal("svc", ro.ServiceName())
+	assert.Equal("http.request", ro.ResourceName())
+	assert.Equal("web", ro.SpanType())
+	assert.Equal(start, ro.StartTime())
+	assert.Zero(ro.Elapsed())
+	assert.False(ro.IsError())
+	assert.Equal(map[string]string{"k": "v", "b": "true"}, ro.StringTags())
+	assert.Equal(map[string]float64{"n": 2}, ro.NumericTags())
+	assert.Equal([]ddtrace.SpanLink{{SpanID: 1, TraceID: 2, Attributes: map[string]string{"k": "v"}}}, ro.Links())
+	ro.Links()[0].Attributes["k"] = "changed"
+	assert.Equal("v", ro.Links()[0].Attributes["k"])
+
+	s.Finish(tracer.FinishTime(start.Add(time.Second)), tracer.WithError(errors.New("boom")))
+	assert.Equal(time.Second, ro.Elapsed())
+	assert.True(ro.IsError())
+}
+
+func TestSpanLinks(t *testing.T) {
+	mt := newMockTracer()
+	defer mt.Stop()
+
+	parent := mt.StartSpan("parent")
+	parent.SetTag(ext.SamplingPriority, ext.PriorityAutoKeep)
+	start := ddtrace.SpanLink{TraceID: 1, SpanID: 2}
+	s := mt.StartSpan("consume", tracer.WithSpanLinks([]ddtrace.SpanLink{start}))
+	s.(tracer.SpanWithAddLink).AddLink(parent.Context(), map[string]string{"k": "v"})
+	s.Finish()
+	s.(tracer.SpanWithAddLink).AddLink(parent.Context(), nil)
+
+	assert.Equal(t, []ddtrace.SpanLink{
+		start,
+		{
+			TraceID:    parent.Context().TraceID(),
+			SpanID:     parent.Context().SpanID(),
+			Attributes: map[string]string{"k": "v"},
+			Flags:      0x80000001,
+		},
+	}, mt.FinishedSpans()[0].Links())
+}
+
 func TestSpanOperationName(t *testing.T) {
 	t.Run("default", func(t *testing.T) {
 		s := basicSpan("http.request")
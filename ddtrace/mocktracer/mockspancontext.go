@@ -61,6 +61,13 @@ func (sc *spanContext) setSamplingPriority(p int) {
 	sc.hasPriority = true
 }
 
+// SamplingPriority returns the sampling priority of the context, if set.
+func (sc *spanContext) SamplingPriority() (int, bool) {
+	sc.RLock()
+	defer sc.RUnlock()
+	return sc.priority, sc.hasPriority
+}
+
 func (sc *spanContext) hasSamplingPriority() bool {
 	sc.RLock()
 	defer sc.RUnlock()
@@ -0,0 +1,143 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/logtrace"
+
+	otellog "go.opentelemetry.io/otel/log"
+	lognoop "go.opentelemetry.io/otel/log/noop"
+)
+
+var _ otellog.Logger = (*logger)(nil)
+
+type logger struct {
+	lognoop.Logger // https://pkg.go.dev/go.opentelemetry.io/otel/log#hdr-API_Implementations
+	provider       *LoggerProvider
+	name           string
+}
+
+// Enabled reports whether a record of the given severity would be written.
+func (l *logger) Enabled(_ context.Context, param otellog.EnabledParameters) bool {
+	if atomic.LoadUint32(&l.provider.stopped) != 0 {
+		return false
+	}
+	return param.Severity == otellog.SeverityUndefined || param.Severity >= l.provider.cfg.minSeverity
+}
+
+// Emit writes the record as a JSON line in the Datadog log format, correlated
+// with the Datadog span found in ctx, if any.
+func (l *logger) Emit(ctx context.Context, r otellog.Record) {
+	if !l.Enabled(ctx, otellog.EnabledParameters{Severity: r.Severity()}) {
+		return
+	}
+	b, err := json.Marshal(l.entry(ctx, &r))
+	if err != nil {
+		log.Debug("opentelemetry/log: unable to encode log record: %v", err)
+		return
+	}
+	l.provider.write(append(b, '\n'))
+}
+
+// entry returns the Datadog log entry of the record r. The record attributes
+// are set at the root level, where they are overridden by the reserved
+// attributes of the same name.
+func (l *logger) entry(ctx context.Context, r *otellog.Record) map[string]interface{} {
+	e := make(map[string]interface{}, r.AttributesLen()+8)
+	r.WalkAttributes(func(kv otellog.KeyValue) bool {
+		e[kv.Key] = logValue(kv.Value)
+		return true
+	})
+	ts := r.Timestamp()
+	if ts.IsZero() {
+		ts = r.ObservedTimestamp()
+	}
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	e["timestamp"] = ts.UTC().Format(time.RFC3339Nano)
+	e["status"] = logStatus(r.Severity(), r.SeverityText())
+	if body := r.Body(); body.Kind() == otellog.KindString {
+		e["message"] = body.AsString()
+	} else if !body.Empty() {
+		// the message must be a string: other bodies are encoded as JSON
+		if b, err := json.Marshal(logValue(body)); err == nil {
+			e["message"] = string(b)
+		}
+	}
+	if l.name != "" {
+		e["logger.name"] = l.name
+	}
+	f, ok := logtrace.FromContext(ctx)
+	if !ok {
+		f = logtrace.FromEnv()
+	}
+	f.ForEach(func(k, v string) { e[k] = v })
+	return e
+}
+
+// logStatus returns the Datadog log status of the severity s, following the
+// mapping of the Datadog exporter of the OpenTelemetry Collector. The severity
+// text is used when the severity is undefined.
+func logStatus(s otellog.Severity, text string) string {
+	switch {
+	case s == otellog.SeverityUndefined && text != "":
+		return strings.ToLower(text)
+	case s == otellog.SeverityUndefined:
+		return "info"
+	case s <= otellog.SeverityTrace4:
+		return "trace"
+	case s <= otellog.SeverityDebug4:
+		return "debug"
+	case s <= otellog.SeverityInfo4:
+		return "info"
+	case s <= otellog.SeverityWarn4:
+		return "warn"
+	case s <= otellog.SeverityError4:
+		return "error"
+	default:
+		return "fatal"
+	}
+}
+
+// logValue converts v to a value encoded to the corresponding JSON type.
+func logValue(v otellog.Value) interface{} {
+	switch v.Kind() {
+	case otellog.KindBool:
+		return v.AsBool()
+	case otellog.KindFloat64:
+		return v.AsFloat64()
+	case otellog.KindInt64:
+		return v.AsInt64()
+	case otellog.KindString:
+		return v.AsString()
+	case otellog.KindBytes:
+		return v.AsBytes()
+	case otellog.KindSlice:
+		vs := v.AsSlice()
+		s := make([]interface{}, len(vs))
+		for i, v := range vs {
+			s[i] = logValue(v)
+		}
+		return s
+	case otellog.KindMap:
+		kvs := v.AsMap()
+		m := make(map[string]interface{}, len(kvs))
+		for _, kv := range kvs {
+			m[kv.Key] = logValue(kv.Value)
+		}
+		return m
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,155 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+// Package log provides an implementation of the OpenTelemetry Logs API writing
+// log records correlated with Datadog spans.
+//
+// It is separate from the opentelemetry package because the OpenTelemetry Logs
+// API isn't stable yet: programs that don't import it don't depend on it.
+package log
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	otellog "go.opentelemetry.io/otel/log"
+	lognoop "go.opentelemetry.io/otel/log/noop"
+)
+
+var _ otellog.LoggerProvider = (*LoggerProvider)(nil)
+
+// LoggerProvider provides an implementation of the OpenTelemetry LoggerProvider
+// interface, writing the log records of its loggers as JSON lines in the Datadog
+// log format, to the standard output by default.
+//
+// Records emitted with a context holding a Datadog span, including the spans
+// of the TracerProvider of the opentelemetry package, are correlated with the
+// span through the dd.trace_id and dd.span_id attributes, like the
+// contrib/log/slog integration does. All records are tagged with dd.service,
+// dd.env and dd.version.
+type LoggerProvider struct {
+	lognoop.LoggerProvider // https://pkg.go.dev/go.opentelemetry.io/otel/log#hdr-API_Implementations
+	cfg                    *loggerConfig
+	closer                 io.Closer  // closer closes the file opened by the provider, if any.
+	stopped                uint32     // stopped indicates whether the LoggerProvider has been shutdown.
+	mu                     sync.Mutex // guards writes to cfg.w
+	sync.Once
+}
+
+// LoggerProviderOption configures a LoggerProvider.
+type LoggerProviderOption func(*loggerConfig)
+
+type loggerConfig struct {
+	w           io.Writer
+	path        string
+	minSeverity otellog.Severity
+}
+
+// WithLogWriter sets the sink the log records are written to, one JSON object
+// per line. Writes are serialized by the LoggerProvider.
+func WithLogWriter(w io.Writer) LoggerProviderOption {
+	return func(c *loggerConfig) {
+		c.w = w
+		c.path = ""
+	}
+}
+
+// WithLogFile sets the file the log records are appended to, one JSON object
+// per line. The file is created if needed, and closed when the LoggerProvider
+// is shut down. If it can't be opened, records are written to the standard
+// output instead.
+func WithLogFile(path string) LoggerProviderOption {
+	return func(c *loggerConfig) {
+		c.path = path
+		c.w = nil
+	}
+}
+
+// WithMinSeverity sets the minimum severity of the records written by the
+// loggers. Records with an undefined severity are always written.
+func WithMinSeverity(s otellog.Severity) LoggerProviderOption {
+	return func(c *loggerConfig) {
+		c.minSeverity = s
+	}
+}
+
+// NewLoggerProvider returns an instance of an OpenTelemetry LoggerProvider
+// writing log records correlated with Datadog spans.
+func NewLoggerProvider(opts ...LoggerProviderOption) *LoggerProvider {
+	cfg := &loggerConfig{w: os.Stdout}
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	p := &LoggerProvider{cfg: cfg}
+	if cfg.path != "" {
+		f, err := os.OpenFile(cfg.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Error("opentelemetry/log: unable to open log file %q, logging to stdout: %v", cfg.path, err)
+			cfg.w = os.Stdout
+		} else {
+			cfg.w = f
+			p.closer = f
+		}
+	}
+	if cfg.w == nil {
+		cfg.w = os.Stdout
+	}
+	return p
+}
+
+// Logger returns a logger recording name as the logger.name attribute of its
+// records, ignoring the provided options. If the LoggerProvider has already
+// been shut down, this will return a no-op logger.
+func (p *LoggerProvider) Logger(name string, _ ...otellog.LoggerOption) otellog.Logger {
+	if atomic.LoadUint32(&p.stopped) != 0 {
+		return lognoop.NewLoggerProvider().Logger(name)
+	}
+	return &logger{provider: p, name: name}
+}
+
+// write writes the encoded record b to the sink of the provider.
+func (p *LoggerProvider) write(b []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if atomic.LoadUint32(&p.stopped) != 0 {
+		return
+	}
+	if _, err := p.cfg.w.Write(b); err != nil {
+		log.Debug("opentelemetry/log: unable to write log record: %v", err)
+	}
+}
+
+// ForceFlush syncs the log file to disk, if the sink is a file.
+func (p *LoggerProvider) ForceFlush(_ context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if atomic.LoadUint32(&p.stopped) != 0 {
+		return nil
+	}
+	if f, ok := p.cfg.w.(*os.File); ok && f != os.Stdout && f != os.Stderr {
+		return f.Sync()
+	}
+	return nil
+}
+
+// Shutdown stops writing log records, and closes the log file opened by the
+// provider, if any. Subsequent calls are valid but become no-op.
+func (p *LoggerProvider) Shutdown(_ context.Context) error {
+	var err error
+	p.Once.Do(func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		atomic.StoreUint32(&p.stopped, 1)
+		if p.closer != nil {
+			err = p.closer.Close()
+		}
+	})
+	return err
+}
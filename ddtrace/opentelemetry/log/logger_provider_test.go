@@ -0,0 +1,168 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/opentelemetry"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	internallog "gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	otellog "go.opentelemetry.io/otel/log"
+)
+
+func decodeLogs(t *testing.T, b []byte) []map[string]interface{} {
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var e map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &e), line)
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestLoggerProviderEmit(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("DD_ENV", "prod")
+	t.Setenv("DD_VERSION", "1.2.3")
+	tracer.Start(tracer.WithService("svc"), tracer.WithLogger(internallog.DiscardLogger{}))
+	defer tracer.Stop()
+
+	var buf bytes.Buffer
+	lp := NewLoggerProvider(WithLogWriter(&buf))
+	defer lp.Shutdown(context.Background())
+	l := lp.Logger("my.logger")
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "op")
+	var r otellog.Record
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	r.SetTimestamp(ts)
+	r.SetSeverity(otellog.SeverityWarn)
+	r.SetBody(otellog.StringValue("hello"))
+	r.AddAttributes(
+		otellog.String("str", "v"),
+		otellog.Int("int", 1),
+		otellog.Bool("bool", true),
+		otellog.Map("map", otellog.Float64("f", 0.5)),
+		otellog.Slice("slice", otellog.StringValue("a"), otellog.IntValue(2)),
+		otellog.String("status", "overridden"),
+	)
+	l.Emit(ctx, r)
+	span.Finish()
+
+	// records emitted outside of a span aren't correlated
+	var r2 otellog.Record
+	r2.SetBody(otellog.MapValue(otellog.String("k", "v")))
+	l.Emit(context.Background(), r2)
+
+	entries := decodeLogs(t, buf.Bytes())
+	assert.Len(entries, 2)
+	assert.Equal(map[string]interface{}{
+		"timestamp":   ts.Format(time.RFC3339Nano),
+		"status":      "warn",
+		"message":     "hello",
+		"logger.name": "my.logger",
+		"dd.trace_id": strconv.FormatUint(span.Context().TraceID(), 10),
+		"dd.span_id":  strconv.FormatUint(span.Context().SpanID(), 10),
+		"dd.service":  "svc",
+		"dd.env":      "prod",
+		"dd.version":  "1.2.3",
+		"str":         "v",
+		"int":         float64(1),
+		"bool":        true,
+		"map":         map[string]interface{}{"f": 0.5},
+		"slice":       []interface{}{"a", float64(2)},
+	}, entries[0])
+
+	assert.NotContains(entries[1], "dd.trace_id")
+	assert.NotContains(entries[1], "dd.span_id")
+	assert.Equal("svc", entries[1]["dd.service"])
+	assert.Equal("info", entries[1]["status"])
+	assert.Equal(`{"k":"v"}`, entries[1]["message"])
+	assert.NotEmpty(entries[1]["timestamp"])
+}
+
+func TestLoggerProviderOTelSpan(t *testing.T) {
+	tp := opentelemetry.NewTracerProvider(tracer.WithLogger(internallog.DiscardLogger{}))
+	defer tp.Shutdown()
+	var buf bytes.Buffer
+	lp := NewLoggerProvider(WithLogWriter(&buf))
+	defer lp.Shutdown(context.Background())
+
+	ctx, sp := tp.Tracer("").Start(context.Background(), "op")
+	var r otellog.Record
+	r.SetBody(otellog.StringValue("hello"))
+	lp.Logger("").Emit(ctx, r)
+	sp.End()
+
+	entries := decodeLogs(t, buf.Bytes())
+	assert.Len(t, entries, 1)
+	ddSpan, ok := tracer.SpanFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, strconv.FormatUint(ddSpan.Context().SpanID(), 10), entries[0]["dd.span_id"])
+	assert.NotContains(t, entries[0], "logger.name")
+}
+
+func TestLoggerProviderSeverity(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	lp := NewLoggerProvider(WithLogWriter(&buf), WithMinSeverity(otellog.SeverityInfo))
+	l := lp.Logger("")
+	ctx := context.Background()
+
+	assert.False(l.Enabled(ctx, otellog.EnabledParameters{Severity: otellog.SeverityDebug}))
+	assert.True(l.Enabled(ctx, otellog.EnabledParameters{Severity: otellog.SeverityInfo}))
+	assert.True(l.Enabled(ctx, otellog.EnabledParameters{}))
+
+	for _, s := range []otellog.Severity{otellog.SeverityDebug, otellog.SeverityInfo2, otellog.SeverityError, otellog.SeverityFatal4} {
+		var r otellog.Record
+		r.SetSeverity(s)
+		l.Emit(ctx, r)
+	}
+	var r otellog.Record
+	r.SetSeverityText("NOTICE")
+	l.Emit(ctx, r)
+
+	var statuses []interface{}
+	for _, e := range decodeLogs(t, buf.Bytes()) {
+		statuses = append(statuses, e["status"])
+	}
+	assert.Equal([]interface{}{"info", "error", "fatal", "notice"}, statuses)
+	assert.Equal("trace", logStatus(otellog.SeverityTrace3, ""))
+}
+
+func TestLoggerProviderFile(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "otel.log")
+	lp := NewLoggerProvider(WithLogFile(path))
+	var r otellog.Record
+	r.SetBody(otellog.StringValue("to file"))
+	lp.Logger("").Emit(context.Background(), r)
+	assert.NoError(lp.ForceFlush(context.Background()))
+	assert.NoError(lp.Shutdown(context.Background()))
+	assert.NoError(lp.Shutdown(context.Background()))
+
+	// the provider is stopped
+	lp.Logger("").Emit(context.Background(), r)
+	assert.False(lp.Logger("").Enabled(context.Background(), otellog.EnabledParameters{}))
+
+	b, err := os.ReadFile(path)
+	assert.NoError(err)
+	entries := decodeLogs(t, b)
+	assert.Len(entries, 1)
+	assert.Equal("to file", entries[0]["message"])
+}
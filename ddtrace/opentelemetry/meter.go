@@ -0,0 +1,456 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package opentelemetry
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+)
+
+var _ otelmetric.Meter = (*meter)(nil)
+
+// maxTagLength is the maximum length of the tags created from attributes, beyond
+// which they are truncated.
+const maxTagLength = 200
+
+// overflowTag tags the measurements whose attributes exceeded the maximum number
+// of attribute sets of their instrument.
+const overflowTag = "otel.metric.overflow:true"
+
+type instrumentKind int
+
+const (
+	kindCounter instrumentKind = iota
+	kindUpDownCounter
+	kindHistogram
+	kindGauge
+	kindObservableCounter
+	kindObservableUpDownCounter
+	kindObservableGauge
+)
+
+type meter struct {
+	metricnoop.Meter // https://pkg.go.dev/go.opentelemetry.io/otel/metric#hdr-API_Implementations
+	cfg              *meterConfig
+
+	mu          sync.Mutex // guards below fields
+	instruments map[instrumentID]*instrument
+	callbacks   map[uint64]func(context.Context) error
+	nextID      uint64
+}
+
+// instrumentID identifies the instruments created with the same name and kind,
+// which share their attribute sets.
+type instrumentID struct {
+	name string
+	kind instrumentKind
+}
+
+func newMeter(cfg *meterConfig) *meter {
+	return &meter{
+		cfg:         cfg,
+		instruments: make(map[instrumentID]*instrument),
+		callbacks:   make(map[uint64]func(context.Context) error),
+	}
+}
+
+// instrument returns the instrument named name of the given kind.
+func (m *meter) instrument(name string, kind instrumentKind) *instrument {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := instrumentID{name: name, kind: kind}
+	if in, ok := m.instruments[id]; ok {
+		return in
+	}
+	in := &instrument{
+		name:   name,
+		kind:   kind,
+		cfg:    m.cfg,
+		series: make(map[attribute.Distinct]*series),
+	}
+	m.instruments[id] = in
+	return in
+}
+
+// register registers the callback cb, called on each collection, and returns its
+// registration.
+func (m *meter) register(cb func(context.Context) error) *registration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	m.callbacks[m.nextID] = cb
+	return &registration{meter: m, id: m.nextID}
+}
+
+// collect calls the callbacks of the observable instruments.
+func (m *meter) collect(ctx context.Context) {
+	m.mu.Lock()
+	callbacks := make([]func(context.Context) error, 0, len(m.callbacks))
+	for _, cb := range m.callbacks {
+		callbacks = append(callbacks, cb)
+	}
+	m.mu.Unlock()
+	for _, cb := range callbacks {
+		if err := cb(ctx); err != nil {
+			log.Debug("opentelemetry: metric callback failed: %v", err)
+		}
+	}
+}
+
+type registration struct {
+	metricnoop.Registration
+	meter *meter
+	id    uint64
+}
+
+// Unregister stops calling the registered callback.
+func (r *registration) Unregister() error {
+	r.meter.mu.Lock()
+	defer r.meter.mu.Unlock()
+	delete(r.meter.callbacks, r.id)
+	return nil
+}
+
+// RegisterCallback registers f to be called on each collection, to observe the
+// values of the given observable instruments.
+func (m *meter) RegisterCallback(f otelmetric.Callback, _ ...otelmetric.Observable) (otelmetric.Registration, error) {
+	return m.register(func(ctx context.Context) error {
+		return f(ctx, observer{})
+	}), nil
+}
+
+type observer struct {
+	metricnoop.Observer
+}
+
+// instrumented is implemented by the instruments of the meter.
+type instrumented interface {
+	base() *instrument
+}
+
+func (observer) ObserveInt64(o otelmetric.Int64Observable, v int64, opts ...otelmetric.ObserveOption) {
+	if in, ok := o.(instrumented); ok {
+		in.base().record(float64(v), otelmetric.NewObserveConfig(opts).Attributes())
+	}
+}
+
+func (observer) ObserveFloat64(o otelmetric.Float64Observable, v float64, opts ...otelmetric.ObserveOption) {
+	if in, ok := o.(instrumented); ok {
+		in.base().record(v, otelmetric.NewObserveConfig(opts).Attributes())
+	}
+}
+
+// instrument records the measurements of all the instruments of a name and kind.
+type instrument struct {
+	name string
+	kind instrumentKind
+	cfg  *meterConfig
+
+	mu       sync.Mutex // guards below fields
+	series   map[attribute.Distinct]*series
+	overflow *series
+}
+
+// series holds the state of the metric of an attribute set.
+type series struct {
+	tags []string
+	// value is the sum of an up-down counter, the fractional part of the sum of
+	// a counter not recorded yet, or the last value of an observable counter.
+	value float64
+}
+
+func (in *instrument) base() *instrument { return in }
+
+// seriesLocked returns the series of the attributes attrs, or the overflow series
+// if the instrument already has the maximum number of attribute sets. It must be
+// called with in.mu held.
+func (in *instrument) seriesLocked(attrs attribute.Set) *series {
+	key := attrs.Equivalent()
+	if s, ok := in.series[key]; ok {
+		return s
+	}
+	if len(in.series) >= in.cfg.maxAttributeSets {
+		if in.overflow == nil {
+			in.overflow = &series{tags: append(in.cfg.tags[:len(in.cfg.tags):len(in.cfg.tags)], overflowTag)}
+		}
+		return in.overflow
+	}
+	tags := make([]string, 0, len(in.cfg.tags)+attrs.Len())
+	tags = append(tags, in.cfg.tags...)
+	for iter := attrs.Iter(); iter.Next(); {
+		kv := iter.Attribute()
+		tag := string(kv.Key) + ":" + kv.Value.Emit()
+		if len(tag) > maxTagLength {
+			tag = tag[:maxTagLength]
+		}
+		tags = append(tags, tag)
+	}
+	s := &series{tags: tags}
+	in.series[key] = s
+	return s
+}
+
+// record records the measurement v with the attributes attrs.
+func (in *instrument) record(v float64, attrs attribute.Set) {
+	statsd := in.cfg.statsd
+	in.mu.Lock()
+	s := in.seriesLocked(attrs)
+	switch in.kind {
+	case kindCounter:
+		if v < 0 {
+			in.mu.Unlock()
+			log.Debug("opentelemetry: ignoring negative increment of counter %q", in.name)
+			return
+		}
+		s.value += v
+		n := math.Trunc(s.value)
+		s.value -= n
+		in.mu.Unlock()
+		if n != 0 {
+			statsd.Count(in.name, int64(n), s.tags, 1)
+		}
+	case kindObservableCounter:
+		// observable counters report cumulative values, recorded as counts of
+		// their increase
+		n := math.Trunc(v) - math.Trunc(s.value)
+		if v < s.value {
+			// the counter was reset
+			n = math.Trunc(v)
+		}
+		s.value = v
+		in.mu.Unlock()
+		if n != 0 {
+			statsd.Count(in.name, int64(n), s.tags, 1)
+		}
+	case kindUpDownCounter:
+		s.value += v
+		sum := s.value
+		in.mu.Unlock()
+		statsd.Gauge(in.name, sum, s.tags, 1)
+	case kindHistogram:
+		in.mu.Unlock()
+		statsd.DistributionSamples(in.name, []float64{v}, s.tags, 1)
+	default:
+		// gauges, observable gauges and observable up-down counters
+		in.mu.Unlock()
+		statsd.Gauge(in.name, v, s.tags, 1)
+	}
+}
+
+type int64Counter struct {
+	metricnoop.Int64Counter
+	*instrument
+}
+
+func (m *meter) Int64Counter(name string, _ ...otelmetric.Int64CounterOption) (otelmetric.Int64Counter, error) {
+	return int64Counter{instrument: m.instrument(name, kindCounter)}, nil
+}
+
+func (c int64Counter) Add(_ context.Context, incr int64, opts ...otelmetric.AddOption) {
+	c.record(float64(incr), otelmetric.NewAddConfig(opts).Attributes())
+}
+
+type float64Counter struct {
+	metricnoop.Float64Counter
+	*instrument
+}
+
+func (m *meter) Float64Counter(name string, _ ...otelmetric.Float64CounterOption) (otelmetric.Float64Counter, error) {
+	return float64Counter{instrument: m.instrument(name, kindCounter)}, nil
+}
+
+func (c float64Counter) Add(_ context.Context, incr float64, opts ...otelmetric.AddOption) {
+	c.record(incr, otelmetric.NewAddConfig(opts).Attributes())
+}
+
+type int64UpDownCounter struct {
+	metricnoop.Int64UpDownCounter
+	*instrument
+}
+
+func (m *meter) Int64UpDownCounter(name string, _ ...otelmetric.Int64UpDownCounterOption) (otelmetric.Int64UpDownCounter, error) {
+	return int64UpDownCounter{instrument: m.instrument(name, kindUpDownCounter)}, nil
+}
+
+func (c int64UpDownCounter) Add(_ context.Context, incr int64, opts ...otelmetric.AddOption) {
+	c.record(float64(incr), otelmetric.NewAddConfig(opts).Attributes())
+}
+
+type float64UpDownCounter struct {
+	metricnoop.Float64UpDownCounter
+	*instrument
+}
+
+func (m *meter) Float64UpDownCounter(name string, _ ...otelmetric.Float64UpDownCounterOption) (otelmetric.Float64UpDownCounter, error) {
+	return float64UpDownCounter{instrument: m.instrument(name, kindUpDownCounter)}, nil
+}
+
+func (c float64UpDownCounter) Add(_ context.Context, incr float64, opts ...otelmetric.AddOption) {
+	c.record(incr, otelmetric.NewAddConfig(opts).Attributes())
+}
+
+type int64Histogram struct {
+	metricnoop.Int64Histogram
+	*instrument
+}
+
+func (m *meter) Int64Histogram(name string, _ ...otelmetric.Int64HistogramOption) (otelmetric.Int64Histogram, error) {
+	return int64Histogram{instrument: m.instrument(name, kindHistogram)}, nil
+}
+
+func (h int64Histogram) Record(_ context.Context, v int64, opts ...otelmetric.RecordOption) {
+	h.record(float64(v), otelmetric.NewRecordConfig(opts).Attributes())
+}
+
+type float64Histogram struct {
+	metricnoop.Float64Histogram
+	*instrument
+}
+
+func (m *meter) Float64Histogram(name string, _ ...otelmetric.Float64HistogramOption) (otelmetric.Float64Histogram, error) {
+	return float64Histogram{instrument: m.instrument(name, kindHistogram)}, nil
+}
+
+func (h float64Histogram) Record(_ context.Context, v float64, opts ...otelmetric.RecordOption) {
+	h.record(v, otelmetric.NewRecordConfig(opts).Attributes())
+}
+
+type int64Gauge struct {
+	metricnoop.Int64Gauge
+	*instrument
+}
+
+func (m *meter) Int64Gauge(name string, _ ...otelmetric.Int64GaugeOption) (otelmetric.Int64Gauge, error) {
+	return int64Gauge{instrument: m.instrument(name, kindGauge)}, nil
+}
+
+func (g int64Gauge) Record(_ context.Context, v int64, opts ...otelmetric.RecordOption) {
+	g.record(float64(v), otelmetric.NewRecordConfig(opts).Attributes())
+}
+
+type float64Gauge struct {
+	metricnoop.Float64Gauge
+	*instrument
+}
+
+func (m *meter) Float64Gauge(name string, _ ...otelmetric.Float64GaugeOption) (otelmetric.Float64Gauge, error) {
+	return float64Gauge{instrument: m.instrument(name, kindGauge)}, nil
+}
+
+func (g float64Gauge) Record(_ context.Context, v float64, opts ...otelmetric.RecordOption) {
+	g.record(v, otelmetric.NewRecordConfig(opts).Attributes())
+}
+
+type int64Observer struct {
+	metricnoop.Int64Observer
+	*instrument
+}
+
+func (o int64Observer) Observe(v int64, opts ...otelmetric.ObserveOption) {
+	o.record(float64(v), otelmetric.NewObserveConfig(opts).Attributes())
+}
+
+type float64Observer struct {
+	metricnoop.Float64Observer
+	*instrument
+}
+
+func (o float64Observer) Observe(v float64, opts ...otelmetric.ObserveOption) {
+	o.record(v, otelmetric.NewObserveConfig(opts).Attributes())
+}
+
+// registerInt64Callbacks registers the callbacks of the observable instrument in.
+func (m *meter) registerInt64Callbacks(in *instrument, callbacks []otelmetric.Int64Callback) {
+	for _, cb := range callbacks {
+		cb := cb
+		m.register(func(ctx context.Context) error {
+			return cb(ctx, int64Observer{instrument: in})
+		})
+	}
+}
+
+// registerFloat64Callbacks registers the callbacks of the observable instrument in.
+func (m *meter) registerFloat64Callbacks(in *instrument, callbacks []otelmetric.Float64Callback) {
+	for _, cb := range callbacks {
+		cb := cb
+		m.register(func(ctx context.Context) error {
+			return cb(ctx, float64Observer{instrument: in})
+		})
+	}
+}
+
+type int64ObservableCounter struct {
+	metricnoop.Int64ObservableCounter
+	*instrument
+}
+
+func (m *meter) Int64ObservableCounter(name string, opts ...otelmetric.Int64ObservableCounterOption) (otelmetric.Int64ObservableCounter, error) {
+	in := m.instrument(name, kindObservableCounter)
+	m.registerInt64Callbacks(in, otelmetric.NewInt64ObservableCounterConfig(opts...).Callbacks())
+	return int64ObservableCounter{instrument: in}, nil
+}
+
+type int64ObservableUpDownCounter struct {
+	metricnoop.Int64ObservableUpDownCounter
+	*instrument
+}
+
+func (m *meter) Int64ObservableUpDownCounter(name string, opts ...otelmetric.Int64ObservableUpDownCounterOption) (otelmetric.Int64ObservableUpDownCounter, error) {
+	in := m.instrument(name, kindObservableUpDownCounter)
+	m.registerInt64Callbacks(in, otelmetric.NewInt64ObservableUpDownCounterConfig(opts...).Callbacks())
+	return int64ObservableUpDownCounter{instrument: in}, nil
+}
+
+type int64ObservableGauge struct {
+	metricnoop.Int64ObservableGauge
+	*instrument
+}
+
+func (m *meter) Int64ObservableGauge(name string, opts ...otelmetric.Int64ObservableGaugeOption) (otelmetric.Int64ObservableGauge, error) {
+	in := m.instrument(name, kindObservableGauge)
+	m.registerInt64Callbacks(in, otelmetric.NewInt64ObservableGaugeConfig(opts...).Callbacks())
+	return int64ObservableGauge{instrument: in}, nil
+}
+
+type float64ObservableCounter struct {
+	metricnoop.Float64ObservableCounter
+	*instrument
+}
+
+func (m *meter) Float64ObservableCounter(name string, opts ...otelmetric.Float64ObservableCounterOption) (otelmetric.Float64ObservableCounter, error) {
+	in := m.instrument(name, kindObservableCounter)
+	m.registerFloat64Callbacks(in, otelmetric.NewFloat64ObservableCounterConfig(opts...).Callbacks())
+	return float64ObservableCounter{instrument: in}, nil
+}
+
+type float64ObservableUpDownCounter struct {
+	metricnoop.Float64ObservableUpDownCounter
+	*instrument
+}
+
+func (m *meter) Float64ObservableUpDownCounter(name string, opts ...otelmetric.Float64ObservableUpDownCounterOption) (otelmetric.Float64ObservableUpDownCounter, error) {
+	in := m.instrument(name, kindObservableUpDownCounter)
+	m.registerFloat64Callbacks(in, otelmetric.NewFloat64ObservableUpDownCounterConfig(opts...).Callbacks())
+	return float64ObservableUpDownCounter{instrument: in}, nil
+}
+
+type float64ObservableGauge struct {
+	metricnoop.Float64ObservableGauge
+	*instrument
+}
+
+func (m *meter) Float64ObservableGauge(name string, opts ...otelmetric.Float64ObservableGaugeOption) (otelmetric.Float64ObservableGauge, error) {
+	in := m.instrument(name, kindObservableGauge)
+	m.registerFloat64Callbacks(in, otelmetric.NewFloat64ObservableGaugeConfig(opts...).Callbacks())
+	return float64ObservableGauge{instrument: in}, nil
+}
@@ -0,0 +1,227 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package opentelemetry
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
+	globalinternal "gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	otelmetric "go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+)
+
+var _ otelmetric.MeterProvider = (*MeterProvider)(nil)
+
+const (
+	// defaultMaxAttributeSets is the default maximum number of distinct attribute
+	// sets recorded for each instrument.
+	defaultMaxAttributeSets = 1000
+	// defaultCollectInterval is the default interval at which the callbacks of the
+	// observable instruments are called.
+	defaultCollectInterval = 10 * time.Second
+)
+
+// StatsdClient is the DogStatsD client a MeterProvider records measurements with.
+// It is implemented by the clients of github.com/DataDog/datadog-go/v5/statsd.
+type StatsdClient interface {
+	Count(name string, value int64, tags []string, rate float64) error
+	Gauge(name string, value float64, tags []string, rate float64) error
+	DistributionSamples(name string, values []float64, tags []string, rate float64) error
+	Flush() error
+	Close() error
+}
+
+// MeterProvider provides an implementation of the OpenTelemetry MeterProvider
+// interface, recording the measurements of its instruments as DogStatsD metrics:
+//
+//   - counters are recorded as counts,
+//   - up-down counters as gauges of their sum,
+//   - histograms as distributions,
+//   - gauges as gauges.
+//
+// The observable instruments are recorded the same way, from the values reported
+// by their callbacks, which are called periodically.
+//
+// Metrics are tagged with the service, env and version of the application, as
+// configured in the tracer when it is started, and with the attributes of the
+// measurements. To bound the cardinality of the
+// metrics, each instrument records at most a fixed number of distinct attribute
+// sets; the measurements with other attributes are recorded with the
+// otel.metric.overflow:true tag instead.
+//
+// The instrumentation scope, descriptions, units and histogram bucket boundaries
+// are ignored.
+type MeterProvider struct {
+	metricnoop.MeterProvider // https://pkg.go.dev/go.opentelemetry.io/otel/metric#hdr-API_Implementations
+	meter                    *meter
+	closeStatsd              bool          // whether the statsd client was created by the provider
+	stopped                  uint32        // stopped indicates whether the MeterProvider has been shutdown.
+	stop                     chan struct{} // closing stop stops the collection of observable instruments
+	wg                       sync.WaitGroup
+	sync.Once
+}
+
+// MeterProviderOption configures a MeterProvider.
+type MeterProviderOption func(*meterConfig)
+
+type meterConfig struct {
+	statsd           StatsdClient
+	tags             []string // tags added to all the metrics
+	maxAttributeSets int
+	collectInterval  time.Duration
+}
+
+// WithStatsd sets the DogStatsD client the measurements are recorded with. By
+// default, the MeterProvider creates its own client, sending to the DogStatsD
+// address configured in the tracer, and closes it when it's shut down.
+func WithStatsd(client StatsdClient) MeterProviderOption {
+	return func(c *meterConfig) {
+		c.statsd = client
+	}
+}
+
+// WithMaxAttributeSets sets the maximum number of distinct attribute sets recorded
+// for each instrument, 1000 by default. The measurements with other attribute sets
+// are recorded with the otel.metric.overflow:true tag, without their attributes.
+func WithMaxAttributeSets(n int) MeterProviderOption {
+	return func(c *meterConfig) {
+		c.maxAttributeSets = n
+	}
+}
+
+// WithCollectInterval sets the interval at which the observable instruments are
+// collected, 10 seconds by default.
+func WithCollectInterval(d time.Duration) MeterProviderOption {
+	return func(c *meterConfig) {
+		c.collectInterval = d
+	}
+}
+
+// NewMeterProvider returns an instance of an OpenTelemetry MeterProvider recording
+// measurements as DogStatsD metrics. This MeterProvider only supports a singleton
+// meter, and repeated calls to the Meter() method return the same instance.
+func NewMeterProvider(opts ...MeterProviderOption) *MeterProvider {
+	cfg := &meterConfig{
+		maxAttributeSets: defaultMaxAttributeSets,
+		collectInterval:  defaultCollectInterval,
+	}
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	if cfg.maxAttributeSets <= 0 {
+		cfg.maxAttributeSets = defaultMaxAttributeSets
+	}
+	if cfg.collectInterval <= 0 {
+		cfg.collectInterval = defaultCollectInterval
+	}
+	p := &MeterProvider{stop: make(chan struct{})}
+	var tags []string
+	if t, ok := internal.GetGlobalTracer().(serviceTagsTracer); ok {
+		tags = t.GetServiceTags()
+	} else {
+		tags = defaultMeterTags()
+	}
+	if cfg.statsd != nil {
+		cfg.tags = tags
+	} else {
+		// the metrics are tagged by the client, which doesn't share the global
+		// tags of the tracer client to keep their cardinality low
+		client, err := globalinternal.NewStatsdClient(globalconfig.DogstatsdAddr(), tags)
+		if err != nil {
+			log.Warn("opentelemetry: unable to create the DogStatsD client, metrics will be dropped: %v", err)
+		}
+		cfg.statsd = client
+		p.closeStatsd = true
+	}
+	p.meter = newMeter(cfg)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		tick := time.NewTicker(cfg.collectInterval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+				p.meter.collect(context.Background())
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// serviceTagsTracer is implemented by the tracer of the tracer package, once
+// started, giving access to the configuration of the application.
+type serviceTagsTracer interface {
+	GetServiceTags() []string
+}
+
+// defaultMeterTags returns the service, env and version tags of the application,
+// when the tracer isn't started.
+func defaultMeterTags() []string {
+	var tags []string
+	service := globalconfig.ServiceName()
+	if service == "" {
+		service = os.Getenv("DD_SERVICE")
+	}
+	if service != "" {
+		tags = append(tags, "service:"+service)
+	}
+	if env := os.Getenv("DD_ENV"); env != "" {
+		tags = append(tags, "env:"+env)
+	}
+	if version := os.Getenv("DD_VERSION"); version != "" {
+		tags = append(tags, "version:"+version)
+	}
+	return tags
+}
+
+// Meter returns the singleton meter of the MeterProvider, ignoring the provided
+// name and options. If the MeterProvider has already been shut down, this will
+// return a no-op meter.
+func (p *MeterProvider) Meter(_ string, _ ...otelmetric.MeterOption) otelmetric.Meter {
+	if atomic.LoadUint32(&p.stopped) != 0 {
+		return metricnoop.NewMeterProvider().Meter("")
+	}
+	return p.meter
+}
+
+// ForceFlush collects the observable instruments and flushes the buffered
+// metrics to DogStatsD.
+func (p *MeterProvider) ForceFlush(ctx context.Context) error {
+	if atomic.LoadUint32(&p.stopped) != 0 {
+		return nil
+	}
+	p.meter.collect(ctx)
+	return p.meter.cfg.statsd.Flush()
+}
+
+// Shutdown collects the observable instruments one last time, flushes the
+// buffered metrics and stops the MeterProvider. Subsequent calls are valid but
+// become no-op.
+func (p *MeterProvider) Shutdown(ctx context.Context) error {
+	var err error
+	p.Once.Do(func() {
+		close(p.stop)
+		p.wg.Wait()
+		p.meter.collect(ctx)
+		atomic.StoreUint32(&p.stopped, 1)
+		err = p.meter.cfg.statsd.Flush()
+		if p.closeStatsd {
+			p.meter.cfg.statsd.Close()
+		}
+	})
+	return err
+}
@@ -0,0 +1,228 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package opentelemetry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	internallog "gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/statsdtest"
+)
+
+func newTestMeterProvider(t *testing.T, opts ...MeterProviderOption) (*MeterProvider, *statsdtest.TestStatsdClient) {
+	t.Setenv("DD_SERVICE", "svc")
+	t.Setenv("DD_ENV", "prod")
+	t.Setenv("DD_VERSION", "1.2.3")
+	statsd := &statsdtest.TestStatsdClient{}
+	mp := NewMeterProvider(append([]MeterProviderOption{WithStatsd(statsd)}, opts...)...)
+	t.Cleanup(func() { mp.Shutdown(context.Background()) })
+	return mp, statsd
+}
+
+func TestMeterProviderInstruments(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	mp, statsd := newTestMeterProvider(t)
+	m := mp.Meter("test")
+	assert.True(m == mp.Meter("other"))
+	ctx := context.Background()
+	attrs := otelmetric.WithAttributes(attribute.String("k", "v"), attribute.Int("n", 1))
+	tags := []string{"service:svc", "env:prod", "version:1.2.3", "k:v", "n:1"}
+
+	t.Run("counter", func(t *testing.T) {
+		c, err := m.Int64Counter("int.counter")
+		require.NoError(err)
+		c.Add(ctx, 3, attrs)
+		c.Add(ctx, -1, attrs) // counters are monotonic
+		calls := statsdtest.FilterCallsByName(statsd.CountCalls(), "int.counter")
+		require.Len(calls, 1)
+		assert.Equal(int64(3), calls[0].IntVal())
+		assert.Equal(tags, calls[0].Tags())
+
+		f, err := m.Float64Counter("float.counter")
+		require.NoError(err)
+		f.Add(ctx, 0.6)
+		f.Add(ctx, 0.6)
+		f.Add(ctx, 0.6)
+		calls = statsdtest.FilterCallsByName(statsd.CountCalls(), "float.counter")
+		require.Len(calls, 1)
+		assert.Equal(int64(1), calls[0].IntVal())
+	})
+
+	t.Run("updowncounter", func(t *testing.T) {
+		c, err := m.Int64UpDownCounter("updown")
+		require.NoError(err)
+		c.Add(ctx, 5, attrs)
+		c.Add(ctx, -2, attrs)
+		calls := statsdtest.FilterCallsByName(statsd.GaugeCalls(), "updown")
+		require.Len(calls, 2)
+		assert.Equal(float64(3), calls[1].FloatVal())
+		assert.Equal(tags, calls[1].Tags())
+	})
+
+	t.Run("histogram", func(t *testing.T) {
+		h, err := m.Float64Histogram("histogram")
+		require.NoError(err)
+		h.Record(ctx, 1.5, otelmetric.WithAttributes(attribute.String("k", "v"), attribute.Int("n", 1)))
+		calls := statsdtest.FilterCallsByName(statsd.DistributionCalls(), "histogram")
+		require.Len(calls, 1)
+		assert.Equal(1.5, calls[0].FloatVal())
+		assert.Equal(tags, calls[0].Tags())
+	})
+
+	t.Run("gauge", func(t *testing.T) {
+		g, err := m.Int64Gauge("gauge")
+		require.NoError(err)
+		g.Record(ctx, 7)
+		g.Record(ctx, 4)
+		calls := statsdtest.FilterCallsByName(statsd.GaugeCalls(), "gauge")
+		require.Len(calls, 2)
+		assert.Equal(float64(4), calls[1].FloatVal())
+		assert.Equal([]string{"service:svc", "env:prod", "version:1.2.3"}, calls[1].Tags())
+	})
+}
+
+func TestMeterProviderObservable(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	mp, statsd := newTestMeterProvider(t)
+	m := mp.Meter("test")
+	ctx := context.Background()
+
+	var total int64
+	_, err := m.Int64ObservableCounter("observable.counter", otelmetric.WithInt64Callback(func(_ context.Context, o otelmetric.Int64Observer) error {
+		o.Observe(total)
+		return nil
+	}))
+	require.NoError(err)
+	gauge, err := m.Float64ObservableGauge("observable.gauge")
+	require.NoError(err)
+	reg, err := m.RegisterCallback(func(_ context.Context, o otelmetric.Observer) error {
+		o.ObserveFloat64(gauge, 0.5, otelmetric.WithAttributes(attribute.Bool("b", true)))
+		return nil
+	}, gauge)
+	require.NoError(err)
+
+	total = 10
+	require.NoError(mp.ForceFlush(ctx))
+	total = 15
+	require.NoError(mp.ForceFlush(ctx))
+	total = 4 // reset
+	require.NoError(mp.ForceFlush(ctx))
+
+	var counts []int64
+	for _, c := range statsd.GetCallsByName("observable.counter") {
+		counts = append(counts, c.IntVal())
+	}
+	assert.Equal([]int64{10, 5, 4}, counts)
+
+	calls := statsd.GetCallsByName("observable.gauge")
+	require.Len(calls, 3)
+	assert.Equal(0.5, calls[0].FloatVal())
+	assert.Equal([]string{"service:svc", "env:prod", "version:1.2.3", "b:true"}, calls[0].Tags())
+
+	require.NoError(reg.Unregister())
+	require.NoError(mp.ForceFlush(ctx))
+	assert.Len(statsd.GetCallsByName("observable.gauge"), 3)
+	assert.Equal(4, statsd.Flushed())
+}
+
+func TestMeterProviderCardinality(t *testing.T) {
+	assert := assert.New(t)
+	mp, statsd := newTestMeterProvider(t, WithMaxAttributeSets(2))
+	c, err := mp.Meter("test").Int64Counter("counter")
+	assert.NoError(err)
+	for i := 0; i < 4; i++ {
+		c.Add(context.Background(), 1, otelmetric.WithAttributes(attribute.Int("i", i)))
+	}
+	c.Add(context.Background(), 1, otelmetric.WithAttributes(attribute.String("long", fmt.Sprintf("%0300d", 0))))
+
+	calls := statsd.GetCallsByName("counter")
+	assert.Len(calls, 5)
+	assert.Contains(calls[0].Tags(), "i:0")
+	assert.Contains(calls[1].Tags(), "i:1")
+	for _, c := range calls[2:] {
+		assert.Equal([]string{"service:svc", "env:prod", "version:1.2.3", overflowTag}, c.Tags())
+	}
+
+	mp, statsd = newTestMeterProvider(t)
+	c, _ = mp.Meter("test").Int64Counter("counter")
+	c.Add(context.Background(), 1, otelmetric.WithAttributes(attribute.String("long", fmt.Sprintf("%0300d", 0))))
+	calls = statsd.GetCallsByName("counter")
+	assert.Len(calls, 1)
+	assert.Len(calls[0].Tags()[3], maxTagLength)
+}
+
+func TestMeterProviderShutdown(t *testing.T) {
+	assert := assert.New(t)
+	mp, statsd := newTestMeterProvider(t, WithCollectInterval(time.Millisecond))
+	var calls int
+	var mu sync.Mutex
+	_, err := mp.Meter("test").Int64ObservableGauge("gauge", otelmetric.WithInt64Callback(func(_ context.Context, o otelmetric.Int64Observer) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		o.Observe(1)
+		return nil
+	}))
+	assert.NoError(err)
+	assert.Eventually(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls >= 2
+	}, time.Second, time.Millisecond)
+
+	assert.NoError(mp.Shutdown(context.Background()))
+	assert.NoError(mp.Shutdown(context.Background()))
+	assert.False(statsd.Closed()) // the client is owned by the caller
+	n := len(statsd.GetCallsByName("gauge"))
+
+	c, err := mp.Meter("test").Int64Counter("counter")
+	assert.NoError(err)
+	c.Add(context.Background(), 1)
+	assert.NoError(mp.ForceFlush(context.Background()))
+	assert.Len(statsd.GetCallsByName("counter"), 0)
+	assert.Len(statsd.GetCallsByName("gauge"), n)
+}
+
+func TestMeterProviderTracer(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("DD_ENV", "staging")
+	tracer.Start(
+		tracer.WithService("svc"),
+		tracer.WithEnv("prod"),
+		tracer.WithServiceVersion("1.2.3"),
+		tracer.WithLogger(internallog.DiscardLogger{}),
+	)
+	defer tracer.Stop()
+
+	t.Run("statsd", func(t *testing.T) {
+		statsd := &statsdtest.TestStatsdClient{}
+		mp := NewMeterProvider(WithStatsd(statsd))
+		defer mp.Shutdown(context.Background())
+		assert.Equal([]string{"service:svc", "env:prod", "version:1.2.3"}, mp.meter.cfg.tags)
+	})
+
+	t.Run("dedicated", func(t *testing.T) {
+		mp := NewMeterProvider()
+		// the dedicated client is tagged with the service, env and version only
+		assert.Empty(mp.meter.cfg.tags)
+		assert.True(mp.closeStatsd)
+		// the provider doesn't need to be shut down before the tracer is stopped
+		tracer.Stop()
+		assert.NoError(mp.Shutdown(context.Background()))
+	})
+}
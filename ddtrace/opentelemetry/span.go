@@ -12,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
@@ -79,7 +80,11 @@ func (s *span) End(options ...oteltrace.SpanEndOption) {
 	for k, v := range s.attributes {
 		s.DD.SetTag(k, v)
 	}
-	if s.events != nil {
+	if es, ok := s.DD.(ddtrace.SpanWithEvents); ok {
+		for _, e := range s.events {
+			es.AddEvent(e.Name, time.Unix(0, e.TimeUnixNano), e.Attributes)
+		}
+	} else if s.events != nil {
 		b, err := json.Marshal(s.events)
 		if err == nil {
 			s.DD.SetTag("events", string(b))
@@ -162,6 +167,22 @@ func uint64ToByte(n uint64, b []byte) {
 	binary.BigEndian.PutUint64(b, n)
 }
 
+// AddLink adds a link to the span of link.SpanContext, unless the span has ended.
+// The link is added to the underlying Datadog span with AddLink.
+func (s *span) AddLink(link oteltrace.Link) {
+	if !link.SpanContext.IsValid() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finished {
+		return
+	}
+	if ls, ok := s.DD.(tracer.SpanWithAddLink); ok {
+		ls.AddLink(&otelCtxToDDCtx{link.SpanContext}, linkAttributes(link))
+	}
+}
+
 // IsRecording returns the recording state of the Span. It will return
 // true if the Span is active and events can be recorded.
 func (s *span) IsRecording() bool {
@@ -204,6 +225,29 @@ func (s *span) AddEvent(name string, opts ...oteltrace.EventOption) {
 	s.events = append(s.events, e)
 }
 
+// RecordError records err, and the errors it wraps, as exception events on the
+// span, along with the stack trace if the oteltrace.WithStackTrace option is set.
+// It doesn't change the status of the span.
+func (s *span) RecordError(err error, opts ...oteltrace.EventOption) {
+	if err == nil || !s.IsRecording() {
+		return
+	}
+	es, ok := s.DD.(ddtrace.SpanWithExceptions)
+	if !ok {
+		return
+	}
+	c := oteltrace.NewEventConfig(opts...)
+	attrs := make(map[string]interface{}, len(c.Attributes()))
+	for _, a := range c.Attributes() {
+		attrs[string(a.Key)] = a.Value.AsInterface()
+	}
+	eopts := []tracer.ExceptionOption{tracer.ExceptionTime(c.Timestamp()), tracer.ExceptionAttributes(attrs)}
+	if !c.StackTrace() {
+		eopts = append(eopts, tracer.ExceptionNoStack())
+	}
+	es.RecordException(err, eopts...)
+}
+
 // SetAttributes sets the key-value pairs as tags on the span.
 // Every value is propagated as an interface.
 // Some attribute keys are reserved and will be remapped to Datadog reserved tags.
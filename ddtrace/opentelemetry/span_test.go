@@ -185,6 +185,52 @@ func TestSpanLink(t *testing.T) {
 	assert.Equal(uint32(0x80000001), spanLinks[0].Flags) // sampled and set
 }
 
+func TestSpanAddLink(t *testing.T) {
+	assert := assert.New(t)
+	_, payloads, cleanup := mockTracerProvider(t)
+	tr := otel.Tracer("")
+	defer cleanup()
+
+	traceID, _ := oteltrace.TraceIDFromHex("00000000000001c8000000000000007b")
+	spanID, _ := oteltrace.SpanIDFromHex("000000000000000f")
+	traceState, _ := oteltrace.ParseTraceState("dd_origin=ci")
+	remoteSpanContext := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceState: traceState,
+		Remote:     true,
+	})
+
+	_, span := tr.Start(context.Background(), "span_with_link")
+	span.AddLink(oteltrace.Link{
+		SpanContext: remoteSpanContext,
+		Attributes:  []attribute.KeyValue{attribute.String("link.name", "alpha_transaction")},
+	})
+	span.AddLink(oteltrace.Link{}) // invalid span context
+	span.End()
+	span.AddLink(oteltrace.Link{SpanContext: remoteSpanContext}) // ended
+
+	tracer.Flush()
+	payload, err := waitForPayload(payloads)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.Len(payload, 1)
+	assert.Len(payload[0], 1)
+
+	var spanLinks []ddtrace.SpanLink
+	spanLinkBytes, _ := json.Marshal(payload[0][0]["span_links"])
+	json.Unmarshal(spanLinkBytes, &spanLinks)
+	assert.Len(spanLinks, 1)
+
+	assert.Equal(uint64(123), spanLinks[0].TraceID)
+	assert.Equal(uint64(456), spanLinks[0].TraceIDHigh)
+	assert.Equal(uint64(15), spanLinks[0].SpanID)
+	assert.Equal(map[string]string{"link.name": "alpha_transaction"}, spanLinks[0].Attributes)
+	assert.Equal("dd_origin=ci", spanLinks[0].Tracestate)
+	assert.Equal(uint32(0x80000000), spanLinks[0].Flags) // not sampled and set
+}
+
 func TestSpanEnd(t *testing.T) {
 	assert := assert.New(t)
 	_, payloads, cleanup := mockTracerProvider(t)
@@ -380,6 +426,37 @@ func TestSpanAddEvent(t *testing.T) {
 	})
 }
 
+func TestSpanRecordError(t *testing.T) {
+	assert := assert.New(t)
+	_, payloads, cleanup := mockTracerProvider(t)
+	tr := otel.Tracer("")
+	defer cleanup()
+
+	_, sp := tr.Start(context.Background(), "span")
+	now := time.Now()
+	sp.RecordError(fmt.Errorf("wrapped: %w", io.EOF), oteltrace.WithTimestamp(now), oteltrace.WithAttributes(attribute.String("key", "value")))
+	sp.RecordError(nil)
+	sp.End()
+	sp.RecordError(io.EOF)
+
+	tracer.Flush()
+	traces, err := waitForPayload(payloads)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	meta := traces[0][0]["meta"].(map[string]interface{})
+	assert.NotContains(meta, "error.message")
+	var events []map[string]interface{}
+	assert.NoError(json.Unmarshal([]byte(meta["events"].(string)), &events))
+	assert.Len(events, 2)
+	assert.Equal("exception", events[0]["name"])
+	attrs := events[0]["attributes"].(map[string]interface{})
+	assert.Equal("wrapped: EOF", attrs["exception.message"])
+	assert.Equal("value", attrs["key"])
+	assert.NotContains(attrs, "exception.stacktrace")
+	assert.Equal("EOF", events[1]["attributes"].(map[string]interface{})["exception.message"])
+}
+
 // attributesContains returns true if attrs contains an attribute.KeyValue with the provided key and val
 func attributesContains(attrs map[string]interface{}, key string, val interface{}) bool {
 	for k, v := range attrs {
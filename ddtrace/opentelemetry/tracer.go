@@ -66,22 +66,7 @@ func (t *oteltracer) Start(ctx context.Context, spanName string, opts ...oteltra
 	if len(ssConfig.Links()) > 0 {
 		links := make([]ddtrace.SpanLink, 0, len(ssConfig.Links()))
 		for _, link := range ssConfig.Links() {
-			ctx := otelCtxToDDCtx{link.SpanContext}
-			attrs := make(map[string]string, len(link.Attributes))
-			for _, attr := range link.Attributes {
-				attrs[string(attr.Key)] = attr.Value.Emit()
-			}
-			links = append(links, ddtrace.SpanLink{
-				TraceID:     ctx.TraceID(),
-				TraceIDHigh: ctx.TraceIDUpper(),
-				SpanID:      ctx.SpanID(),
-				Tracestate:  link.SpanContext.TraceState().String(),
-				Attributes:  attrs,
-				// To distinguish between "not sampled" and "not set", Datadog
-				// will rely on the highest bit being set. The OTel API doesn't
-				// differentiate this, so we will just always mark it as set.
-				Flags: uint32(link.SpanContext.TraceFlags()) | (1 << 31),
-			})
+			links = append(links, tracer.SpanLinkFromContext(&otelCtxToDDCtx{link.SpanContext}, linkAttributes(link)))
 		}
 		ddopts = append(ddopts, tracer.WithSpanLinks(links))
 	}
@@ -102,6 +87,15 @@ func (t *oteltracer) Start(ctx context.Context, spanName string, opts ...oteltra
 	return ctx, os
 }
 
+// linkAttributes returns the attributes of link as span link attributes.
+func linkAttributes(link oteltrace.Link) map[string]string {
+	attrs := make(map[string]string, len(link.Attributes))
+	for _, attr := range link.Attributes {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	return attrs
+}
+
 type otelCtxToDDCtx struct {
 	oc oteltrace.SpanContext
 }
@@ -131,3 +125,19 @@ func (c *otelCtxToDDCtx) TraceID128() string {
 func (c *otelCtxToDDCtx) TraceID128Bytes() [16]byte {
 	return c.oc.TraceID()
 }
+
+// Tracestate returns the W3C tracestate of the context, for the span links
+// built with tracer.SpanLinkFromContext.
+func (c *otelCtxToDDCtx) Tracestate() string {
+	return c.oc.TraceState().String()
+}
+
+// SamplingPriority returns the sampling decision of the trace flags, for the span
+// links built with tracer.SpanLinkFromContext. The OTel API doesn't distinguish
+// "not sampled" from "not set", so the decision is always reported as set.
+func (c *otelCtxToDDCtx) SamplingPriority() (int, bool) {
+	if c.oc.IsSampled() {
+		return 1, true
+	}
+	return 0, true
+}
@@ -0,0 +1,183 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
+)
+
+// baggageTagPrefix prefixes the span tags holding the baggage items copied by
+// WithBaggageTagKeys.
+const baggageTagPrefix = "baggage."
+
+// baggageLimiter applies the baggage limits and allow-list of a PropagatorConfig
+// to a sequence of baggage items.
+type baggageLimiter struct {
+	maxItems    int
+	maxBytes    int
+	allowedKeys map[string]struct{} // nil if all keys are allowed
+	style       string              // the propagation style of the baggage, reported through telemetry
+
+	items int
+	bytes int
+	// truncated holds the limit which was exceeded, if any: "item count" or "byte count".
+	truncated string
+}
+
+func newBaggageLimiter(cfg *PropagatorConfig) *baggageLimiter {
+	l := &baggageLimiter{maxItems: baggageMaxItems, maxBytes: baggageMaxBytes, style: "baggage"}
+	if cfg == nil {
+		return l
+	}
+	if cfg.BaggageMaxItems > 0 {
+		l.maxItems = cfg.BaggageMaxItems
+	}
+	if cfg.BaggageMaxBytes > 0 {
+		l.maxBytes = cfg.BaggageMaxBytes
+	}
+	if len(cfg.BaggageAllowedKeys) > 0 {
+		l.allowedKeys = make(map[string]struct{}, len(cfg.BaggageAllowedKeys))
+		for _, k := range cfg.BaggageAllowedKeys {
+			l.allowedKeys[k] = struct{}{}
+		}
+	}
+	return l
+}
+
+// allowed reports whether the baggage item with the given key may be extracted,
+// and reports the item as dropped through telemetry otherwise.
+func (l *baggageLimiter) allowed(key string) bool {
+	if l.allowedKeys == nil {
+		return true
+	}
+	if _, ok := l.allowedKeys[key]; ok {
+		return true
+	}
+	telemetry.Count(telemetry.NamespaceTracers, "context_header.dropped",
+		[]string{"header_style:" + l.style, "reason:key_not_allowed"}).Submit(1)
+	return false
+}
+
+// add accounts for the encoded baggage item, in <key>=<value> format, and reports
+// whether it fits in the limits. Once a limit is exceeded, the truncation is
+// reported through telemetry and no other item is accepted.
+func (l *baggageLimiter) add(item string) bool {
+	if l.truncated != "" {
+		return false
+	}
+	size := len(item)
+	if l.items > 0 {
+		size++ // account for the comma separator
+	}
+	switch {
+	case l.items >= l.maxItems:
+		l.truncated = "item count"
+		telemetry.Count(telemetry.NamespaceTracers, "context_header.truncated",
+			[]string{"header_style:" + l.style, "truncation_reason:baggage_item_count_exceeded"}).Submit(1)
+		return false
+	case l.bytes+size > l.maxBytes:
+		l.truncated = "byte count"
+		telemetry.Count(telemetry.NamespaceTracers, "context_header.truncated",
+			[]string{"header_style:" + l.style, "truncation_reason:baggage_byte_count_exceeded"}).Submit(1)
+		return false
+	}
+	l.items++
+	l.bytes += size
+	return true
+}
+
+// limitExtractedBaggage applies the baggage limits and allow-list of cfg to the
+// baggage extracted in ctx, whichever propagator it comes from. Items are
+// considered in the order of their keys.
+func limitExtractedBaggage(cfg *PropagatorConfig, ctx *spanContext) {
+	if ctx == nil || atomic.LoadUint32(&ctx.hasBaggage) == 0 {
+		return
+	}
+	l := newBaggageLimiter(cfg)
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	for _, k := range sortedKeys(ctx.baggage) {
+		if !l.allowed(k) || !l.add(encodeKey(k)+"="+encodeValue(ctx.baggage[k])) {
+			delete(ctx.baggage, k)
+		}
+	}
+	if len(ctx.baggage) == 0 {
+		atomic.StoreUint32(&ctx.hasBaggage, 0)
+	}
+}
+
+// injectBaggageHeaders sets the baggage items of ctx in writer, each in a header
+// made of prefix and the item key, as done by the datadog and jaeger propagation
+// styles. Items are considered in the order of their keys, and those exceeding
+// the baggage limits of cfg are dropped. The values are encoded with encode, if
+// not nil.
+func injectBaggageHeaders(cfg *PropagatorConfig, style string, ctx *spanContext, writer TextMapWriter, prefix string, encode func(string) string) {
+	if atomic.LoadUint32(&ctx.hasBaggage) == 0 {
+		return
+	}
+	ctx.mu.RLock()
+	baggage := make(map[string]string, len(ctx.baggage))
+	for k, v := range ctx.baggage {
+		baggage[k] = v
+	}
+	ctx.mu.RUnlock()
+
+	l := newBaggageLimiter(cfg)
+	l.style = style
+	for _, k := range sortedKeys(baggage) {
+		v := baggage[k]
+		if encode != nil {
+			v = encode(v)
+		}
+		if !l.add(k + "=" + v) {
+			log.Warn("Baggage %s limit exceeded. Only the first %d items and %d bytes will be propagated.", l.truncated, l.maxItems, l.maxBytes)
+			return
+		}
+		writer.Set(prefix+k, v)
+	}
+}
+
+// setBaggageTags copies the baggage items with the given keys into span tags
+// prefixed with "baggage.". The key "*" copies all baggage items.
+func (s *span) setBaggageTags(keys []string) {
+	tags := make(map[string]string)
+	for _, k := range keys {
+		if k == "*" {
+			s.context.ForeachBaggageItem(func(k, v string) bool {
+				tags[k] = v
+				return true
+			})
+			break
+		}
+		if v := s.context.baggageItem(k); v != "" {
+			tags[k] = v
+		}
+	}
+	if len(tags) == 0 {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	if s.finished {
+		return
+	}
+	for k, v := range tags {
+		s.setMeta(baggageTagPrefix+k, v)
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,261 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry/telemetrytest"
+)
+
+func baggageOf(ctx interface {
+	ForeachBaggageItem(func(k, v string) bool)
+}) map[string]string {
+	got := make(map[string]string)
+	ctx.ForeachBaggageItem(func(k, v string) bool {
+		got[k] = v
+		return true
+	})
+	return got
+}
+
+func TestBaggageLimits(t *testing.T) {
+	t.Run("inject", func(t *testing.T) {
+		telemetryClient := new(telemetrytest.RecordClient)
+		defer telemetry.MockClient(telemetryClient)()
+		tracer := newTracer(WithBaggageLimits(2, 0))
+		defer tracer.Stop()
+
+		root := tracer.StartSpan("web.request").(*span)
+		root.SetBaggageItem("c", "3")
+		root.SetBaggageItem("a", "1")
+		root.SetBaggageItem("b", "2")
+		carrier := TextMapCarrier{}
+		require.NoError(t, tracer.Inject(root.Context(), carrier))
+		assert.Equal(t, "a=1,b=2", carrier[DefaultBaggageHeader])
+		assert.Equal(t, 1.0, telemetryClient.Count(telemetry.NamespaceTracers, "context_header.truncated",
+			[]string{"header_style:baggage", "truncation_reason:baggage_item_count_exceeded"}).Get())
+	})
+
+	t.Run("inject-datadog", func(t *testing.T) {
+		telemetryClient := new(telemetrytest.RecordClient)
+		defer telemetry.MockClient(telemetryClient)()
+		t.Setenv(headerPropagationStyleInject, "datadog")
+		tracer := newTracer(WithBaggageLimits(2, 0))
+		defer tracer.Stop()
+
+		root := tracer.StartSpan("web.request").(*span)
+		root.SetBaggageItem("c", "3")
+		root.SetBaggageItem("a", "1")
+		root.SetBaggageItem("b", "2")
+		carrier := TextMapCarrier{}
+		require.NoError(t, tracer.Inject(root.Context(), carrier))
+		assert.Equal(t, "1", carrier[DefaultBaggageHeaderPrefix+"a"])
+		assert.Equal(t, "2", carrier[DefaultBaggageHeaderPrefix+"b"])
+		assert.NotContains(t, carrier, DefaultBaggageHeaderPrefix+"c")
+		assert.Equal(t, 1.0, telemetryClient.Count(telemetry.NamespaceTracers, "context_header.truncated",
+			[]string{"header_style:datadog", "truncation_reason:baggage_item_count_exceeded"}).Get())
+	})
+
+	t.Run("inject-jaeger", func(t *testing.T) {
+		t.Setenv(headerPropagationStyleInject, "jaeger")
+		tracer := newTracer(WithBaggageLimits(0, 12))
+		defer tracer.Stop()
+
+		root := tracer.StartSpan("web.request").(*span)
+		root.SetBaggageItem("a", "x y")
+		root.SetBaggageItem("b", "2")
+		root.SetBaggageItem("c", "3")
+		carrier := TextMapCarrier{}
+		require.NoError(t, tracer.Inject(root.Context(), carrier))
+		// the encoded values are accounted for: "a=x+y" and "b=2" fit in 12 bytes
+		assert.Equal(t, "x+y", carrier[jaegerBaggagePrefix+"a"])
+		assert.Equal(t, "2", carrier[jaegerBaggagePrefix+"b"])
+		assert.NotContains(t, carrier, jaegerBaggagePrefix+"c")
+	})
+
+	t.Run("extract-items", func(t *testing.T) {
+		telemetryClient := new(telemetrytest.RecordClient)
+		defer telemetry.MockClient(telemetryClient)()
+		tracer := newTracer(WithBaggageLimits(2, 0))
+		defer tracer.Stop()
+
+		ctx, err := tracer.Extract(TextMapCarrier{DefaultBaggageHeader: "c=3,a=1,b=2"})
+		require.NoError(t, err)
+		// the first items of the header are kept
+		assert.Equal(t, map[string]string{"c": "3", "a": "1"}, baggageOf(ctx))
+		assert.Equal(t, 1.0, telemetryClient.Count(telemetry.NamespaceTracers, "context_header.truncated",
+			[]string{"header_style:baggage", "truncation_reason:baggage_item_count_exceeded"}).Get())
+	})
+
+	t.Run("extract-bytes", func(t *testing.T) {
+		telemetryClient := new(telemetrytest.RecordClient)
+		defer telemetry.MockClient(telemetryClient)()
+		tracer := newTracer(WithBaggageLimits(0, 8))
+		defer tracer.Stop()
+
+		ctx, err := tracer.Extract(TextMapCarrier{DefaultBaggageHeader: "a=1,b=2,c=3"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"a": "1", "b": "2"}, baggageOf(ctx))
+		assert.Equal(t, 1.0, telemetryClient.Count(telemetry.NamespaceTracers, "context_header.truncated",
+			[]string{"header_style:baggage", "truncation_reason:baggage_byte_count_exceeded"}).Get())
+	})
+
+	t.Run("extract-invalid", func(t *testing.T) {
+		tracer := newTracer(WithBaggageLimits(1, 0))
+		defer tracer.Stop()
+
+		// items past the limits are still validated
+		_, err := tracer.Extract(TextMapCarrier{DefaultBaggageHeader: "a=1,b"})
+		assert.Error(t, err)
+	})
+
+	t.Run("extract-other-propagators", func(t *testing.T) {
+		t.Setenv(headerPropagationStyleExtract, "datadog,jaeger")
+		tracer := newTracer(WithBaggageLimits(2, 0))
+		defer tracer.Stop()
+
+		ctx, err := tracer.Extract(TextMapCarrier{
+			DefaultTraceIDHeader:             "1",
+			DefaultParentIDHeader:            "2",
+			DefaultBaggageHeaderPrefix + "c": "3",
+			DefaultBaggageHeaderPrefix + "a": "1",
+			DefaultBaggageHeaderPrefix + "b": "2",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"a": "1", "b": "2"}, baggageOf(ctx))
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("DD_TRACE_BAGGAGE_MAX_ITEMS", "1")
+		t.Setenv("DD_TRACE_BAGGAGE_MAX_BYTES", "100")
+		c := newConfig()
+		assert.Equal(t, 1, c.baggageMaxItems)
+		assert.Equal(t, 100, c.baggageMaxBytes)
+		cfg := c.propagator.(*chainedPropagator).cfg
+		assert.Equal(t, 1, cfg.BaggageMaxItems)
+		assert.Equal(t, 100, cfg.BaggageMaxBytes)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		c := newConfig(WithBaggageLimits(-1, 0))
+		cfg := c.propagator.(*chainedPropagator).cfg
+		assert.Equal(t, baggageMaxItems, cfg.BaggageMaxItems)
+		assert.Equal(t, baggageMaxBytes, cfg.BaggageMaxBytes)
+	})
+}
+
+func TestBaggageAllowedKeys(t *testing.T) {
+	t.Run("extract", func(t *testing.T) {
+		telemetryClient := new(telemetrytest.RecordClient)
+		defer telemetry.MockClient(telemetryClient)()
+		tracer := newTracer(WithBaggageAllowedKeys("user.tier", "region"))
+		defer tracer.Stop()
+
+		ctx, err := tracer.Extract(TextMapCarrier{DefaultBaggageHeader: "user.tier=gold,session=abc,region=eu,token=xyz"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"user.tier": "gold", "region": "eu"}, baggageOf(ctx))
+		assert.Equal(t, 2.0, telemetryClient.Count(telemetry.NamespaceTracers, "context_header.dropped",
+			[]string{"header_style:baggage", "reason:key_not_allowed"}).Get())
+	})
+
+	t.Run("none-allowed", func(t *testing.T) {
+		tracer := newTracer(WithBaggageAllowedKeys("user.tier"))
+		defer tracer.Stop()
+
+		_, err := tracer.Extract(TextMapCarrier{DefaultBaggageHeader: "session=abc"})
+		assert.Equal(t, ErrSpanContextNotFound, err)
+
+		ctx, err := tracer.Extract(TextMapCarrier{
+			DefaultTraceIDHeader:                   "1",
+			DefaultParentIDHeader:                  "2",
+			DefaultBaggageHeaderPrefix + "session": "abc",
+		})
+		require.NoError(t, err)
+		assert.Empty(t, baggageOf(ctx))
+	})
+
+	t.Run("inject", func(t *testing.T) {
+		tracer := newTracer(WithBaggageAllowedKeys("user.tier"))
+		defer tracer.Stop()
+
+		// baggage set locally is propagated
+		root := tracer.StartSpan("web.request").(*span)
+		root.SetBaggageItem("session", "abc")
+		carrier := TextMapCarrier{}
+		require.NoError(t, tracer.Inject(root.Context(), carrier))
+		assert.Equal(t, "session=abc", carrier[DefaultBaggageHeader])
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("DD_TRACE_BAGGAGE_ALLOWED_KEYS", "user.tier, region,")
+		c := newConfig()
+		assert.Equal(t, []string{"user.tier", "region"}, c.baggageAllowedKeys)
+		assert.Equal(t, []string{"user.tier", "region"}, c.propagator.(*chainedPropagator).cfg.BaggageAllowedKeys)
+	})
+}
+
+func TestBaggageTags(t *testing.T) {
+	t.Run("local-root", func(t *testing.T) {
+		tracer, _, _, stop := startTestTracer(t, WithBaggageTagKeys("user.tier", "missing"))
+		defer stop()
+
+		ctx, err := tracer.Extract(TextMapCarrier{
+			DefaultTraceIDHeader:  "1",
+			DefaultParentIDHeader: "2",
+			DefaultBaggageHeader:  "user.tier=gold,session=abc",
+		})
+		require.NoError(t, err)
+		root := tracer.StartSpan("web.request", ChildOf(ctx)).(*span)
+		child := tracer.StartSpan("db.query", ChildOf(root.Context())).(*span)
+		child.Finish()
+		root.Finish()
+
+		assert.Equal(t, "gold", root.Meta["baggage.user.tier"])
+		assert.NotContains(t, root.Meta, "baggage.session")
+		assert.NotContains(t, root.Meta, "baggage.missing")
+		assert.NotContains(t, child.Meta, "baggage.user.tier")
+	})
+
+	t.Run("all-spans", func(t *testing.T) {
+		tracer, _, _, stop := startTestTracer(t, WithBaggageTagKeys("*"), WithBaggageTagsOnAllSpans())
+		defer stop()
+
+		root := tracer.StartSpan("web.request").(*span)
+		root.SetBaggageItem("user.tier", "gold")
+		child := tracer.StartSpan("db.query", ChildOf(root.Context())).(*span)
+		child.SetBaggageItem("session", "abc")
+		child.Finish()
+		root.Finish()
+
+		assert.Equal(t, "gold", root.Meta["baggage.user.tier"])
+		assert.NotContains(t, root.Meta, "baggage.session")
+		assert.Equal(t, "gold", child.Meta["baggage.user.tier"])
+		assert.Equal(t, "abc", child.Meta["baggage.session"])
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		tracer, _, _, stop := startTestTracer(t)
+		defer stop()
+
+		root := tracer.StartSpan("web.request").(*span)
+		root.SetBaggageItem("user.tier", "gold")
+		root.Finish()
+		assert.NotContains(t, root.Meta, "baggage.user.tier")
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("DD_TRACE_BAGGAGE_TAG_KEYS", "user.tier,region")
+		t.Setenv("DD_TRACE_BAGGAGE_TAG_ALL_SPANS", "true")
+		c := newConfig()
+		assert.Equal(t, []string{"user.tier", "region"}, c.baggageTagKeys)
+		assert.True(t, c.baggageTagsOnAllSpans)
+	})
+}
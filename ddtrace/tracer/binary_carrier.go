@@ -0,0 +1,261 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"encoding/binary"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
+	globalinternal "gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/samplernames"
+)
+
+// BinaryContextKey is the key of the message header, or gRPC metadata, holding
+// a span context encoded by InjectBinary. The "-bin" suffix marks it as binary
+// gRPC metadata.
+const BinaryContextKey = "x-datadog-context-bin"
+
+const (
+	// binaryContextVersion is the version of the layout written by InjectBinary.
+	binaryContextVersion byte = 1
+
+	// binaryContextHeaderLen is the length of the fixed part of the layout:
+	// version, flags, 128-bit trace ID and span ID.
+	binaryContextHeaderLen = 2 + 16 + 8
+)
+
+// Flags of the binary layout, telling which optional fields are present.
+const (
+	binaryFlagPriority byte = 1 << iota
+	binaryFlagOrigin
+)
+
+// InjectBinary encodes ctx in a compact binary form, suitable for the binary
+// headers of messaging systems or gRPC metadata, under BinaryContextKey. It holds
+// the 128-bit trace ID, the span ID, the sampling priority, the origin and the
+// propagated trace tags of ctx, and is decoded by ExtractBinary. It returns
+// ErrInvalidSpanContext if ctx wasn't created by this tracer. If the tracer is
+// disabled, it returns a nil slice.
+//
+// Version 1 of the layout is, with integers in big-endian order:
+//
+//	version     1 byte (1)
+//	flags       1 byte (0x1: priority is present, 0x2: origin is present)
+//	trace ID    16 bytes
+//	span ID     8 bytes
+//	priority    1 byte, signed, if present
+//	origin      uvarint length followed by the origin, if present
+//	tags        uvarint count followed by, for each tag, the uvarint length of
+//	            its key, the key, the uvarint length of its value and the value
+func InjectBinary(ctx ddtrace.SpanContext) ([]byte, error) {
+	if t, ok := internal.GetGlobalTracer().(*tracer); ok {
+		if !t.config.enabled.current {
+			return nil, nil
+		}
+		if t.config.tracingAsTransport {
+			// in tracing as transport mode, only propagate when there is an upstream appsec event
+			if ctx, ok := ctx.(*spanContext); ok && ctx.trace != nil &&
+				!globalinternal.VerifyTraceSourceEnabled(ctx.trace.propagatingTag(keyPropagatedTraceSource), globalinternal.ASMTraceSource) {
+				return nil, nil
+			}
+		}
+		t.updateSampling(ctx)
+	}
+	sctx, ok := ctx.(*spanContext)
+	if !ok || sctx.traceID.Empty() || sctx.spanID == 0 {
+		return nil, ErrInvalidSpanContext
+	}
+	return marshalBinarySpanContext(sctx), nil
+}
+
+// ExtractBinary decodes a span context encoded by InjectBinary. It returns
+// ErrSpanContextCorrupted if data isn't a valid encoding, and ErrSpanContextNotFound
+// if data is empty or doesn't hold a trace ID and a span ID.
+func ExtractBinary(data []byte) (ddtrace.SpanContext, error) {
+	if t, ok := internal.GetGlobalTracer().(*tracer); ok && !t.config.enabled.current {
+		return internal.NoopSpanContext{}, nil
+	}
+	if len(data) == 0 {
+		return nil, ErrSpanContextNotFound
+	}
+	ctx, err := unmarshalBinarySpanContext(data)
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := internal.GetGlobalTracer().(*tracer); ok && t.config.tracingAsTransport {
+		// in tracing as transport mode, reset upstream sampling decision to make sure we keep 1 trace/minute
+		if ctx.trace != nil &&
+			!globalinternal.VerifyTraceSourceEnabled(ctx.trace.propagatingTag(keyPropagatedTraceSource), globalinternal.ASMTraceSource) {
+			ctx.trace.priority = nil
+		}
+	}
+	return ctx, nil
+}
+
+// marshalBinarySpanContext encodes ctx in version 1 of the binary layout. Like
+// with the Datadog headers, W3C tags aren't propagated, and invalid tags are
+// dropped. The upper 64 bits of the trace ID aren't propagated as a tag, since
+// the layout holds the whole trace ID.
+func marshalBinarySpanContext(ctx *spanContext) []byte {
+	var (
+		flags    byte
+		priority int
+		origin   = ctx.origin
+		tags     []string // key, value pairs
+		tagsLen  int
+	)
+	if p, ok := ctx.SamplingPriority(); ok {
+		flags |= binaryFlagPriority
+		priority = p
+	}
+	if origin != "" {
+		flags |= binaryFlagOrigin
+	}
+	if ctx.trace != nil {
+		var properr string
+		ctx.trace.iteratePropagatingTags(func(k, v string) bool {
+			if k == tracestateHeader || k == traceparentHeader || k == keyTraceID128 {
+				return true
+			}
+			if err := isValidPropagatableTag(k, v); err != nil {
+				log.Warn("Won't propagate tag '%s': %v", k, err.Error())
+				properr = "encoding_error"
+				return true
+			}
+			if tagsLen += len(k) + len(v); tagsLen > maxPropagatedTagsLength {
+				log.Warn("Won't propagate tags: length exceeds the maximum len of (%d).", maxPropagatedTagsLength)
+				properr = "inject_max_size"
+				tags = nil
+				return false
+			}
+			tags = append(tags, k, v)
+			return true
+		})
+		if properr != "" {
+			ctx.trace.setTag(keyPropagationError, properr)
+		}
+	}
+
+	size := binaryContextHeaderLen + 1 + binary.MaxVarintLen64*(len(tags)+2) + len(origin)
+	for _, s := range tags {
+		size += len(s)
+	}
+	b := make([]byte, binaryContextHeaderLen, size)
+	b[0] = binaryContextVersion
+	b[1] = flags
+	copy(b[2:18], ctx.traceID[:])
+	binary.BigEndian.PutUint64(b[18:26], ctx.spanID)
+	if flags&binaryFlagPriority != 0 {
+		b = append(b, byte(int8(priority)))
+	}
+	if flags&binaryFlagOrigin != 0 {
+		b = binary.AppendUvarint(b, uint64(len(origin)))
+		b = append(b, origin...)
+	}
+	b = binary.AppendUvarint(b, uint64(len(tags)/2))
+	for _, s := range tags {
+		b = binary.AppendUvarint(b, uint64(len(s)))
+		b = append(b, s...)
+	}
+	return b
+}
+
+// unmarshalBinarySpanContext decodes a span context encoded by marshalBinarySpanContext.
+// Layouts of versions newer than binaryContextVersion are decoded as far as this
+// version goes, fields appended by newer versions being ignored.
+func unmarshalBinarySpanContext(data []byte) (*spanContext, error) {
+	if len(data) < binaryContextHeaderLen || data[0] == 0 {
+		return nil, ErrSpanContextCorrupted
+	}
+	var ctx spanContext
+	flags := data[1]
+	copy(ctx.traceID[:], data[2:18])
+	ctx.spanID = binary.BigEndian.Uint64(data[18:26])
+	r := binaryReader(data[binaryContextHeaderLen:])
+	if flags&binaryFlagPriority != 0 {
+		p, ok := r.byte()
+		if !ok {
+			return nil, ErrSpanContextCorrupted
+		}
+		ctx.setSamplingPriority(int(int8(p)), samplernames.Unknown)
+	}
+	if flags&binaryFlagOrigin != 0 {
+		origin, ok := r.string()
+		if !ok {
+			return nil, ErrSpanContextCorrupted
+		}
+		ctx.origin = origin
+	}
+	n, ok := r.uvarint()
+	if !ok || n > uint64(len(r)) {
+		return nil, ErrSpanContextCorrupted
+	}
+	var (
+		tags    = make(map[string]string, n)
+		tagsLen int
+	)
+	for i := uint64(0); i < n; i++ {
+		k, ok1 := r.string()
+		v, ok2 := r.string()
+		if !ok1 || !ok2 {
+			return nil, ErrSpanContextCorrupted
+		}
+		tags[k] = v
+		tagsLen += len(k) + len(v)
+	}
+	delete(tags, keyTraceID128)
+	if len(tags) > 0 {
+		if ctx.trace == nil {
+			ctx.trace = newTrace()
+		}
+		if tagsLen > propagationExtractMaxSize {
+			log.Warn("Did not extract %s, size limit exceeded: %d. Incoming tags will not be propagated further.", BinaryContextKey, propagationExtractMaxSize)
+			ctx.trace.setTag(keyPropagationError, "extract_max_size")
+		} else {
+			ctx.trace.replacePropagatingTags(tags)
+		}
+	}
+	if ctx.traceID.HasUpper() {
+		setPropagatingTag(&ctx, keyTraceID128, ctx.traceID.UpperHex())
+	}
+	if ctx.traceID.Empty() || (ctx.spanID == 0 && ctx.origin != "synthetics") {
+		return nil, ErrSpanContextNotFound
+	}
+	return &ctx, nil
+}
+
+// binaryReader reads the fields of the binary layout.
+type binaryReader []byte
+
+func (r *binaryReader) byte() (byte, bool) {
+	if len(*r) == 0 {
+		return 0, false
+	}
+	b := (*r)[0]
+	*r = (*r)[1:]
+	return b, true
+}
+
+func (r *binaryReader) uvarint() (uint64, bool) {
+	v, n := binary.Uvarint(*r)
+	if n <= 0 {
+		return 0, false
+	}
+	*r = (*r)[n:]
+	return v, true
+}
+
+func (r *binaryReader) string() (string, bool) {
+	n, ok := r.uvarint()
+	if !ok || n > uint64(len(*r)) {
+		return "", false
+	}
+	s := string((*r)[:n])
+	*r = (*r)[n:]
+	return s, true
+}
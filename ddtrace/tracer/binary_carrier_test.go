@@ -0,0 +1,263 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
+)
+
+func TestBinarySpanContext(t *testing.T) {
+	t.Run("round-trip", func(t *testing.T) {
+		tracer, _, _, stop := startTestTracer(t)
+		defer stop()
+		assert := assert.New(t)
+
+		root := tracer.StartSpan("web.request", WithSpanID(1234)).(*span)
+		root.SetTag(ext.ManualKeep, true)
+		root.context.origin = "synthetics"
+		root.context.trace.setPropagatingTag("_dd.p.usr.id", "baz64==")
+		root.context.trace.setPropagatingTag(tracestateHeader, "dd=s:2")
+		child := tracer.StartSpan("db.query", ChildOf(root.Context())).(*span)
+
+		data, err := InjectBinary(child.Context())
+		assert.NoError(err)
+		assert.Equal(binaryContextVersion, data[0])
+
+		ctx, err := ExtractBinary(data)
+		require.NoError(t, err)
+		sctx, ok := ctx.(*spanContext)
+		require.True(t, ok)
+		assert.Equal(root.context.traceID, sctx.traceID)
+		assert.Equal(child.SpanID, sctx.SpanID())
+		p, ok := sctx.SamplingPriority()
+		assert.True(ok)
+		assert.Equal(ext.PriorityUserKeep, p)
+		assert.Equal("synthetics", sctx.origin)
+		assert.Equal("baz64==", sctx.trace.propagatingTag("_dd.p.usr.id"))
+		assert.Equal("-4", sctx.trace.propagatingTag(keyDecisionMaker))
+		assert.Equal(root.context.traceID.UpperHex(), sctx.trace.propagatingTag(keyTraceID128))
+		assert.False(sctx.trace.hasPropagatingTag(tracestateHeader))
+
+		// the upper bits of the trace ID are part of the layout, not of the tags
+		assert.False(bytes.Contains(data, []byte(keyTraceID128)))
+	})
+
+	t.Run("minimal", func(t *testing.T) {
+		var ctx spanContext
+		ctx.traceID.SetLower(1)
+		ctx.spanID = 2
+		data := marshalBinarySpanContext(&ctx)
+		assert.Equal(t, []byte{
+			1, 0,
+			0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1,
+			0, 0, 0, 0, 0, 0, 0, 2,
+			0,
+		}, data)
+
+		extracted, err := unmarshalBinarySpanContext(data)
+		assert.NoError(t, err)
+		assert.Equal(t, ctx.traceID, extracted.traceID)
+		assert.Equal(t, ctx.spanID, extracted.spanID)
+		_, ok := extracted.SamplingPriority()
+		assert.False(t, ok)
+		assert.Nil(t, extracted.trace)
+	})
+
+	t.Run("newer-version", func(t *testing.T) {
+		var ctx spanContext
+		ctx.traceID.SetLower(1)
+		ctx.spanID = 2
+		data := marshalBinarySpanContext(&ctx)
+		data[0] = 2
+		data = append(data, 0xde, 0xad)
+		extracted, err := unmarshalBinarySpanContext(data)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(2), extracted.spanID)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		valid := func() []byte {
+			var ctx spanContext
+			ctx.traceID.SetLower(1)
+			ctx.spanID = 2
+			ctx.origin = "rum"
+			ctx.setSamplingPriority(1, 0)
+			ctx.trace.setPropagatingTag("_dd.p.dm", "-4")
+			return marshalBinarySpanContext(&ctx)
+		}
+		for name, tc := range map[string]struct {
+			data []byte
+			err  error
+		}{
+			"empty":         {nil, ErrSpanContextNotFound},
+			"short":         {valid()[:binaryContextHeaderLen-1], ErrSpanContextCorrupted},
+			"version":       {append([]byte{0}, valid()[1:]...), ErrSpanContextCorrupted},
+			"truncated":     {valid()[:len(valid())-1], ErrSpanContextCorrupted},
+			"no-tags-count": {valid()[:binaryContextHeaderLen+5], ErrSpanContextCorrupted},
+			"no-trace-id":   {append(append([]byte{1, 0}, make([]byte, 16)...), valid()[18:]...), ErrSpanContextNotFound},
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := ExtractBinary(tc.data)
+				assert.Equal(t, tc.err, err)
+			})
+		}
+	})
+
+	t.Run("extract-max-size", func(t *testing.T) {
+		var ctx spanContext
+		ctx.traceID.SetLower(1)
+		ctx.spanID = 2
+		ctx.trace = newTrace()
+		ctx.trace.propagatingTags = map[string]string{
+			"_dd.p.a": string(make([]byte, 300)),
+			"_dd.p.b": string(make([]byte, 300)),
+		}
+		// bypass the injection limits
+		data := marshalBinarySpanContext(&spanContext{traceID: ctx.traceID, spanID: ctx.spanID})
+		data = data[:len(data)-1]
+		data = append(data, 2)
+		for k, v := range ctx.trace.propagatingTags {
+			data = append(data, byte(len(k)))
+			data = append(data, k...)
+			data = append(data, 0xac, 0x02) // 300
+			data = append(data, v...)
+		}
+		extracted, err := unmarshalBinarySpanContext(data)
+		assert.NoError(t, err)
+		assert.Equal(t, "extract_max_size", extracted.trace.tags[keyPropagationError])
+		assert.Empty(t, extracted.trace.propagatingTags)
+	})
+
+	t.Run("inject-max-size", func(t *testing.T) {
+		var ctx spanContext
+		ctx.traceID.SetLower(1)
+		ctx.spanID = 2
+		ctx.trace = newTrace()
+		ctx.trace.setPropagatingTag("_dd.p.a", string(make([]byte, 300)))
+		ctx.trace.setPropagatingTag("_dd.p.b", "valid")
+		ctx.trace.setPropagatingTag("_dd.p.c", "invalid,value")
+		data := marshalBinarySpanContext(&ctx)
+		assert.Equal(t, "encoding_error", ctx.trace.tags[keyPropagationError])
+		extracted, err := unmarshalBinarySpanContext(data)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"_dd.p.b": "valid"}, extracted.trace.propagatingTags)
+	})
+
+	t.Run("mocktracer", func(t *testing.T) {
+		_, err := InjectBinary(internal.NoopSpanContext{})
+		assert.Equal(t, ErrInvalidSpanContext, err)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Setenv("DD_TRACE_ENABLED", "false")
+		tracer, _, _, stop := startTestTracer(t)
+		defer stop()
+
+		data, err := InjectBinary(tracer.StartSpan("web.request").Context())
+		assert.NoError(t, err)
+		assert.Nil(t, data)
+		ctx, err := ExtractBinary([]byte{1})
+		assert.NoError(t, err)
+		assert.Equal(t, internal.NoopSpanContext{}, ctx)
+	})
+}
+
+// FuzzBinarySpanContext checks that a span context round-trips through the
+// binary layout the same way it does through the Datadog headers.
+func FuzzBinarySpanContext(f *testing.F) {
+	f.Add(uint64(0), uint64(1), uint64(2), int8(1), "", "_dd.p.dm", "-4")
+	f.Add(uint64(0x6567a3e600000000), uint64(0xbd862e3fe1be46a9), uint64(0x53995c3f42cd8ad8), int8(-1), "synthetics", "_dd.p.usr.id", "baz64==")
+	f.Add(uint64(1), uint64(1), uint64(0), int8(2), "synthetics", "key", "value")
+	f.Fuzz(func(t *testing.T, upper, lower, spanID uint64, priority int8, origin, key, val string) {
+		if key == keyTraceID128 {
+			t.Skip("the 128-bit trace ID tag is derived from the trace ID")
+		}
+		if key == keyDecisionMaker && priority <= 0 {
+			t.Skip("the decision maker of rejected traces depends on the order of the headers")
+		}
+		newCtx := func() *spanContext {
+			ctx := &spanContext{spanID: spanID, origin: origin}
+			ctx.traceID.SetUpper(upper)
+			ctx.traceID.SetLower(lower)
+			ctx.setSamplingPriority(int(priority), 0)
+			ctx.trace.setPropagatingTag(key, val)
+			return ctx
+		}
+		if ctx := newCtx(); ctx.traceID.Empty() || ctx.spanID == 0 {
+			t.Skip("not injectable")
+		}
+
+		p := &propagator{&PropagatorConfig{
+			BaggagePrefix:    DefaultBaggageHeaderPrefix,
+			TraceHeader:      DefaultTraceIDHeader,
+			ParentHeader:     DefaultParentIDHeader,
+			PriorityHeader:   DefaultPriorityHeader,
+			MaxTagsHeaderLen: maxPropagatedTagsLength,
+		}}
+		textCtx := newCtx()
+		carrier := TextMapCarrier{}
+		if err := p.injectTextMap(textCtx, carrier); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := textCtx.trace.tags[keyPropagationError]; ok {
+			t.Skip("tags can't be propagated")
+		}
+		want, textErr := p.extractTextMap(carrier)
+
+		binCtx := newCtx()
+		got, binErr := unmarshalBinarySpanContext(marshalBinarySpanContext(binCtx))
+		if _, ok := binCtx.trace.tags[keyPropagationError]; ok {
+			t.Fatalf("binary injection failed: %v", binCtx.trace.tags[keyPropagationError])
+		}
+		if textErr != nil {
+			if binErr != textErr {
+				t.Fatalf("expected error %v, got %v", textErr, binErr)
+			}
+			return
+		}
+		if binErr != nil {
+			t.Fatalf("unexpected error: %v", binErr)
+		}
+		wantCtx := want.(*spanContext)
+		if _, ok := wantCtx.trace.tags[keyPropagationError]; ok {
+			t.Skip("tags can't be extracted")
+		}
+		assert.Equal(t, wantCtx.traceID, got.traceID)
+		assert.Equal(t, wantCtx.spanID, got.spanID)
+		assert.Equal(t, wantCtx.origin, got.origin)
+		wantP, _ := wantCtx.SamplingPriority()
+		gotP, _ := got.SamplingPriority()
+		assert.Equal(t, wantP, gotP)
+		assert.Equal(t, wantCtx.trace.propagatingTags, got.trace.propagatingTags)
+	})
+}
+
+func FuzzExtractBinary(f *testing.F) {
+	var ctx spanContext
+	ctx.traceID.SetLower(1)
+	ctx.spanID = 2
+	ctx.origin = "rum"
+	ctx.setSamplingPriority(1, 0)
+	ctx.trace.setPropagatingTag("_dd.p.dm", "-4")
+	f.Add(marshalBinarySpanContext(&ctx))
+	f.Add([]byte{1, 3})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ctx, err := unmarshalBinarySpanContext(data)
+		if err != nil {
+			return
+		}
+		if _, err := unmarshalBinarySpanContext(marshalBinarySpanContext(ctx)); err != nil {
+			t.Fatalf("can't decode re-encoded context: %v", err)
+		}
+	})
+}
@@ -67,6 +67,22 @@ func (dc *dynamicConfig[T]) reset() bool {
 	return dc.apply(dc.startup)
 }
 
+// updateStartup replaces the startup configuration value, when it is reloaded from its
+// local source. The current value is left untouched if it was set by remote config,
+// which keeps precedence until it is reset to the new startup value.
+// Returns whether the current configuration value has been updated or not.
+func (dc *dynamicConfig[T]) updateStartup(val T, origin telemetry.Origin) bool {
+	dc.Lock()
+	defer dc.Unlock()
+	dc.startup = val
+	if dc.cfgOrigin == telemetry.OriginRemoteConfig || dc.equal(dc.current, val) {
+		return false
+	}
+	dc.current = val
+	dc.cfgOrigin = origin
+	return dc.apply(val)
+}
+
 // handleRC processes a new configuration value from remote config
 // Returns whether the configuration value has been updated or not
 func (dc *dynamicConfig[T]) handleRC(val *T) bool {
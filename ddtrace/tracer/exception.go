@@ -0,0 +1,218 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/stacktrace"
+)
+
+const (
+	// defaultMaxExceptionEvents is the default maximum number of exception
+	// events recorded on the spans of a trace.
+	defaultMaxExceptionEvents = 100
+
+	// maxExceptionChainLength is the maximum number of errors of a chain
+	// recorded by a single call to RecordException.
+	maxExceptionChainLength = 32
+
+	// exceptionEventName is the name of the span events recording exceptions.
+	exceptionEventName = "exception"
+
+	// keyExceptionEventsDropped holds the number of exception events which were
+	// not recorded on a span, because the limit of its trace was reached.
+	keyExceptionEventsDropped = "_dd.exception_events_dropped"
+)
+
+// Attributes of the exception events.
+const (
+	exceptionType        = "exception.type"
+	exceptionMessage     = "exception.message"
+	exceptionStacktrace  = "exception.stacktrace"
+	exceptionChainIndex  = "exception.chain.index"
+	exceptionChainParent = "exception.chain.parent"
+)
+
+// ExceptionOption is a configuration option for RecordException. It is aliased
+// in order to help godoc group all the functions returning it together.
+type ExceptionOption = ddtrace.ExceptionOption
+
+// ExceptionTime sets the time at which the exception occurred. It defaults to
+// the time RecordException is called.
+func ExceptionTime(t time.Time) ExceptionOption {
+	return func(cfg *ddtrace.ExceptionConfig) {
+		cfg.Time = t
+	}
+}
+
+// ExceptionAttributes sets additional attributes on the event recording the
+// outermost error.
+func ExceptionAttributes(attrs map[string]interface{}) ExceptionOption {
+	return func(cfg *ddtrace.ExceptionConfig) {
+		cfg.Attributes = attrs
+	}
+}
+
+// ExceptionNoStack prevents the stack trace of the call to RecordException
+// from being recorded.
+func ExceptionNoStack() ExceptionOption {
+	return func(cfg *ddtrace.ExceptionConfig) {
+		cfg.NoStack = true
+	}
+}
+
+// ExceptionSkipStackFrames skips the first n frames of the recorded stack trace,
+// such as those of helpers wrapping RecordException. Frames of this library are
+// always skipped.
+func ExceptionSkipStackFrames(n uint) ExceptionOption {
+	return func(cfg *ddtrace.ExceptionConfig) {
+		cfg.SkipStackFrames = n
+	}
+}
+
+// RecordException records err as span events named "exception", one for each
+// error of its chain: the errors it wraps, as returned by an Unwrap() error or
+// Unwrap() []error method, are recorded after it, recursively. Each event holds
+// the type and message of its error, in the exception.type and exception.message
+// attributes, along with its position in the chain: exception.chain.index, and
+// exception.chain.parent, the index of the error wrapping it (-1 for err). The
+// event of err also holds the stack trace of the call in exception.stacktrace.
+//
+// Recording an exception doesn't mark the span as errored. The number of
+// exception events recorded on the spans of a trace is limited, 100 by default,
+// which can be changed with the DD_TRACE_MAX_EXCEPTION_EVENTS environment variable.
+// The stack trace isn't recorded when debug stacks are disabled with WithDebugStack.
+func (s *span) RecordException(err error, opts ...ExceptionOption) {
+	if err == nil {
+		return
+	}
+	var cfg ddtrace.ExceptionConfig
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+	if cfg.Time.IsZero() {
+		cfg.Time = time.Now()
+	}
+	s.RLock()
+	noStack := cfg.NoStack || s.noDebugStack
+	s.RUnlock()
+	chain := unwrapErrorChain(err)
+	events := make([]spanEvent, 0, len(chain))
+	for i, e := range chain {
+		attrs := make(map[string]interface{}, len(cfg.Attributes)+5)
+		if i == 0 {
+			for k, v := range cfg.Attributes {
+				attrs[k] = v
+			}
+			if !noStack {
+				attrs[exceptionStacktrace] = captureStackTrace(cfg.SkipStackFrames)
+			}
+		}
+		attrs[exceptionType] = reflect.TypeOf(e.err).String()
+		attrs[exceptionMessage] = e.err.Error()
+		attrs[exceptionChainIndex] = i
+		attrs[exceptionChainParent] = e.parent
+		events = append(events, newSpanEvent(exceptionEventName, cfg.Time, attrs))
+	}
+
+	limit := defaultMaxExceptionEvents
+	if tr, ok := internal.GetGlobalTracer().(*tracer); ok {
+		limit = tr.config.maxExceptionEvents
+	}
+	s.Lock()
+	defer s.Unlock()
+	if s.finished {
+		// don't use up the limit of the trace
+		return
+	}
+	n := s.context.trace.reserveExceptionEvents(len(events), limit)
+	s.SpanEvents = append(s.SpanEvents, events[:n]...)
+	if dropped := len(events) - n; dropped > 0 {
+		s.setMetric(keyExceptionEventsDropped, s.Metrics[keyExceptionEventsDropped]+float64(dropped))
+	}
+}
+
+// reserveExceptionEvents reserves up to n exception events out of the limit
+// of the trace, returning the number of events which can be recorded.
+func (t *trace) reserveExceptionEvents(n, limit int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if left := limit - t.exceptionEvents; n > left {
+		n = left
+	}
+	if n < 0 {
+		n = 0
+	}
+	t.exceptionEvents += n
+	return n
+}
+
+// chainedError is an error of a chain, with the index of the error wrapping it.
+type chainedError struct {
+	err    error
+	parent int
+}
+
+// unwrapErrorChain returns err followed by all the errors it wraps, depth-first,
+// up to maxExceptionChainLength errors.
+func unwrapErrorChain(err error) []chainedError {
+	chain := []chainedError{{err: err, parent: -1}}
+	var walk func(i int)
+	walk = func(i int) {
+		var wrapped []error
+		switch e := chain[i].err.(type) {
+		case interface{ Unwrap() error }:
+			wrapped = []error{e.Unwrap()}
+		case interface{ Unwrap() []error }:
+			wrapped = e.Unwrap()
+		}
+		for _, w := range wrapped {
+			if w == nil {
+				continue
+			}
+			if len(chain) >= maxExceptionChainLength {
+				return
+			}
+			chain = append(chain, chainedError{err: w, parent: i})
+			walk(len(chain) - 1)
+		}
+	}
+	walk(0)
+	return chain
+}
+
+// captureStackTrace returns the current stack trace, skipping the frames of this
+// library and the skip following ones, in the format of Go panics.
+func captureStackTrace(skip uint) string {
+	// skip runtime.Callers
+	st := stacktrace.SkipAndCapture(1)
+	if int(skip) >= len(st) {
+		return ""
+	}
+	st = st[skip:]
+	var b strings.Builder
+	for i, f := range st {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fn := f.Function
+		if f.Namespace != "" {
+			if f.ClassName != "" {
+				fn = fmt.Sprintf("%s.(%s).%s", f.Namespace, f.ClassName, f.Function)
+			} else {
+				fn = f.Namespace + "." + f.Function
+			}
+		}
+		fmt.Fprintf(&b, "%s\n\t%s:%d", fn, f.File, f.Line)
+	}
+	return b.String()
+}
@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordException(t *testing.T) {
+	t.Run("chain", func(t *testing.T) {
+		assert := assert.New(t)
+		tracer, _, _, stop := startTestTracer(t)
+		defer stop()
+
+		ts := time.Unix(1700000000, 0)
+		joined := errors.Join(io.EOF, &fs.PathError{Op: "open", Path: "/tmp", Err: fs.ErrNotExist})
+		err := fmt.Errorf("request failed: %w", joined)
+		sp := tracer.StartSpan("span").(*span)
+		sp.RecordException(err, ExceptionTime(ts), ExceptionAttributes(map[string]interface{}{"retry": 2}))
+		defer sp.Finish()
+
+		type entry struct {
+			typ, msg      string
+			index, parent int64
+		}
+		var got []entry
+		for _, e := range sp.SpanEvents {
+			assert.Equal("exception", e.Name)
+			assert.Equal(uint64(ts.UnixNano()), e.TimeUnixNano)
+			got = append(got, entry{
+				typ:    e.Attributes[exceptionType].(string),
+				msg:    e.Attributes[exceptionMessage].(string),
+				index:  e.Attributes[exceptionChainIndex].(int64),
+				parent: e.Attributes[exceptionChainParent].(int64),
+			})
+		}
+		assert.Equal([]entry{
+			{typ: "*fmt.wrapError", msg: err.Error(), index: 0, parent: -1},
+			{typ: "*errors.joinError", msg: joined.Error(), index: 1, parent: 0},
+			{typ: "*errors.errorString", msg: "EOF", index: 2, parent: 1},
+			{typ: "*fs.PathError", msg: "open /tmp: file does not exist", index: 3, parent: 1},
+			{typ: "*errors.errorString", msg: "file does not exist", index: 4, parent: 3},
+		}, got)
+
+		first := sp.SpanEvents[0].Attributes
+		assert.Equal(int64(2), first["retry"])
+		assert.Contains(first[exceptionStacktrace], "testing.tRunner")
+		assert.NotContains(sp.SpanEvents[1].Attributes, exceptionStacktrace)
+		assert.NotContains(sp.SpanEvents[1].Attributes, "retry")
+		assert.Zero(sp.Error)
+	})
+
+	t.Run("no-stack", func(t *testing.T) {
+		sp := newBasicSpan("span")
+		sp.RecordException(errors.New("boom"), ExceptionNoStack())
+		sp.RecordException(nil)
+		require.Len(t, sp.SpanEvents, 1)
+		assert.NotContains(t, sp.SpanEvents[0].Attributes, exceptionStacktrace)
+	})
+
+	t.Run("no-debug-stack", func(t *testing.T) {
+		tracer, _, _, stop := startTestTracer(t, WithDebugStack(false))
+		defer stop()
+
+		sp := tracer.StartSpan("span").(*span)
+		defer sp.Finish()
+		sp.RecordException(errors.New("boom"))
+		require.Len(t, sp.SpanEvents, 1)
+		assert.NotContains(t, sp.SpanEvents[0].Attributes, exceptionStacktrace)
+	})
+
+	t.Run("chain-length", func(t *testing.T) {
+		err := errors.New("root")
+		for i := 0; i < 2*maxExceptionChainLength; i++ {
+			err = fmt.Errorf("wrap %d: %w", i, err)
+		}
+		assert.Len(t, unwrapErrorChain(err), maxExceptionChainLength)
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		assert := assert.New(t)
+		t.Setenv("DD_TRACE_MAX_EXCEPTION_EVENTS", "3")
+		tracer, _, _, stop := startTestTracer(t)
+		defer stop()
+
+		root := tracer.StartSpan("root").(*span)
+		child := tracer.StartSpan("child", ChildOf(root.Context())).(*span)
+		root.RecordException(fmt.Errorf("wrap: %w", io.EOF))
+		child.RecordException(fmt.Errorf("wrap: %w", io.EOF))
+		child.RecordException(io.EOF)
+		assert.Len(root.SpanEvents, 2)
+		assert.Len(child.SpanEvents, 1)
+		assert.Equal(2., child.Metrics[keyExceptionEventsDropped])
+	})
+
+	t.Run("finished", func(t *testing.T) {
+		t.Setenv("DD_TRACE_MAX_EXCEPTION_EVENTS", "1")
+		tracer, _, _, stop := startTestTracer(t)
+		defer stop()
+
+		root := tracer.StartSpan("root").(*span)
+		child := tracer.StartSpan("child", ChildOf(root.Context())).(*span)
+		child.Finish()
+		// a finished span doesn't use up the limit of the trace
+		child.RecordException(io.EOF)
+		root.RecordException(io.EOF)
+		assert.Empty(t, child.SpanEvents)
+		assert.Len(t, root.SpanEvents, 1)
+	})
+}
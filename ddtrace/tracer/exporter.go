@@ -0,0 +1,257 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	globalinternal "gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+const (
+	// exporterQueueSize is the maximum number of trace chunks waiting to be
+	// exported by each SpanExporter.
+	exporterQueueSize = 1000
+
+	// exporterShutdownTimeout is the maximum time given to a SpanExporter to
+	// shut down when the tracer stops.
+	exporterShutdownTimeout = 5 * time.Second
+
+	// exporterStopTimeout is the maximum time the tracer waits for the
+	// SpanExporters to export their queued chunks and shut down when it stops.
+	exporterStopTimeout = 10 * time.Second
+)
+
+// SpanExporter receives the finished traces of the tracer, to send them to a
+// destination other than the Datadog agent. Exporters are registered with
+// WithSpanExporter or WithSpanExporterOnly.
+//
+// Traces are exported in chunks, made of all the spans of a trace or, when
+// partial flushing is enabled, of a part of them. Only the spans which are kept
+// after sampling are exported: the whole chunk when the trace is kept, or the
+// spans kept by single span sampling rules otherwise.
+//
+// Each exporter has its own queue of up to 1000 chunks, which ExportSpans is
+// called with sequentially from a dedicated goroutine. When an exporter doesn't
+// keep up and its queue is full, new chunks are dropped for this exporter only
+// and reported in the datadog.tracer.traces_dropped health metric with the
+// reason:exporter_queue_full tag. Chunks for which ExportSpans returns an error
+// are reported with the reason:exporter_error tag. Exporting never blocks the
+// application nor the other writers.
+//
+// When the tracer stops, it waits up to 10 seconds for the exporters to export
+// their queued chunks and shut down. The chunks which are still queued after
+// that are dropped and reported with the reason:exporter_stop_timeout tag.
+type SpanExporter interface {
+	// ExportSpans exports a chunk of finished spans. The snapshots may be
+	// retained after ExportSpans returns, but are shared with the other
+	// exporters and must not be modified.
+	ExportSpans(ctx context.Context, spans []SpanSnapshot) error
+
+	// Shutdown is called once, when the tracer stops, after all the queued
+	// chunks have been exported. It should flush any buffered data and release
+	// the resources of the exporter before ctx is done.
+	Shutdown(ctx context.Context) error
+}
+
+// SpanSnapshot is a read-only copy of a finished span, as passed to a
+// SpanExporter.
+type SpanSnapshot struct {
+	// Name is the operation name of the span.
+	Name string
+	// Service is the service name of the span.
+	Service string
+	// Resource is the resource name of the span.
+	Resource string
+	// Type is the type of the span, such as "web" or "db".
+	Type string
+	// TraceID is the lower 64 bits of the span's trace id.
+	TraceID uint64
+	// TraceIDHigh is the upper 64 bits of the span's trace id, when 128-bit.
+	TraceIDHigh uint64
+	// SpanID is the identifier of the span.
+	SpanID uint64
+	// ParentID is the identifier of the span's parent, zero for a root span.
+	ParentID uint64
+	// Start is the time at which the span started.
+	Start time.Time
+	// Duration is the duration of the span.
+	Duration time.Duration
+	// Error reports whether the span was marked as errored.
+	Error bool
+	// Meta holds the string tags of the span.
+	Meta map[string]string
+	// Metrics holds the numeric tags of the span.
+	Metrics map[string]float64
+	// Links holds the links of the span to other spans.
+	Links []ddtrace.SpanLink
+	// Events holds the events recorded on the span which were not moved to Meta.
+	Events []ddtrace.SpanEvent
+	// SamplingPriority is the sampling priority of the span's trace.
+	SamplingPriority int
+}
+
+// newSpanSnapshot returns a copy of the finished span s.
+func newSpanSnapshot(s *span) SpanSnapshot {
+	s.RLock()
+	defer s.RUnlock()
+	snap := SpanSnapshot{
+		Name:        s.Name,
+		Service:     s.Service,
+		Resource:    s.Resource,
+		Type:        s.Type,
+		TraceID:     s.TraceID,
+		TraceIDHigh: s.context.traceID.Upper(),
+		SpanID:      s.SpanID,
+		ParentID:    s.ParentID,
+		Start:       time.Unix(0, s.Start),
+		Duration:    time.Duration(s.Duration),
+		Error:       s.Error != 0,
+		Meta:        make(map[string]string, len(s.Meta)),
+		Metrics:     make(map[string]float64, len(s.Metrics)),
+	}
+	for k, v := range s.Meta {
+		snap.Meta[k] = v
+	}
+	for k, v := range s.Metrics {
+		snap.Metrics[k] = v
+	}
+	if len(s.SpanLinks) > 0 {
+		snap.Links = append([]ddtrace.SpanLink(nil), s.SpanLinks...)
+	}
+	for i := range s.SpanEvents {
+		snap.Events = append(snap.Events, s.SpanEvents[i].toPublic())
+	}
+	if p, ok := s.context.SamplingPriority(); ok {
+		snap.SamplingPriority = p
+	}
+	return snap
+}
+
+// exporterTraceWriter is a traceWriter passing the sampled traces to the
+// SpanExporters registered in the configuration.
+type exporterTraceWriter struct {
+	queues   []chan []SpanSnapshot
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+	statsd   globalinternal.StatsdClient
+
+	// abort is closed when stop gave up waiting for the exporters, so that
+	// the chunks still queued are dropped instead of exported.
+	abort chan struct{}
+	// stopTimeout is the maximum time stop waits for the exporters.
+	stopTimeout time.Duration
+}
+
+func newExporterTraceWriter(exporters []SpanExporter, statsdClient globalinternal.StatsdClient) *exporterTraceWriter {
+	h := &exporterTraceWriter{
+		queues:      make([]chan []SpanSnapshot, len(exporters)),
+		statsd:      statsdClient,
+		abort:       make(chan struct{}),
+		stopTimeout: exporterStopTimeout,
+	}
+	for i, e := range exporters {
+		q := make(chan []SpanSnapshot, exporterQueueSize)
+		h.queues[i] = q
+		h.wg.Add(1)
+		go func(e SpanExporter) {
+			defer h.wg.Done()
+			h.run(e, q)
+		}(e)
+	}
+	return h
+}
+
+// run exports the chunks received on q with e, until q is closed.
+func (h *exporterTraceWriter) run(e SpanExporter, q <-chan []SpanSnapshot) {
+	for spans := range q {
+		select {
+		case <-h.abort:
+			h.statsd.Incr("datadog.tracer.traces_dropped", []string{"reason:exporter_stop_timeout"}, 1)
+			continue
+		default:
+		}
+		if err := e.ExportSpans(context.Background(), spans); err != nil {
+			h.statsd.Incr("datadog.tracer.traces_dropped", []string{"reason:exporter_error"}, 1)
+			log.Error("Error exporting spans: %v", err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), exporterShutdownTimeout)
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		log.Error("Error shutting down span exporter: %v", err)
+	}
+}
+
+func (h *exporterTraceWriter) add(trace []*span) {
+	trace = sampledSpans(trace)
+	if len(trace) == 0 {
+		return
+	}
+	spans := make([]SpanSnapshot, len(trace))
+	for i, s := range trace {
+		spans[i] = newSpanSnapshot(s)
+	}
+	for _, q := range h.queues {
+		select {
+		case q <- spans:
+		default:
+			h.statsd.Incr("datadog.tracer.traces_dropped", []string{"reason:exporter_queue_full"}, 1)
+		}
+	}
+}
+
+// flush is a no-op: chunks are passed to the exporters as soon as they are added.
+func (h *exporterTraceWriter) flush() {}
+
+// stop waits for the exporters to export the queued chunks and shut down, for
+// at most h.stopTimeout. The chunks which are still queued after that are
+// dropped, and the exporters which are stuck are left behind.
+func (h *exporterTraceWriter) stop() {
+	h.stopOnce.Do(func() {
+		for _, q := range h.queues {
+			close(q)
+		}
+		done := make(chan struct{})
+		go func() {
+			h.wg.Wait()
+			close(done)
+		}()
+		timer := time.NewTimer(h.stopTimeout)
+		defer timer.Stop()
+		select {
+		case <-done:
+		case <-timer.C:
+			close(h.abort)
+			log.Error("Span exporters did not stop within %s, dropping the queued spans.", h.stopTimeout)
+		}
+	})
+}
+
+// multiTraceWriter passes the traces to several traceWriters.
+type multiTraceWriter []traceWriter
+
+func (w multiTraceWriter) add(trace []*span) {
+	for _, tw := range w {
+		tw.add(trace)
+	}
+}
+
+func (w multiTraceWriter) flush() {
+	for _, tw := range w {
+		tw.flush()
+	}
+}
+
+func (w multiTraceWriter) stop() {
+	for _, tw := range w {
+		tw.stop()
+	}
+}
@@ -0,0 +1,157 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/samplernames"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/statsdtest"
+)
+
+// testExporter records the chunks it exports.
+type testExporter struct {
+	mu       sync.Mutex
+	chunks   [][]SpanSnapshot
+	err      error
+	block    chan struct{}
+	shutdown bool
+}
+
+func (e *testExporter) ExportSpans(_ context.Context, spans []SpanSnapshot) error {
+	if e.block != nil {
+		<-e.block
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.chunks = append(e.chunks, spans)
+	return e.err
+}
+
+func (e *testExporter) Shutdown(_ context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shutdown = true
+	return nil
+}
+
+func TestSpanExporter(t *testing.T) {
+	t.Run("snapshot", func(t *testing.T) {
+		assert := assert.New(t)
+		e := &testExporter{}
+		h := newExporterTraceWriter([]SpanExporter{e}, &statsdtest.TestStatsdClient{})
+		root := newSpan("http.request", "web", "GET /", 1, 2, 0)
+		root.context.traceID.SetUpper(3)
+		root.context.setSamplingPriority(ext.PriorityUserKeep, samplernames.Manual)
+		root.Meta["k"] = "v"
+		root.Metrics["n"] = 1
+		root.Error = 1
+		root.SpanLinks = []ddtrace.SpanLink{{TraceID: 5, SpanID: 6}}
+		h.add([]*span{root})
+		root.Meta["k"] = "changed"
+		h.stop()
+
+		require.Len(t, e.chunks, 1)
+		require.Len(t, e.chunks[0], 1)
+		s := e.chunks[0][0]
+		assert.Equal("http.request", s.Name)
+		assert.Equal("web", s.Service)
+		assert.Equal("GET /", s.Resource)
+		assert.Equal(uint64(2), s.TraceID)
+		assert.Equal(uint64(3), s.TraceIDHigh)
+		assert.Equal(uint64(1), s.SpanID)
+		assert.True(s.Error)
+		assert.Equal("v", s.Meta["k"])
+		assert.Equal(1.0, s.Metrics["n"])
+		assert.Equal([]ddtrace.SpanLink{{TraceID: 5, SpanID: 6}}, s.Links)
+		assert.Equal(ext.PriorityUserKeep, s.SamplingPriority)
+		assert.True(e.shutdown)
+	})
+
+	t.Run("sampling", func(t *testing.T) {
+		e := &testExporter{}
+		h := newExporterTraceWriter([]SpanExporter{e}, &statsdtest.TestStatsdClient{})
+		dropped := makeSpan(0)
+		dropped.context.setSamplingPriority(ext.PriorityAutoReject, samplernames.AgentRate)
+		h.add([]*span{dropped})
+		h.stop()
+		assert.Empty(t, e.chunks)
+	})
+
+	t.Run("queue-full", func(t *testing.T) {
+		var statsd statsdtest.TestStatsdClient
+		e := &testExporter{block: make(chan struct{})}
+		h := newExporterTraceWriter([]SpanExporter{e}, &statsd)
+		// one chunk is being exported, exporterQueueSize are queued
+		for i := 0; i < exporterQueueSize+3; i++ {
+			h.add([]*span{makeSpan(0)})
+		}
+		close(e.block)
+		h.stop()
+
+		assert.GreaterOrEqual(t, len(e.chunks), exporterQueueSize)
+		calls := statsd.GetCallsByName("datadog.tracer.traces_dropped")
+		assert.Equal(t, exporterQueueSize+3-len(e.chunks), len(calls))
+	})
+
+	t.Run("stop-timeout", func(t *testing.T) {
+		var statsd statsdtest.TestStatsdClient
+		e := &testExporter{block: make(chan struct{})}
+		defer close(e.block)
+		h := newExporterTraceWriter([]SpanExporter{e}, &statsd)
+		h.stopTimeout = 10 * time.Millisecond
+		for i := 0; i < 3; i++ {
+			h.add([]*span{makeSpan(0)})
+		}
+		// the exporter is stuck, stop gives up waiting for it
+		h.stop()
+		// once unblocked, the queued chunks are dropped
+		e.block <- struct{}{}
+		assert.Eventually(t, func() bool {
+			return statsd.Counts()["datadog.tracer.traces_dropped"] >= 2
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var statsd statsdtest.TestStatsdClient
+		e := &testExporter{err: errors.New("boom")}
+		h := newExporterTraceWriter([]SpanExporter{e}, &statsd)
+		h.add([]*span{makeSpan(0)})
+		h.stop()
+		assert.Equal(t, int64(1), statsd.Counts()["datadog.tracer.traces_dropped"])
+	})
+
+	t.Run("tracer", func(t *testing.T) {
+		e := &testExporter{}
+		tracer, transport, flush, stop := startTestTracer(t, WithSpanExporter(e))
+		tracer.StartSpan("web.request").Finish()
+		flush(1)
+		stop()
+		assert.Len(t, transport.Traces(), 1)
+		require.Len(t, e.chunks, 1)
+		assert.Equal(t, "web.request", e.chunks[0][0].Name)
+	})
+
+	t.Run("only", func(t *testing.T) {
+		e := &testExporter{}
+		tracer, transport, _, stop := startTestTracer(t, WithSpanExporterOnly(e))
+		_, ok := tracer.traceWriter.(*exporterTraceWriter)
+		assert.True(t, ok)
+		tracer.StartSpan("web.request").Finish()
+		stop()
+		assert.Empty(t, transport.Traces())
+		require.Len(t, e.chunks, 1)
+	})
+}
@@ -58,10 +58,11 @@ type startupInfo struct {
 	PartialFlushMinSpans        int                          `json:"partial_flush_min_spans"`        // The min number of spans to trigger a partial flush
 	Orchestrion                 orchestrionConfig            `json:"orchestrion"`                    // Orchestrion (auto-instrumentation) configuration.
 	FeatureFlags                []string                     `json:"feature_flags"`
-	PropagationStyleInject      string                       `json:"propagation_style_inject"`  // Propagation style for inject
-	PropagationStyleExtract     string                       `json:"propagation_style_extract"` // Propagation style for extract
-	TracingAsTransport          bool                         `json:"tracing_as_transport"`      // Whether the tracer is disabled and other products are using it as a transport
-	DogstatsdAddr               string                       `json:"dogstatsd_address"`         // Destination of statsd payloads
+	PropagationStyleInject      string                       `json:"propagation_style_inject"`       // Propagation style for inject
+	PropagationStyleExtract     string                       `json:"propagation_style_extract"`      // Propagation style for extract
+	TracingAsTransport          bool                         `json:"tracing_as_transport"`           // Whether the tracer is disabled and other products are using it as a transport
+	DogstatsdAddr               string                       `json:"dogstatsd_address"`              // Destination of statsd payloads
+	OTLPTracesEndpoint          string                       `json:"otlp_traces_endpoint,omitempty"` // Destination of OTLP trace exports, if enabled
 }
 
 // checkEndpoint tries to connect to the URL specified by endpoint.
@@ -152,6 +153,7 @@ func logStartup(t *tracer) {
 		PropagationStyleExtract:     extractorNames,
 		TracingAsTransport:          t.config.tracingAsTransport,
 		DogstatsdAddr:               t.config.dogstatsdAddr,
+		OTLPTracesEndpoint:          t.config.otlpTracesURL,
 	}
 	if _, _, err := samplingRulesFromEnv(); err != nil {
 		info.SamplingRulesError = fmt.Sprintf("%s", err)
@@ -159,7 +161,7 @@ func logStartup(t *tracer) {
 	if limit, ok := t.rulesSampling.TraceRateLimit(); ok {
 		info.SampleRateLimit = fmt.Sprintf("%v", limit)
 	}
-	if !t.config.logToStdout {
+	if !t.config.logToStdout && t.config.otlpTracesURL == "" {
 		if err := checkEndpoint(t.config.httpClient, t.config.transport.endpoint()); err != nil {
 			info.AgentError = fmt.Sprintf("%s", err)
 			log.Warn("DIAGNOSTICS Unable to reach agent intake: %s", err)
@@ -88,6 +88,7 @@ var contribIntegrations = map[string]struct {
 	"gopkg.in/olivere/elastic.v3":                   {"Elasticsearch v3", false},
 	"github.com/redis/go-redis/v9":                  {"Redis v9", false},
 	"github.com/redis/rueidis":                      {"Rueidis", false},
+	"github.com/rs/zerolog":                         {"Zerolog", false},
 	"github.com/segmentio/kafka-go":                 {"Kafka v0", false},
 	"github.com/IBM/sarama":                         {"IBM sarama", false},
 	"github.com/Shopify/sarama":                     {"Shopify sarama", false},
@@ -98,6 +99,7 @@ var contribIntegrations = map[string]struct {
 	"github.com/urfave/negroni":                     {"Negroni", false},
 	"github.com/valyala/fasthttp":                   {"FastHTTP", false},
 	"github.com/zenazn/goji":                        {"Goji", false},
+	"go.uber.org/zap":                               {"Zap", false},
 	"log/slog":                                      {"log/slog", false},
 	"github.com/uptrace/bun":                        {"Bun", false},
 	"github.com/valkey-io/valkey-go":                {"Valkey", false},
@@ -116,6 +118,10 @@ var (
 
 	// defaultRateLimit specifies the default trace rate limit used when DD_TRACE_RATE_LIMIT is not set.
 	defaultRateLimit = 100.0
+
+	// defaultAdaptiveMinPerKey specifies the default number of traces per second the adaptive
+	// sampler keeps at least for each service and resource.
+	defaultAdaptiveMinPerKey = 1.0
 )
 
 // config holds the tracer configuration.
@@ -148,6 +154,19 @@ type config struct {
 	// retryInterval is the interval between agent connection retries. It has no effect if sendRetries is not set
 	retryInterval time.Duration
 
+	// spoolDir, when set, is the directory in which trace payloads failing to
+	// be sent to the agent are kept, to be sent again once it becomes reachable.
+	spoolDir string
+
+	// spoolMaxSize is the maximum size in bytes of all the payloads kept in spoolDir.
+	spoolMaxSize int64
+
+	// spoolMaxFiles is the maximum number of payloads kept in spoolDir.
+	spoolMaxFiles int
+
+	// spoolTTL is the duration after which a payload kept in spoolDir is discarded.
+	spoolTTL time.Duration
+
 	// logStartup, when true, causes various startup info to be written
 	// when the tracer starts.
 	logStartup bool
@@ -285,6 +304,31 @@ type config struct {
 	// traceSampleRules holds the trace sampling rules
 	traceSampleRules dynamicConfig[[]SamplingRule]
 
+	// maxExceptionEvents specifies the maximum number of exception events recorded
+	// with RecordException on the spans of a trace.
+	maxExceptionEvents int
+
+	// baggageMaxItems and baggageMaxBytes limit the baggage injected and extracted
+	// by the default propagator.
+	baggageMaxItems, baggageMaxBytes int
+
+	// baggageAllowedKeys lists the baggage keys extracted by the default propagator.
+	// If empty, all keys are extracted.
+	baggageAllowedKeys []string
+
+	// baggageTagKeys lists the baggage items copied into "baggage." prefixed span
+	// tags on the local root span, or on all spans if baggageTagsOnAllSpans is set.
+	// The key "*" copies all baggage items.
+	baggageTagKeys []string
+
+	// baggageTagsOnAllSpans copies the baggage items of baggageTagKeys on all spans
+	// instead of the local root span only.
+	baggageTagsOnAllSpans bool
+
+	// traceRulesFile holds the path of the file the trace sampling rules are reloaded
+	// from when it changes, as set in DD_TRACE_SAMPLING_RULES_FILE.
+	traceRulesFile string
+
 	// headerAsTags holds the header as tags configuration.
 	headerAsTags dynamicConfig[[]string]
 
@@ -301,6 +345,17 @@ type config struct {
 	// ciVisibilityAgentless controls if the tracer is loaded with CI Visibility agentless mode. default false
 	ciVisibilityAgentless bool
 
+	// agentless specifies whether traces and stats are sent directly to the Datadog intake
+	// instead of the agent. Value from DD_TRACE_AGENTLESS_ENABLED, default false.
+	agentless bool
+
+	// agentlessURL is the base URL of the intake traces and stats are sent to in agentless
+	// mode. Value from DD_TRACE_AGENTLESS_URL, there is no default.
+	agentlessURL string
+
+	// apiKey is the API key authenticating payloads in agentless mode. Value from DD_API_KEY.
+	apiKey string
+
 	// logDirectory is directory for tracer logs specified by user-setting DD_TRACE_LOG_DIRECTORY. default empty/unused
 	logDirectory string
 
@@ -309,6 +364,40 @@ type config struct {
 
 	// traceRateLimitPerSecond specifies the rate limit for traces.
 	traceRateLimitPerSecond float64
+
+	// adaptiveSamplingBudget specifies the number of traces per second kept by the
+	// adaptive sampler. Adaptive sampling is disabled when it is zero.
+	adaptiveSamplingBudget float64
+
+	// adaptiveSamplingMinPerKey specifies the number of traces per second the adaptive
+	// sampler keeps at least for each service and resource.
+	adaptiveSamplingMinPerKey float64
+
+	// tailSampling configures the tail sampler. It is nil unless tail sampling
+	// is enabled.
+	tailSampling *TailSamplingConfig
+
+	// spanProcessors are the processors called when spans start and finish.
+	spanProcessors []SpanProcessor
+
+	// spanExporters are the exporters receiving the finished traces.
+	spanExporters []SpanExporter
+
+	// spanExportersOnly reports whether the finished traces are only passed to
+	// spanExporters, instead of also being sent to the agent.
+	spanExportersOnly bool
+
+	// traceProtocol specifies the trace payload format used to send traces to
+	// the agent. Value from DD_TRACE_AGENT_PROTOCOL_VERSION, default 0.4.
+	// It falls back to 0.4 when the agent doesn't support the requested version.
+	traceProtocol float64
+
+	// otlpTracesURL, when set, causes traces to be exported to this URL using
+	// OTLP/HTTP (protobuf) instead of being sent to the Datadog Agent.
+	otlpTracesURL string
+
+	// otlpHeaders holds additional HTTP headers sent along OTLP export requests.
+	otlpHeaders map[string]string
 }
 
 // orchestrionConfig contains Orchestrion configuration.
@@ -371,9 +460,29 @@ func newConfig(opts ...StartOption) *config {
 
 	reportTelemetryOnAppStarted(telemetry.Configuration{Name: "trace_rate_limit", Value: c.traceRateLimitPerSecond, Origin: origin})
 
+	c.maxExceptionEvents = internal.IntEnv("DD_TRACE_MAX_EXCEPTION_EVENTS", defaultMaxExceptionEvents)
+	c.baggageMaxItems = internal.IntEnv("DD_TRACE_BAGGAGE_MAX_ITEMS", baggageMaxItems)
+	c.baggageMaxBytes = internal.IntEnv("DD_TRACE_BAGGAGE_MAX_BYTES", baggageMaxBytes)
+	if v := os.Getenv("DD_TRACE_BAGGAGE_ALLOWED_KEYS"); v != "" {
+		c.baggageAllowedKeys = splitBaggageKeys(v)
+	}
+	if v := os.Getenv("DD_TRACE_BAGGAGE_TAG_KEYS"); v != "" {
+		c.baggageTagKeys = splitBaggageKeys(v)
+	}
+	c.baggageTagsOnAllSpans = internal.BoolEnv("DD_TRACE_BAGGAGE_TAG_ALL_SPANS", false)
+	c.adaptiveSamplingBudget = internal.FloatEnv("DD_TRACE_ADAPTIVE_SAMPLING_BUDGET", 0)
+	c.adaptiveSamplingMinPerKey = internal.FloatEnv("DD_TRACE_ADAPTIVE_SAMPLING_MIN_PER_KEY", defaultAdaptiveMinPerKey)
+
 	if v := os.Getenv("OTEL_LOGS_EXPORTER"); v != "" {
 		log.Warn("OTEL_LOGS_EXPORTER is not supported")
 	}
+	c.agentless = internal.BoolEnv("DD_TRACE_AGENTLESS_ENABLED", false)
+	c.agentlessURL = strings.TrimSuffix(os.Getenv("DD_TRACE_AGENTLESS_URL"), "/")
+	c.apiKey = os.Getenv("DD_API_KEY")
+	if isOTLPExporter(os.Getenv("OTEL_TRACES_EXPORTER")) {
+		c.otlpTracesURL = otlpTracesURLFromEnv()
+	}
+	c.otlpHeaders = otlpHeadersFromEnv()
 	if internal.BoolEnv("DD_TRACE_ANALYTICS_ENABLED", false) {
 		globalconfig.SetAnalyticsRate(1.0)
 	}
@@ -486,7 +595,21 @@ func newConfig(opts ...StartOption) *config {
 	if v := os.Getenv("DD_TRACE_PEER_SERVICE_MAPPING"); v != "" {
 		internal.ForEachStringTag(v, internal.DDTagsDelimiter, func(key, val string) { c.peerServiceMappings[key] = val })
 	}
+	c.traceProtocol = traceProtocolV04
+	if v := os.Getenv("DD_TRACE_AGENT_PROTOCOL_VERSION"); v != "" {
+		switch v {
+		case "0.4":
+		case "0.5":
+			c.traceProtocol = traceProtocolV05
+		default:
+			log.Warn("DD_TRACE_AGENT_PROTOCOL_VERSION=%s is not a supported value, using 0.4", v)
+		}
+	}
 	c.retryInterval = time.Millisecond
+	c.spoolDir = os.Getenv("DD_TRACE_SPOOL_DIR")
+	c.spoolMaxSize = int64(internal.IntEnv("DD_TRACE_SPOOL_MAX_SIZE", defaultSpoolMaxSize))
+	c.spoolMaxFiles = internal.IntEnv("DD_TRACE_SPOOL_MAX_FILES", defaultSpoolMaxFiles)
+	c.spoolTTL = internal.DurationEnv("DD_TRACE_SPOOL_TTL", defaultSpoolTTL)
 	for _, fn := range opts {
 		fn(c)
 	}
@@ -523,6 +646,8 @@ func newConfig(opts ...StartOption) *config {
 			}
 		}
 	}
+	globalconfig.SetEnv(c.env)
+	globalconfig.SetVersion(c.version)
 	if c.serviceName == "" {
 		if v, ok := globalTags["service"]; ok {
 			if s, ok := v.(string); ok {
@@ -535,8 +660,20 @@ func newConfig(opts ...StartOption) *config {
 			c.serviceName = filepath.Base(os.Args[0])
 		}
 	}
+	if c.agentless && c.apiKey == "" {
+		log.Error("Agentless mode is disabled as no API key was provided: set DD_API_KEY or use WithAgentless. Sending traces to the agent.")
+		c.agentless = false
+	}
+	if c.agentless && c.agentlessURL == "" {
+		log.Error("Agentless mode is disabled as no intake URL was provided: set DD_TRACE_AGENTLESS_URL or use WithAgentlessURL. Sending traces to the agent.")
+		c.agentless = false
+	}
 	if c.transport == nil {
-		c.transport = newHTTPTransport(c.agentURL.String(), c.httpClient)
+		if c.agentless {
+			c.transport = newAgentlessHTTPTransport(c.agentlessURL, c.apiKey, c.httpClient)
+		} else {
+			c.transport = newHTTPTransport(c.agentURL.String(), c.httpClient)
+		}
 	}
 	if c.propagator == nil {
 		envKey := "DD_TRACE_X_DATADOG_TAGS_MAX_LENGTH"
@@ -550,7 +687,10 @@ func newConfig(opts ...StartOption) *config {
 			max = maxPropagatedTagsLength
 		}
 		c.propagator = NewPropagator(&PropagatorConfig{
-			MaxTagsHeaderLen: max,
+			MaxTagsHeaderLen:   max,
+			BaggageMaxItems:    c.baggageMaxItems,
+			BaggageMaxBytes:    c.baggageMaxBytes,
+			BaggageAllowedKeys: c.baggageAllowedKeys,
 		})
 	}
 	if c.logger != nil {
@@ -570,9 +710,12 @@ func newConfig(opts ...StartOption) *config {
 		c.ciVisibilityAgentless = ciTransport.agentless
 	}
 
-	// if using stdout or traces are disabled or we are in ci visibility agentless mode, agent is disabled
-	agentDisabled := c.logToStdout || !c.enabled.current || c.ciVisibilityAgentless
+	// if using stdout, exporting OTLP or only to span exporters, sending to the intake, or traces are disabled or we are in ci visibility agentless mode, agent is disabled
+	agentDisabled := c.logToStdout || !c.enabled.current || c.ciVisibilityAgentless || c.agentless || c.otlpTracesURL != "" || c.spanExportersOnly
 	c.agent = loadAgentFeatures(agentDisabled, c.agentURL, c.httpClient)
+	if c.traceProtocol == traceProtocolV05 {
+		c.negotiateTraceProtocol()
+	}
 	info, ok := debug.ReadBuildInfo()
 	if !ok {
 		c.loadContribIntegrations([]*debug.Module{})
@@ -732,6 +875,12 @@ type agentFeatures struct {
 
 	// obfuscationVersion reports the trace-agent's version of obfuscation logic. A value of 0 means this field wasn't present.
 	obfuscationVersion int
+
+	// v05 reports whether the trace-agent accepts v0.5 trace payloads on the /v0.5/traces endpoint.
+	v05 bool
+
+	// spanEventsAvailable reports whether the trace-agent can receive spans with the `span_events` field.
+	spanEventsAvailable bool
 }
 
 // HasFlag reports whether the agent has set the feat feature flag.
@@ -763,6 +912,7 @@ func loadAgentFeatures(agentDisabled bool, agentURL *url.URL, httpClient *http.C
 		FeatureFlags       []string `json:"feature_flags"`
 		PeerTags           []string `json:"peer_tags"`
 		SpanMetaStruct     bool     `json:"span_meta_structs"`
+		SpanEvents         bool     `json:"span_events"`
 		ObfuscationVersion int      `json:"obfuscation_version"`
 		Config             struct {
 			StatsdPort int `json:"statsd_port"`
@@ -778,12 +928,15 @@ func loadAgentFeatures(agentDisabled bool, agentURL *url.URL, httpClient *http.C
 	features.DropP0s = info.ClientDropP0s
 	features.StatsdPort = info.Config.StatsdPort
 	features.metaStructAvailable = info.SpanMetaStruct
+	features.spanEventsAvailable = info.SpanEvents
 	features.peerTags = info.PeerTags
 	features.obfuscationVersion = info.ObfuscationVersion
 	for _, endpoint := range info.Endpoints {
 		switch endpoint {
 		case "/v0.6/stats":
 			features.Stats = true
+		case "/v0.5/traces":
+			features.v05 = true
 		}
 	}
 	features.featureFlags = make(map[string]struct{}, len(info.FeatureFlags))
@@ -793,6 +946,32 @@ func loadAgentFeatures(agentDisabled bool, agentURL *url.URL, httpClient *http.C
 	return features
 }
 
+// negotiateTraceProtocol switches the transport to the v0.5 traces endpoint if
+// the agent supports it, falling back to v0.4 otherwise.
+func (c *config) negotiateTraceProtocol() {
+	t, ok := c.transport.(*httpTransport)
+	if !ok {
+		// custom transports only support the default format
+		c.traceProtocol = traceProtocolV04
+		return
+	}
+	if !c.agent.v05 {
+		log.Warn("The agent does not support the v0.5 trace protocol, falling back to v0.4")
+		c.traceProtocol = traceProtocolV04
+		return
+	}
+	t.traceURL = fmt.Sprintf("%s/v0.5/traces", c.agentURL.String())
+}
+
+// canSendSpanEvents reports whether span events can be sent natively, rather than
+// as JSON in the span meta. The v0.5 payload format has no field for them.
+func (c *config) canSendSpanEvents() bool {
+	if c.otlpTracesURL != "" {
+		return true
+	}
+	return c.agent.spanEventsAvailable && c.traceProtocol != traceProtocolV05
+}
+
 // MarkIntegrationImported labels the given integration as imported
 func MarkIntegrationImported(integration string) bool {
 	s, ok := contribIntegrations[integration]
@@ -826,11 +1005,15 @@ func (c *config) loadContribIntegrations(deps []*debug.Module) {
 }
 
 func (c *config) canComputeStats() bool {
+	if c.agentless {
+		// there is no agent aggregating stats, the tracer has to compute them.
+		return true
+	}
 	return c.agent.Stats && (c.HasFeature("discovery") || c.statsComputationEnabled)
 }
 
 func (c *config) canDropP0s() bool {
-	return c.canComputeStats() && c.agent.DropP0s
+	return c.canComputeStats() && (c.agentless || c.agent.DropP0s)
 }
 
 func statsTags(c *config) []string {
@@ -953,6 +1136,25 @@ func WithSendRetries(retries int) StartOption {
 	}
 }
 
+// WithTraceSpool enables keeping the trace payloads which could not be sent to
+// the agent, once all retries are exhausted, in the directory dir. They are sent
+// again, oldest first, as soon as the agent can be reached. At most maxFiles
+// payloads totalling maxSize bytes are kept, each of them for up to ttl, the oldest
+// being dropped first. Zero or negative limits select the defaults of 100MB,
+// 1000 payloads and 24 hours.
+//
+// The spool can also be enabled with the DD_TRACE_SPOOL_DIR environment variable,
+// with limits set by DD_TRACE_SPOOL_MAX_SIZE, DD_TRACE_SPOOL_MAX_FILES and
+// DD_TRACE_SPOOL_TTL.
+func WithTraceSpool(dir string, maxSize int64, maxFiles int, ttl time.Duration) StartOption {
+	return func(c *config) {
+		c.spoolDir = dir
+		c.spoolMaxSize = maxSize
+		c.spoolMaxFiles = maxFiles
+		c.spoolTTL = ttl
+	}
+}
+
 // WithRetryInterval sets the interval, in seconds, for retrying submitting payloads to the agent.
 func WithRetryInterval(interval int) StartOption {
 	return func(c *config) {
@@ -967,6 +1169,64 @@ func WithPropagator(p Propagator) StartOption {
 	}
 }
 
+// WithBaggageLimits sets the maximum number of baggage items, and the maximum size
+// in bytes of the baggage, injected and extracted by the default propagator. Excess
+// items are dropped and reported through telemetry. Values of 0 or less keep the
+// defaults of 64 items and 8192 bytes.
+// The limits can also be set with the DD_TRACE_BAGGAGE_MAX_ITEMS and
+// DD_TRACE_BAGGAGE_MAX_BYTES environment variables.
+// It has no effect when used with WithPropagator.
+func WithBaggageLimits(maxItems, maxBytes int) StartOption {
+	return func(c *config) {
+		c.baggageMaxItems = maxItems
+		c.baggageMaxBytes = maxBytes
+	}
+}
+
+// WithBaggageAllowedKeys restricts the baggage extracted from incoming requests
+// and messages to the items with the given keys, other items being dropped and
+// reported through telemetry. It doesn't affect the baggage set locally.
+// The keys can also be set with the DD_TRACE_BAGGAGE_ALLOWED_KEYS environment
+// variable, as a comma-separated list.
+// It has no effect when used with WithPropagator.
+func WithBaggageAllowedKeys(keys ...string) StartOption {
+	return func(c *config) {
+		c.baggageAllowedKeys = keys
+	}
+}
+
+// WithBaggageTagKeys copies the baggage items with the given keys into span tags
+// prefixed with "baggage.", such as "baggage.user.tier", when the local root span
+// finishes. The key "*" copies all baggage items. Use WithBaggageTagsOnAllSpans to
+// tag every span instead.
+// The keys can also be set with the DD_TRACE_BAGGAGE_TAG_KEYS environment
+// variable, as a comma-separated list.
+func WithBaggageTagKeys(keys ...string) StartOption {
+	return func(c *config) {
+		c.baggageTagKeys = keys
+	}
+}
+
+// WithBaggageTagsOnAllSpans makes WithBaggageTagKeys tag every span with the
+// baggage items, instead of the local root span only. It can also be enabled
+// with the DD_TRACE_BAGGAGE_TAG_ALL_SPANS environment variable.
+func WithBaggageTagsOnAllSpans() StartOption {
+	return func(c *config) {
+		c.baggageTagsOnAllSpans = true
+	}
+}
+
+// splitBaggageKeys splits the comma-separated list of baggage keys v.
+func splitBaggageKeys(v string) []string {
+	var keys []string
+	for _, k := range strings.Split(v, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
 // WithServiceName is deprecated. Please use WithService.
 // If you are using an older version and you are upgrading from WithServiceName
 // to WithService, please note that WithService will determine the service name of
@@ -1276,6 +1536,115 @@ func WithStatsComputation(enabled bool) StartOption {
 	}
 }
 
+// WithAgentless configures the tracer to send traces directly to an intake
+// instead of the agent, authenticated with apiKey. If apiKey is empty, the value
+// of DD_API_KEY is used. The URL of the intake must be set with WithAgentlessURL
+// or DD_TRACE_AGENTLESS_URL: without it, or without an API key, agentless mode is
+// disabled and traces are sent to the agent. Payloads are in the same formats as
+// for the agent, gzip-compressed and retried as set with WithSendRetries. As there
+// is no agent to aggregate them, stats are computed by the tracer, and the traces
+// dropped by sampling are not sent.
+// This can also be enabled by setting DD_TRACE_AGENTLESS_ENABLED to true.
+func WithAgentless(apiKey string) StartOption {
+	return func(c *config) {
+		c.agentless = true
+		if apiKey != "" {
+			c.apiKey = apiKey
+		}
+	}
+}
+
+// WithAgentlessURL sets the base URL of the intake traces and stats are sent to
+// in agentless mode, at the same paths as to the agent: /v0.4/traces and
+// /v0.6/stats. It is required in agentless mode, and can also be set with
+// DD_TRACE_AGENTLESS_URL.
+func WithAgentlessURL(url string) StartOption {
+	return func(c *config) {
+		c.agentlessURL = strings.TrimSuffix(url, "/")
+	}
+}
+
+// WithOTLPTraceExporter configures the tracer to export traces to an
+// OpenTelemetry Collector, or any other OTLP receiver, using OTLP/HTTP with
+// the protobuf encoding, instead of sending them to the Datadog Agent. If
+// endpoint is empty, the value of OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or
+// OTEL_EXPORTER_OTLP_ENDPOINT is used, defaulting to http://localhost:4318/v1/traces.
+// This can also be enabled by setting OTEL_TRACES_EXPORTER to "otlp".
+//
+// Traces dropped by sampling are not exported, and client-side stats are not
+// computed in this mode.
+func WithOTLPTraceExporter(endpoint string) StartOption {
+	return func(c *config) {
+		if endpoint == "" {
+			endpoint = otlpTracesURLFromEnv()
+		}
+		c.otlpTracesURL = endpoint
+	}
+}
+
+// WithAdaptiveSampling enables the adaptive sampler, which samples the traces
+// matching no sampling rule so that about budget traces per second are kept,
+// shared fairly between the services and resources of their root spans: the
+// traces of low-throughput endpoints are kept, while high-throughput endpoints
+// share the rest of the budget. Each endpoint keeps at least minPerKey traces
+// per second, even when this exceeds the budget. Rates are recomputed every 10
+// seconds, and the sampled traces are tagged with a dedicated sampling mechanism.
+// As with sampling rules, the decision is taken again when the trace context is
+// injected and when the root span finishes, should its resource have changed.
+//
+// Adaptive sampling can also be enabled with the DD_TRACE_ADAPTIVE_SAMPLING_BUDGET
+// and DD_TRACE_ADAPTIVE_SAMPLING_MIN_PER_KEY environment variables.
+func WithAdaptiveSampling(budget, minPerKey float64) StartOption {
+	return func(c *config) {
+		c.adaptiveSamplingBudget = budget
+		c.adaptiveSamplingMinPerKey = minPerKey
+	}
+}
+
+// WithTailSampling enables the tail sampler, which revises the sampling decision
+// of the traces which weren't kept when they started, once they are complete.
+// Traces matching any of the rules of cfg are then kept, with the sampling
+// priority ext.PriorityUserKeep, no matter the decision propagated to the
+// downstream services when they started.
+//
+// The spans of a trace are held in memory until it completes. When partial
+// flushing is enabled, the chunks flushed before the trace completes are held
+// by the tail sampler, up to cfg.MaxBufferedSpans spans across all traces,
+// after which the oldest traces are spilled according to cfg.SpillPolicy.
+func WithTailSampling(cfg TailSamplingConfig) StartOption {
+	return func(c *config) {
+		c.tailSampling = &cfg
+	}
+}
+
+// WithSpanProcessor registers a SpanProcessor called when spans start and
+// finish. It can be used multiple times, processors being called in the order
+// in which they were registered.
+func WithSpanProcessor(p SpanProcessor) StartOption {
+	return func(c *config) {
+		c.spanProcessors = append(c.spanProcessors, p)
+	}
+}
+
+// WithSpanExporter registers a SpanExporter receiving the finished traces, in
+// addition to them being sent to the agent. It can be used multiple times to
+// register several exporters. See SpanExporter for the delivery guarantees.
+func WithSpanExporter(e SpanExporter) StartOption {
+	return func(c *config) {
+		c.spanExporters = append(c.spanExporters, e)
+	}
+}
+
+// WithSpanExporterOnly registers a SpanExporter receiving the finished traces,
+// which are then no longer sent to the agent, or to any other default destination.
+// Other exporters can be added with WithSpanExporter.
+func WithSpanExporterOnly(e SpanExporter) StartOption {
+	return func(c *config) {
+		c.spanExporters = append(c.spanExporters, e)
+		c.spanExportersOnly = true
+	}
+}
+
 // WithOrchestrion configures Orchestrion's auto-instrumentation metadata.
 // This option is only intended to be used by Orchestrion https://github.com/DataDog/orchestrion
 func WithOrchestrion(metadata map[string]string) StartOption {
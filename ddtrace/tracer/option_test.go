@@ -22,6 +22,7 @@ import (
 	"runtime"
 	"runtime/debug"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -220,6 +221,18 @@ func TestLoadAgentFeatures(t *testing.T) {
 			defer srv.Close()
 			assert.Zero(t, newConfig(WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")), WithAgentTimeout(2)).agent)
 		})
+
+		t.Run("span-exporter-only", func(t *testing.T) {
+			var hits int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				atomic.AddInt32(&hits, 1)
+				w.Write([]byte(`{"endpoints":["/v0.6/stats"],"client_drop_p0s":true}`))
+			}))
+			defer srv.Close()
+			cfg := newConfig(WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")), WithSpanExporterOnly(&testExporter{}))
+			assert.Zero(t, cfg.agent)
+			assert.Zero(t, atomic.LoadInt32(&hits))
+		})
 	})
 
 	t.Run("OK", func(t *testing.T) {
@@ -254,6 +267,47 @@ func TestLoadAgentFeatures(t *testing.T) {
 	})
 }
 
+func TestTraceProtocol(t *testing.T) {
+	newAgent := func(endpoints string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(`{"endpoints":` + endpoints + `}`))
+		}))
+	}
+
+	t.Run("default", func(t *testing.T) {
+		srv := newAgent(`["/v0.4/traces","/v0.5/traces"]`)
+		defer srv.Close()
+		cfg := newConfig(WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")), WithAgentTimeout(2))
+		assert.Equal(t, traceProtocolV04, cfg.traceProtocol)
+		assert.Equal(t, srv.URL+"/v0.4/traces", cfg.transport.(*httpTransport).traceURL)
+	})
+
+	t.Run("v0.5", func(t *testing.T) {
+		t.Setenv("DD_TRACE_AGENT_PROTOCOL_VERSION", "0.5")
+		srv := newAgent(`["/v0.4/traces","/v0.5/traces"]`)
+		defer srv.Close()
+		cfg := newConfig(WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")), WithAgentTimeout(2))
+		assert.True(t, cfg.agent.v05)
+		assert.Equal(t, traceProtocolV05, cfg.traceProtocol)
+		assert.Equal(t, srv.URL+"/v0.5/traces", cfg.transport.(*httpTransport).traceURL)
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		t.Setenv("DD_TRACE_AGENT_PROTOCOL_VERSION", "0.5")
+		srv := newAgent(`["/v0.4/traces"]`)
+		defer srv.Close()
+		cfg := newConfig(WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")), WithAgentTimeout(2))
+		assert.Equal(t, traceProtocolV04, cfg.traceProtocol)
+		assert.Equal(t, srv.URL+"/v0.4/traces", cfg.transport.(*httpTransport).traceURL)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Setenv("DD_TRACE_AGENT_PROTOCOL_VERSION", "0.7")
+		cfg := newConfig(WithAgentTimeout(2))
+		assert.Equal(t, traceProtocolV04, cfg.traceProtocol)
+	})
+}
+
 // clearIntegreationsForTests clears the state of all integrations
 func clearIntegrationsForTests() {
 	for name, state := range contribIntegrations {
@@ -273,7 +327,7 @@ func TestAgentIntegration(t *testing.T) {
 		defer clearIntegrationsForTests()
 
 		cfg.loadContribIntegrations(nil)
-		assert.Equal(t, 58, len(cfg.integrations))
+		assert.Equal(t, 60, len(cfg.integrations))
 		for integrationName, v := range cfg.integrations {
 			assert.False(t, v.Instrumented, "integrationName=%s", integrationName)
 		}
@@ -1644,6 +1698,67 @@ func TestWithStatsComputation(t *testing.T) {
 	})
 }
 
+func TestWithAgentless(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		assert := assert.New(t)
+		c := newConfig()
+		assert.False(c.agentless)
+		assert.Empty(c.agentlessURL)
+		tr, ok := c.transport.(*httpTransport)
+		assert.True(ok)
+		assert.False(tr.gzip)
+		assert.Empty(tr.apiKey)
+	})
+	t.Run("option", func(t *testing.T) {
+		assert := assert.New(t)
+		c := newConfig(WithAgentless("abc"), WithAgentlessURL("https://intake.example.com/"))
+		assert.True(c.agentless)
+		assert.True(c.canComputeStats())
+		assert.True(c.canDropP0s())
+		tr, ok := c.transport.(*httpTransport)
+		assert.True(ok)
+		assert.True(tr.gzip)
+		assert.Equal("abc", tr.apiKey)
+		assert.Equal("https://intake.example.com/v0.4/traces", tr.traceURL)
+		assert.Equal("https://intake.example.com/v0.6/stats", tr.statsURL)
+	})
+	t.Run("env", func(t *testing.T) {
+		assert := assert.New(t)
+		t.Setenv("DD_TRACE_AGENTLESS_ENABLED", "true")
+		t.Setenv("DD_API_KEY", "def")
+		t.Setenv("DD_TRACE_AGENTLESS_URL", "http://localhost:9999/")
+		c := newConfig()
+		assert.True(c.agentless)
+		tr := c.transport.(*httpTransport)
+		assert.Equal("def", tr.apiKey)
+		assert.Equal("http://localhost:9999/v0.4/traces", tr.traceURL)
+	})
+	t.Run("url-option", func(t *testing.T) {
+		assert := assert.New(t)
+		t.Setenv("DD_API_KEY", "def")
+		c := newConfig(WithAgentless(""), WithAgentlessURL("http://localhost:9999"))
+		tr := c.transport.(*httpTransport)
+		assert.Equal("def", tr.apiKey)
+		assert.Equal("http://localhost:9999/v0.6/stats", tr.statsURL)
+	})
+	t.Run("no-api-key", func(t *testing.T) {
+		assert := assert.New(t)
+		c := newConfig(WithAgentless(""), WithAgentlessURL("http://localhost:9999"))
+		assert.False(c.agentless)
+		tr := c.transport.(*httpTransport)
+		assert.False(tr.gzip)
+		assert.Equal(c.agentURL.String()+"/v0.4/traces", tr.traceURL)
+	})
+	t.Run("no-url", func(t *testing.T) {
+		assert := assert.New(t)
+		c := newConfig(WithAgentless("abc"))
+		assert.False(c.agentless)
+		tr := c.transport.(*httpTransport)
+		assert.Empty(tr.apiKey)
+		assert.Equal(c.agentURL.String()+"/v0.4/traces", tr.traceURL)
+	})
+}
+
 func TestNoHTTPClientOverride(t *testing.T) {
 	t.Run("default", func(t *testing.T) {
 		assert := assert.New(t)
@@ -77,6 +77,8 @@ var propagationMapping = map[string]string{
 	"b3":           "b3 single header",
 	"b3multi":      "b3multi",
 	"datadog":      "datadog",
+	"xray":         "xray",
+	"jaeger":       "jaeger",
 	"none":         "none",
 }
 
@@ -156,9 +158,19 @@ func mapEnabled(ot string) (string, error) {
 	if strings.TrimSpace(strings.ToLower(ot)) == "none" {
 		return "false", nil
 	}
+	if isOTLPExporter(ot) {
+		// tracing stays enabled, traces are exported with the OTLP trace writer
+		return "", nil
+	}
 	return "", fmt.Errorf("The following configuration is not supported: OTEL_METRICS_EXPORTER=%v", ot)
 }
 
+// isOTLPExporter reports whether the OTEL_TRACES_EXPORTER value ot selects
+// the OTLP exporter.
+func isOTLPExporter(ot string) bool {
+	return strings.TrimSpace(strings.ToLower(ot)) == "otlp"
+}
+
 // mapSampleRate maps OTEL_TRACES_SAMPLER to DD_TRACE_SAMPLE_RATE
 func otelTraceIDRatio() string {
 	if v := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); v != "" {
@@ -0,0 +1,567 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	globalinternal "gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/version"
+)
+
+const (
+	// otlpDefaultTracesURL is the URL used to export traces when OTLP export is
+	// enabled but no endpoint is configured. It is the default OTLP/HTTP
+	// endpoint of the OpenTelemetry Collector.
+	otlpDefaultTracesURL = "http://localhost:4318/v1/traces"
+
+	// otlpTracesPath is appended to OTEL_EXPORTER_OTLP_ENDPOINT, as mandated
+	// by the OpenTelemetry exporter specification.
+	otlpTracesPath = "/v1/traces"
+
+	// otlpScopeName is the instrumentation scope name set on exported spans.
+	otlpScopeName = "gopkg.in/DataDog/dd-trace-go.v1"
+
+	// otlpPayloadSizeLimit specifies the maximum size of the encoded OTLP payload
+	// before it will trigger a flush to the collector.
+	otlpPayloadSizeLimit = payloadSizeLimit
+)
+
+var _ traceWriter = (*otlpTraceWriter)(nil)
+
+// otlpTraceWriter converts finished traces into OTLP ResourceSpans and exports
+// them to an OpenTelemetry Collector (or any other OTLP receiver) using the
+// OTLP/HTTP protobuf encoding.
+type otlpTraceWriter struct {
+	// config holds the tracer configuration
+	config *config
+
+	// payload buffers the converted traces until they are flushed
+	payload *otlpPayload
+
+	// climit limits the number of concurrent outgoing connections
+	climit chan struct{}
+
+	// wg waits for all uploads to finish
+	wg sync.WaitGroup
+
+	// statsd is used to send metrics
+	statsd globalinternal.StatsdClient
+
+	tracesQueued uint32
+}
+
+func newOTLPTraceWriter(c *config, statsdClient globalinternal.StatsdClient) *otlpTraceWriter {
+	return &otlpTraceWriter{
+		config:  c,
+		payload: newOTLPPayload(c),
+		climit:  make(chan struct{}, concurrentConnectionLimit),
+		statsd:  statsdClient,
+	}
+}
+
+func (h *otlpTraceWriter) add(trace []*span) {
+	// OTLP receivers have no notion of Datadog sampling priorities
+	trace = sampledSpans(trace)
+	if len(trace) == 0 {
+		h.statsd.Incr("datadog.tracer.traces_dropped", []string{"reason:sampling"}, 1)
+		return
+	}
+	h.payload.push(trace)
+	atomic.AddUint32(&h.tracesQueued, 1)
+	if h.payload.size() > otlpPayloadSizeLimit {
+		h.statsd.Incr("datadog.tracer.flush_triggered", []string{"reason:size"}, 1)
+		h.flush()
+	}
+}
+
+func (h *otlpTraceWriter) stop() {
+	h.statsd.Incr("datadog.tracer.flush_triggered", []string{"reason:shutdown"}, 1)
+	h.flush()
+	h.wg.Wait()
+}
+
+// flush will export any currently buffered traces to the OTLP endpoint.
+func (h *otlpTraceWriter) flush() {
+	if h.payload.itemCount() == 0 {
+		return
+	}
+	h.wg.Add(1)
+	h.climit <- struct{}{}
+	oldp := h.payload
+	h.payload = newOTLPPayload(h.config)
+	go func(p *otlpPayload) {
+		defer func(start time.Time) {
+			h.statsd.Count("datadog.tracer.queue.enqueued.traces", int64(atomic.SwapUint32(&h.tracesQueued, 0)), nil, 1)
+			<-h.climit
+			h.statsd.Timing("datadog.tracer.flush_duration", time.Since(start), nil, 1)
+			h.wg.Done()
+		}(time.Now())
+
+		count := p.itemCount()
+		body, err := p.encode()
+		if err != nil {
+			h.statsd.Count("datadog.tracer.traces_dropped", int64(count), []string{"reason:encoding_error"}, 1)
+			log.Error("Error encoding OTLP payload: %v", err)
+			return
+		}
+		for attempt := 0; attempt <= h.config.sendRetries; attempt++ {
+			log.Debug("Attempt to export OTLP payload: size: %d traces: %d\n", len(body), count)
+			var retry bool
+			retry, err = h.send(body)
+			if err == nil {
+				log.Debug("exported traces after %d attempts", attempt+1)
+				h.statsd.Count("datadog.tracer.flush_bytes", int64(len(body)), nil, 1)
+				h.statsd.Count("datadog.tracer.flush_traces", int64(count), nil, 1)
+				return
+			}
+			if !retry {
+				break
+			}
+			log.Error("failure exporting traces (attempt %d), will retry: %v", attempt+1, err)
+			time.Sleep(h.config.retryInterval)
+		}
+		h.statsd.Count("datadog.tracer.traces_dropped", int64(count), []string{"reason:send_failed"}, 1)
+		log.Error("lost %d traces: %v", count, err)
+	}(oldp)
+}
+
+// send posts the encoded payload to the configured OTLP endpoint. It reports
+// whether the request may be retried when an error is returned, following the
+// OTLP/HTTP specification on retryable response codes.
+func (h *otlpTraceWriter) send(body []byte) (retry bool, err error) {
+	req, err := http.NewRequest("POST", h.config.otlpTracesURL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("cannot create http request: %v", err)
+	}
+	for header, value := range h.config.otlpHeaders {
+		req.Header.Set(header, value)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "dd-trace-go/"+version.Tag)
+	resp, err := h.config.httpClient.Do(req)
+	if err != nil {
+		h.statsd.Incr("datadog.tracer.api.errors", []string{"reason:network_failure"}, 1)
+		return true, err
+	}
+	defer resp.Body.Close()
+	if code := resp.StatusCode; code >= 400 {
+		h.statsd.Incr("datadog.tracer.api.errors", []string{fmt.Sprintf("reason:server_response_%d", code)}, 1)
+		msg := make([]byte, 1000)
+		n, _ := io.ReadFull(resp.Body, msg)
+		txt := http.StatusText(code)
+		switch code {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			retry = true
+		}
+		if n > 0 {
+			return retry, fmt.Errorf("%q (Status: %s)", msg[:n], txt)
+		}
+		return retry, fmt.Errorf("%s", txt)
+	}
+	return false, nil
+}
+
+// otlpPayload buffers traces encoded in the OTLP protobuf format. Similarly to
+// payload, it is meant to be used only once and is not safe for concurrent use.
+type otlpPayload struct {
+	// services lists the services of the buffered spans, in order of appearance.
+	services []string
+
+	// spans maps a service name to the encoded spans of that service, each one
+	// as a ScopeSpans.spans field.
+	spans map[string][]byte
+
+	// count specifies the number of traces in the payload.
+	count int
+
+	// bytes holds the encoded size of the buffered spans.
+	bytes int
+
+	// config is used to populate the resource attributes.
+	config *config
+}
+
+func newOTLPPayload(c *config) *otlpPayload {
+	return &otlpPayload{
+		spans:  make(map[string][]byte),
+		config: c,
+	}
+}
+
+// push encodes the given trace and adds it to the payload.
+func (p *otlpPayload) push(trace []*span) {
+	for _, s := range trace {
+		buf, ok := p.spans[s.Service]
+		if !ok {
+			p.services = append(p.services, s.Service)
+		}
+		n := len(buf)
+		buf = appendOTLPBytes(buf, otlpScopeSpansSpans, appendOTLPSpan(nil, s))
+		p.spans[s.Service] = buf
+		p.bytes += len(buf) - n
+	}
+	p.count++
+}
+
+// itemCount returns the number of traces in the payload.
+func (p *otlpPayload) itemCount() int {
+	return p.count
+}
+
+// size returns the size of the payload once encoded, in bytes. The resource
+// and scope of each service are not accounted for, making it a slight
+// underestimate.
+func (p *otlpPayload) size() int {
+	return p.bytes
+}
+
+// encode returns the payload encoded as an OTLP ExportTraceServiceRequest,
+// with one ResourceSpans per service.
+func (p *otlpPayload) encode() ([]byte, error) {
+	b := make([]byte, 0, p.bytes+len(p.services)*256)
+	for _, service := range p.services {
+		var scope []byte
+		scope = appendOTLPBytes(scope, otlpScopeSpansScope, otlpScope())
+		scope = append(scope, p.spans[service]...)
+		var rs []byte
+		rs = appendOTLPBytes(rs, otlpResourceSpansResource, p.resource(service))
+		rs = appendOTLPBytes(rs, otlpResourceSpansScopeSpans, scope)
+		b = appendOTLPBytes(b, otlpRequestResourceSpans, rs)
+	}
+	return b, nil
+}
+
+// resource returns the encoded OTLP Resource describing the given service.
+func (p *otlpPayload) resource(service string) []byte {
+	var b []byte
+	b = appendOTLPKeyValue(b, otlpResourceAttributes, "service.name", otlpStringValue(service))
+	if p.config.env != "" {
+		b = appendOTLPKeyValue(b, otlpResourceAttributes, "deployment.environment", otlpStringValue(p.config.env))
+	}
+	if p.config.version != "" {
+		b = appendOTLPKeyValue(b, otlpResourceAttributes, "service.version", otlpStringValue(p.config.version))
+	}
+	if p.config.hostname != "" {
+		b = appendOTLPKeyValue(b, otlpResourceAttributes, "host.name", otlpStringValue(p.config.hostname))
+	}
+	b = appendOTLPKeyValue(b, otlpResourceAttributes, "telemetry.sdk.language", otlpStringValue("go"))
+	b = appendOTLPKeyValue(b, otlpResourceAttributes, "telemetry.sdk.name", otlpStringValue("datadog"))
+	b = appendOTLPKeyValue(b, otlpResourceAttributes, "telemetry.sdk.version", otlpStringValue(version.Tag))
+	return b
+}
+
+// otlpScope returns the encoded OTLP InstrumentationScope set on exported spans.
+func otlpScope() []byte {
+	var b []byte
+	b = appendOTLPString(b, otlpInstrumentationScopeName, otlpScopeName)
+	b = appendOTLPString(b, otlpInstrumentationScopeVersion, version.Tag)
+	return b
+}
+
+// Field numbers of the OTLP messages, as defined by the opentelemetry-proto
+// definitions of ExportTraceServiceRequest and the messages it references.
+const (
+	otlpRequestResourceSpans protowire.Number = 1
+
+	otlpResourceSpansResource   protowire.Number = 1
+	otlpResourceSpansScopeSpans protowire.Number = 2
+
+	otlpResourceAttributes protowire.Number = 1
+
+	otlpScopeSpansScope protowire.Number = 1
+	otlpScopeSpansSpans protowire.Number = 2
+
+	otlpInstrumentationScopeName    protowire.Number = 1
+	otlpInstrumentationScopeVersion protowire.Number = 2
+
+	otlpSpanTraceID      protowire.Number = 1
+	otlpSpanSpanID       protowire.Number = 2
+	otlpSpanParentSpanID protowire.Number = 4
+	otlpSpanName         protowire.Number = 5
+	otlpSpanKind         protowire.Number = 6
+	otlpSpanStartTime    protowire.Number = 7
+	otlpSpanEndTime      protowire.Number = 8
+	otlpSpanAttributes   protowire.Number = 9
+	otlpSpanEvents       protowire.Number = 11
+	otlpSpanLinks        protowire.Number = 13
+	otlpSpanStatus       protowire.Number = 15
+
+	otlpEventTime       protowire.Number = 1
+	otlpEventName       protowire.Number = 2
+	otlpEventAttributes protowire.Number = 3
+
+	otlpLinkTraceID    protowire.Number = 1
+	otlpLinkSpanID     protowire.Number = 2
+	otlpLinkTraceState protowire.Number = 3
+	otlpLinkAttributes protowire.Number = 4
+	otlpLinkFlags      protowire.Number = 6
+
+	otlpStatusMessage protowire.Number = 2
+	otlpStatusCode    protowire.Number = 3
+
+	otlpKeyValueKey   protowire.Number = 1
+	otlpKeyValueValue protowire.Number = 2
+
+	otlpAnyValueString protowire.Number = 1
+	otlpAnyValueBool   protowire.Number = 2
+	otlpAnyValueInt    protowire.Number = 3
+	otlpAnyValueDouble protowire.Number = 4
+	otlpAnyValueArray  protowire.Number = 5
+
+	otlpArrayValueValues protowire.Number = 1
+)
+
+// OTLP span kinds and status codes.
+const (
+	otlpSpanKindInternal = 1
+	otlpSpanKindServer   = 2
+	otlpSpanKindClient   = 3
+	otlpSpanKindProducer = 4
+	otlpSpanKindConsumer = 5
+
+	otlpStatusCodeError = 2
+)
+
+// otlpSpanKinds maps the Datadog span.kind values to the OTLP span kinds.
+var otlpSpanKinds = map[string]uint64{
+	ext.SpanKindServer:   otlpSpanKindServer,
+	ext.SpanKindClient:   otlpSpanKindClient,
+	ext.SpanKindProducer: otlpSpanKindProducer,
+	ext.SpanKindConsumer: otlpSpanKindConsumer,
+	ext.SpanKindInternal: otlpSpanKindInternal,
+}
+
+// appendOTLPSpan appends the finished span s to b, encoded as an OTLP Span.
+// Fields which have no OTLP counterpart, such as the resource and span type,
+// are stored as attributes so that they can be mapped back by the Datadog
+// exporter.
+func appendOTLPSpan(b []byte, s *span) []byte {
+	var tid [16]byte
+	if s.context != nil {
+		tid = s.context.traceID
+	} else {
+		binary.BigEndian.PutUint64(tid[8:], s.TraceID)
+	}
+	b = appendOTLPBytes(b, otlpSpanTraceID, tid[:])
+	b = appendOTLPBytes(b, otlpSpanSpanID, otlpSpanID(s.SpanID))
+	if s.ParentID != 0 {
+		b = appendOTLPBytes(b, otlpSpanParentSpanID, otlpSpanID(s.ParentID))
+	}
+	b = appendOTLPString(b, otlpSpanName, s.Name)
+	kind, ok := otlpSpanKinds[s.Meta[ext.SpanKind]]
+	if !ok {
+		kind = otlpSpanKindInternal
+	}
+	b = protowire.AppendTag(b, otlpSpanKind, protowire.VarintType)
+	b = protowire.AppendVarint(b, kind)
+	b = protowire.AppendTag(b, otlpSpanStartTime, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, uint64(s.Start))
+	b = protowire.AppendTag(b, otlpSpanEndTime, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, uint64(s.Start+s.Duration))
+
+	b = appendOTLPKeyValue(b, otlpSpanAttributes, "resource.name", otlpStringValue(s.Resource))
+	if s.Type != "" {
+		b = appendOTLPKeyValue(b, otlpSpanAttributes, "span.type", otlpStringValue(s.Type))
+	}
+	for k, v := range s.Meta {
+		if k == "_dd.span_links" {
+			// span links are exported natively below
+			continue
+		}
+		b = appendOTLPKeyValue(b, otlpSpanAttributes, k, otlpStringValue(v))
+	}
+	for k, v := range s.Metrics {
+		if k == keySamplingPriority {
+			b = appendOTLPKeyValue(b, otlpSpanAttributes, "sampling.priority", otlpIntValue(int64(v)))
+			continue
+		}
+		b = appendOTLPKeyValue(b, otlpSpanAttributes, k, otlpDoubleValue(v))
+	}
+	for _, e := range s.SpanEvents {
+		var evt []byte
+		evt = protowire.AppendTag(evt, otlpEventTime, protowire.Fixed64Type)
+		evt = protowire.AppendFixed64(evt, uint64(e.TimeUnixNano))
+		evt = appendOTLPString(evt, otlpEventName, e.Name)
+		for k, v := range e.Attributes {
+			if val, ok := otlpAttributeValue(v); ok {
+				evt = appendOTLPKeyValue(evt, otlpEventAttributes, k, val)
+			}
+		}
+		b = appendOTLPBytes(b, otlpSpanEvents, evt)
+	}
+	for _, l := range s.SpanLinks {
+		var ltid [16]byte
+		binary.BigEndian.PutUint64(ltid[:8], l.TraceIDHigh)
+		binary.BigEndian.PutUint64(ltid[8:], l.TraceID)
+		var lb []byte
+		lb = appendOTLPBytes(lb, otlpLinkTraceID, ltid[:])
+		lb = appendOTLPBytes(lb, otlpLinkSpanID, otlpSpanID(l.SpanID))
+		lb = appendOTLPString(lb, otlpLinkTraceState, l.Tracestate)
+		for k, v := range l.Attributes {
+			lb = appendOTLPKeyValue(lb, otlpLinkAttributes, k, otlpStringValue(v))
+		}
+		// Datadog sets the most significant bit to signal the flags are set;
+		// OTLP only carries the W3C trace flags in the lower 8 bits.
+		if flags := l.Flags & 0xff; flags != 0 {
+			lb = protowire.AppendTag(lb, otlpLinkFlags, protowire.Fixed32Type)
+			lb = protowire.AppendFixed32(lb, flags)
+		}
+		b = appendOTLPBytes(b, otlpSpanLinks, lb)
+	}
+	if s.Error != 0 {
+		var status []byte
+		status = appendOTLPString(status, otlpStatusMessage, s.Meta[ext.ErrorMsg])
+		status = protowire.AppendTag(status, otlpStatusCode, protowire.VarintType)
+		status = protowire.AppendVarint(status, otlpStatusCodeError)
+		b = appendOTLPBytes(b, otlpSpanStatus, status)
+	}
+	return b
+}
+
+// otlpAttributeValue returns the normalized span event attribute value v
+// encoded as an OTLP AnyValue. It reports false if v has an unsupported type.
+func otlpAttributeValue(v interface{}) ([]byte, bool) {
+	switch v := v.(type) {
+	case string:
+		return otlpStringValue(v), true
+	case bool:
+		return otlpBoolValue(v), true
+	case int64:
+		return otlpIntValue(v), true
+	case float64:
+		return otlpDoubleValue(v), true
+	case []string:
+		values := make([][]byte, len(v))
+		for i, x := range v {
+			values[i] = otlpStringValue(x)
+		}
+		return otlpArrayValue(values), true
+	case []bool:
+		values := make([][]byte, len(v))
+		for i, x := range v {
+			values[i] = otlpBoolValue(x)
+		}
+		return otlpArrayValue(values), true
+	case []int64:
+		values := make([][]byte, len(v))
+		for i, x := range v {
+			values[i] = otlpIntValue(x)
+		}
+		return otlpArrayValue(values), true
+	case []float64:
+		values := make([][]byte, len(v))
+		for i, x := range v {
+			values[i] = otlpDoubleValue(x)
+		}
+		return otlpArrayValue(values), true
+	}
+	return nil, false
+}
+
+func otlpStringValue(v string) []byte {
+	b := protowire.AppendTag(nil, otlpAnyValueString, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func otlpBoolValue(v bool) []byte {
+	b := protowire.AppendTag(nil, otlpAnyValueBool, protowire.VarintType)
+	return protowire.AppendVarint(b, protowire.EncodeBool(v))
+}
+
+func otlpIntValue(v int64) []byte {
+	b := protowire.AppendTag(nil, otlpAnyValueInt, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+func otlpDoubleValue(v float64) []byte {
+	b := protowire.AppendTag(nil, otlpAnyValueDouble, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(v))
+}
+
+func otlpArrayValue(values [][]byte) []byte {
+	var arr []byte
+	for _, v := range values {
+		arr = appendOTLPBytes(arr, otlpArrayValueValues, v)
+	}
+	return appendOTLPBytes(nil, otlpAnyValueArray, arr)
+}
+
+// appendOTLPKeyValue appends to b the KeyValue k=v as field num, where v is an
+// encoded AnyValue.
+func appendOTLPKeyValue(b []byte, num protowire.Number, k string, v []byte) []byte {
+	kv := protowire.AppendTag(nil, otlpKeyValueKey, protowire.BytesType)
+	kv = protowire.AppendString(kv, k)
+	kv = appendOTLPBytes(kv, otlpKeyValueValue, v)
+	return appendOTLPBytes(b, num, kv)
+}
+
+// appendOTLPBytes appends to b the bytes v, such as an encoded message, as
+// field num.
+func appendOTLPBytes(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// appendOTLPString appends to b the string v as field num. Empty strings are
+// omitted, as they are the default value of proto3 fields.
+func appendOTLPString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func otlpSpanID(id uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], id)
+	return b[:]
+}
+
+// otlpTracesURLFromEnv returns the URL traces should be exported to, based on
+// the OTEL_EXPORTER_OTLP_TRACES_ENDPOINT and OTEL_EXPORTER_OTLP_ENDPOINT
+// environment variables.
+func otlpTracesURLFromEnv() string {
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); v != "" {
+		// signal-specific endpoints are used as-is
+		return v
+	}
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); v != "" {
+		return strings.TrimSuffix(v, "/") + otlpTracesPath
+	}
+	return otlpDefaultTracesURL
+}
+
+// otlpHeadersFromEnv returns the headers to send along OTLP export requests,
+// as set through OTEL_EXPORTER_OTLP_HEADERS and OTEL_EXPORTER_OTLP_TRACES_HEADERS.
+func otlpHeadersFromEnv() map[string]string {
+	headers := make(map[string]string)
+	for _, env := range []string{"OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TRACES_HEADERS"} {
+		for _, kv := range strings.Split(os.Getenv(env), ",") {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				continue
+			}
+			headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return headers
+}
@@ -0,0 +1,313 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/samplernames"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/statsdtest"
+)
+
+// otlpTestServer records the OTLP requests it receives, failing the first
+// failCount of them with the given status code.
+type otlpTestServer struct {
+	mu        sync.Mutex
+	failCount int
+	status    int
+	attempts  int
+	headers   http.Header
+	traces    []otlpMessage
+}
+
+// otlpMessage is a decoded protobuf message, mapping field numbers to their
+// values: uint64 for varint and fixed64 fields, uint32 for fixed32 fields and
+// []byte for length-delimited fields.
+type otlpMessage map[protowire.Number][]interface{}
+
+func decodeOTLPMessage(b []byte) (otlpMessage, error) {
+	m := make(otlpMessage)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		var v interface{}
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			v, n = protowire.ConsumeFixed32(b)
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		default:
+			return nil, errors.New("unexpected wire type")
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		m[num] = append(m[num], v)
+	}
+	return m, nil
+}
+
+func (m otlpMessage) messages(t *testing.T, num protowire.Number) []otlpMessage {
+	var msgs []otlpMessage
+	for _, v := range m[num] {
+		msg, err := decodeOTLPMessage(v.([]byte))
+		require.NoError(t, err)
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func (m otlpMessage) bytes(num protowire.Number) []byte {
+	if len(m[num]) == 0 {
+		return nil
+	}
+	return m[num][0].([]byte)
+}
+
+func (m otlpMessage) str(num protowire.Number) string {
+	return string(m.bytes(num))
+}
+
+func (m otlpMessage) uint(num protowire.Number) uint64 {
+	if len(m[num]) == 0 {
+		return 0
+	}
+	switch v := m[num][0].(type) {
+	case uint32:
+		return uint64(v)
+	default:
+		return v.(uint64)
+	}
+}
+
+// attributes decodes the KeyValue list found at field num.
+func (m otlpMessage) attributes(t *testing.T, num protowire.Number) map[string]interface{} {
+	attrs := make(map[string]interface{})
+	for _, kv := range m.messages(t, num) {
+		attrs[kv.str(otlpKeyValueKey)] = otlpAnyValue(t, kv.messages(t, otlpKeyValueValue)[0])
+	}
+	return attrs
+}
+
+func otlpAnyValue(t *testing.T, v otlpMessage) interface{} {
+	switch {
+	case v[otlpAnyValueString] != nil:
+		return v.str(otlpAnyValueString)
+	case v[otlpAnyValueBool] != nil:
+		return v.uint(otlpAnyValueBool) != 0
+	case v[otlpAnyValueInt] != nil:
+		return int64(v.uint(otlpAnyValueInt))
+	case v[otlpAnyValueDouble] != nil:
+		return math.Float64frombits(v.uint(otlpAnyValueDouble))
+	case v[otlpAnyValueArray] != nil:
+		var values []interface{}
+		for _, x := range v.messages(t, otlpAnyValueArray)[0].messages(t, otlpArrayValueValues) {
+			values = append(values, otlpAnyValue(t, x))
+		}
+		return values
+	}
+	return nil
+}
+
+// otlpSpans returns the spans of all the ResourceSpans found in the request req.
+func otlpSpans(t *testing.T, req otlpMessage) []otlpMessage {
+	var spans []otlpMessage
+	for _, rs := range req.messages(t, otlpRequestResourceSpans) {
+		for _, ss := range rs.messages(t, otlpResourceSpansScopeSpans) {
+			spans = append(spans, ss.messages(t, otlpScopeSpansSpans)...)
+		}
+	}
+	return spans
+}
+
+func (s *otlpTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	s.headers = r.Header
+	if s.attempts <= s.failCount {
+		w.WriteHeader(s.status)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	td, err := decodeOTLPMessage(body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.traces = append(s.traces, td)
+}
+
+func TestOTLPTraceWriter(t *testing.T) {
+	t.Run("encode", func(t *testing.T) {
+		assert := assert.New(t)
+		srv := &otlpTestServer{}
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+		t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=secret")
+
+		c := newConfig(WithOTLPTraceExporter(ts.URL), WithEnv("prod"), WithServiceVersion("1.2.3"))
+		h := newOTLPTraceWriter(c, &statsdtest.TestStatsdClient{})
+		root := newSpan("http.request", "web", "GET /", 1, 2, 0)
+		root.context.traceID.SetUpper(3)
+		root.Type = ext.SpanTypeWeb
+		root.Meta[ext.SpanKind] = ext.SpanKindServer
+		root.Meta[ext.ErrorMsg] = "boom"
+		root.Metrics[keySamplingPriority] = 1
+		root.Metrics["num"] = 42
+		root.Error = 1
+		root.Duration = int64(time.Second)
+		root.SpanLinks = []ddtrace.SpanLink{{TraceID: 5, TraceIDHigh: 6, SpanID: 7, Tracestate: "dd=s:1", Flags: 1 | 1<<31, Attributes: map[string]string{"k": "v"}}}
+		root.AddEvent("evt", time.Unix(0, 100), map[string]interface{}{"n": 1, "tags": []string{"a"}})
+		child := newSpan("sql.query", "db", "SELECT 1", 4, 2, 1)
+		child.context = root.context
+		h.add([]*span{root, child})
+		h.stop()
+
+		require.Len(t, srv.traces, 1)
+		assert.Equal("secret", srv.headers.Get("api-key"))
+		assert.Equal("application/x-protobuf", srv.headers.Get("Content-Type"))
+		td := srv.traces[0]
+		assert.Len(otlpSpans(t, td), 2)
+		resources := td.messages(t, otlpRequestResourceSpans)
+		require.Len(t, resources, 2)
+
+		rs := resources[0]
+		res := rs.messages(t, otlpResourceSpansResource)[0].attributes(t, otlpResourceAttributes)
+		assert.Equal("web", res["service.name"])
+		assert.Equal("prod", res["deployment.environment"])
+		assert.Equal("1.2.3", res["service.version"])
+		scope := rs.messages(t, otlpResourceSpansScopeSpans)[0]
+		assert.Equal(otlpScopeName, scope.messages(t, otlpScopeSpansScope)[0].str(otlpInstrumentationScopeName))
+		sp := scope.messages(t, otlpScopeSpansSpans)[0]
+		assert.Equal("http.request", sp.str(otlpSpanName))
+		assert.Equal(uint64(otlpSpanKindServer), sp.uint(otlpSpanKind))
+		assert.Equal([]byte{7: 3, 15: 2}, sp.bytes(otlpSpanTraceID))
+		assert.Equal([]byte{7: 1}, sp.bytes(otlpSpanSpanID))
+		assert.Nil(sp.bytes(otlpSpanParentSpanID))
+		assert.Equal(uint64(time.Second), sp.uint(otlpSpanEndTime)-sp.uint(otlpSpanStartTime))
+		status := sp.messages(t, otlpSpanStatus)[0]
+		assert.Equal(uint64(otlpStatusCodeError), status.uint(otlpStatusCode))
+		assert.Equal("boom", status.str(otlpStatusMessage))
+		attrs := sp.attributes(t, otlpSpanAttributes)
+		assert.Equal("GET /", attrs["resource.name"])
+		assert.Equal(ext.SpanTypeWeb, attrs["span.type"])
+		assert.Equal(42.0, attrs["num"])
+		assert.Equal(int64(1), attrs["sampling.priority"])
+		links := sp.messages(t, otlpSpanLinks)
+		require.Len(t, links, 1)
+		assert.Equal([]byte{7: 6, 15: 5}, links[0].bytes(otlpLinkTraceID))
+		assert.Equal("dd=s:1", links[0].str(otlpLinkTraceState))
+		assert.Equal(uint64(1), links[0].uint(otlpLinkFlags))
+		assert.Equal(map[string]interface{}{"k": "v"}, links[0].attributes(t, otlpLinkAttributes))
+		events := sp.messages(t, otlpSpanEvents)
+		require.Len(t, events, 1)
+		assert.Equal("evt", events[0].str(otlpEventName))
+		assert.Equal(uint64(100), events[0].uint(otlpEventTime))
+		assert.Equal(map[string]interface{}{"n": int64(1), "tags": []interface{}{"a"}}, events[0].attributes(t, otlpEventAttributes))
+
+		child1 := resources[1].messages(t, otlpResourceSpansScopeSpans)[0].messages(t, otlpScopeSpansSpans)
+		require.Len(t, child1, 1)
+		assert.Equal([]byte{7: 1}, child1[0].bytes(otlpSpanParentSpanID))
+		assert.Equal(uint64(otlpSpanKindInternal), child1[0].uint(otlpSpanKind))
+	})
+
+	t.Run("size", func(t *testing.T) {
+		p := newOTLPPayload(newConfig())
+		p.push([]*span{makeSpan(0), makeSpan(0)})
+		body, err := p.encode()
+		require.NoError(t, err)
+		assert.Greater(t, p.size(), 0)
+		assert.LessOrEqual(t, p.size(), len(body))
+	})
+
+	t.Run("retries", func(t *testing.T) {
+		for _, tc := range []struct {
+			status      int
+			failCount   int
+			expAttempts int
+			sent        bool
+		}{
+			{status: http.StatusServiceUnavailable, failCount: 1, expAttempts: 2, sent: true},
+			{status: http.StatusTooManyRequests, failCount: 3, expAttempts: 3, sent: false},
+			{status: http.StatusBadRequest, failCount: 1, expAttempts: 1, sent: false},
+		} {
+			srv := &otlpTestServer{status: tc.status, failCount: tc.failCount}
+			ts := httptest.NewServer(srv)
+			var statsd statsdtest.TestStatsdClient
+			c := newConfig(WithOTLPTraceExporter(ts.URL), WithSendRetries(2))
+			h := newOTLPTraceWriter(c, &statsd)
+			h.add([]*span{makeSpan(0)})
+			h.stop()
+			ts.Close()
+
+			assert.Equal(t, tc.expAttempts, srv.attempts)
+			assert.Equal(t, tc.sent, len(srv.traces) == 1)
+			if !tc.sent {
+				assert.Equal(t, int64(1), statsd.Counts()["datadog.tracer.traces_dropped"])
+			}
+		}
+	})
+
+	t.Run("sampling", func(t *testing.T) {
+		srv := &otlpTestServer{}
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+		c := newConfig(WithOTLPTraceExporter(ts.URL))
+		h := newOTLPTraceWriter(c, &statsdtest.TestStatsdClient{})
+
+		dropped := makeSpan(0)
+		dropped.context.setSamplingPriority(ext.PriorityAutoReject, samplernames.AgentRate)
+		h.add([]*span{dropped})
+		assert.Equal(t, 0, h.payload.itemCount())
+
+		root, single := makeSpan(0), makeSpan(0)
+		single.context = root.context
+		root.context.setSamplingPriority(ext.PriorityAutoReject, samplernames.AgentRate)
+		single.Metrics[keySpanSamplingMechanism] = float64(samplernames.SingleSpan)
+		h.add([]*span{root, single})
+		h.stop()
+
+		require.Len(t, srv.traces, 1)
+		assert.Len(t, otlpSpans(t, srv.traces[0]), 1)
+	})
+}
+
+func TestOTLPTracesURLFromEnv(t *testing.T) {
+	assert.Equal(t, otlpDefaultTracesURL, otlpTracesURLFromEnv())
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318/")
+	assert.Equal(t, "http://collector:4318/v1/traces", otlpTracesURLFromEnv())
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "http://collector:4318/custom")
+	assert.Equal(t, "http://collector:4318/custom", otlpTracesURLFromEnv())
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "otlp")
+	c := newConfig()
+	assert.Equal(t, "http://collector:4318/custom", c.otlpTracesURL)
+	assert.True(t, c.enabled.current)
+}
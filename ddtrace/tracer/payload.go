@@ -57,6 +57,16 @@ type payload struct {
 
 	// reader is used for reading the contents of buf.
 	reader *bytes.Reader
+
+	// strings holds the string dictionary when the payload is encoded in the
+	// v0.5 format. It is nil for v0.4 payloads.
+	strings *stringTable
+
+	// scratch is reused to encode v0.5 traces before writing them to buf.
+	scratch []byte
+
+	// readerV05 is used for reading the contents of a v0.5 payload.
+	readerV05 io.Reader
 }
 
 var _ io.Reader = (*payload)(nil)
@@ -72,6 +82,11 @@ func newPayload() *payload {
 
 // push pushes a new item into the stream.
 func (p *payload) push(t spanList) error {
+	if p.strings != nil {
+		p.pushV05(t)
+		atomic.AddUint32(&p.count, 1)
+		return nil
+	}
 	p.buf.Grow(t.Msgsize())
 	if err := msgp.Encode(&p.buf, t); err != nil {
 		return err
@@ -89,6 +104,9 @@ func (p *payload) itemCount() int {
 // size returns the payload size in bytes. After the first read the value becomes
 // inaccurate by up to 8 bytes.
 func (p *payload) size() int {
+	if p.strings != nil {
+		return p.sizeV05()
+	}
 	return p.buf.Len() + len(p.header) - p.off
 }
 
@@ -96,6 +114,7 @@ func (p *payload) size() int {
 // underlying byte contents of the buffer. reset should not be used in order to
 // reuse the payload for another set of traces.
 func (p *payload) reset() {
+	p.readerV05 = nil
 	p.updateHeader()
 	if p.reader != nil {
 		p.reader.Seek(0, 0)
@@ -106,6 +125,11 @@ func (p *payload) reset() {
 func (p *payload) clear() {
 	p.buf = bytes.Buffer{}
 	p.reader = nil
+	p.readerV05 = nil
+	p.scratch = nil
+	if p.strings != nil {
+		p.strings = newStringTable()
+	}
 }
 
 // https://github.com/msgpack/msgpack/blob/master/spec.md#array-format-family
@@ -141,6 +165,12 @@ func (p *payload) Close() error {
 
 // Read implements io.Reader. It reads from the msgpack-encoded stream.
 func (p *payload) Read(b []byte) (n int, err error) {
+	if p.strings != nil {
+		if p.readerV05 == nil {
+			p.readerV05 = p.newReaderV05()
+		}
+		return p.readerV05.Read(b)
+	}
 	if p.off < len(p.header) {
 		// reading header
 		n = copy(b, p.header[p.off:])
@@ -74,6 +74,177 @@ func TestPayloadDecode(t *testing.T) {
 	}
 }
 
+// decodeV05 decodes a v0.5 payload, resolving all string indexes through
+// its dictionary.
+func decodeV05(r io.Reader) (spanLists, error) {
+	mr := msgp.NewReader(r)
+	if _, err := mr.ReadArrayHeader(); err != nil {
+		return nil, err
+	}
+	n, err := mr.ReadArrayHeader()
+	if err != nil {
+		return nil, err
+	}
+	dict := make([]string, n)
+	for i := range dict {
+		if dict[i], err = mr.ReadString(); err != nil {
+			return nil, err
+		}
+	}
+	str := func() string {
+		var i uint32
+		if err == nil {
+			i, err = mr.ReadUint32()
+		}
+		if int(i) >= len(dict) {
+			return ""
+		}
+		return dict[i]
+	}
+	num := func() uint64 {
+		var v uint64
+		if err == nil {
+			v, err = mr.ReadUint64()
+		}
+		return v
+	}
+	ntraces, err := mr.ReadArrayHeader()
+	if err != nil {
+		return nil, err
+	}
+	lists := make(spanLists, ntraces)
+	for i := range lists {
+		nspans, err := mr.ReadArrayHeader()
+		if err != nil {
+			return nil, err
+		}
+		lists[i] = make(spanList, nspans)
+		for j := range lists[i] {
+			if _, err = mr.ReadArrayHeader(); err != nil {
+				return nil, err
+			}
+			s := &span{Service: str(), Name: str(), Resource: str()}
+			s.TraceID, s.SpanID, s.ParentID = num(), num(), num()
+			s.Start, s.Duration = int64(num()), int64(num())
+			if err == nil {
+				s.Error, err = mr.ReadInt32()
+			}
+			var nmeta, nmetrics uint32
+			if err == nil {
+				nmeta, err = mr.ReadMapHeader()
+			}
+			s.Meta = make(map[string]string, nmeta)
+			for k := uint32(0); k < nmeta; k++ {
+				s.Meta[str()] = str()
+			}
+			if err == nil {
+				nmetrics, err = mr.ReadMapHeader()
+			}
+			s.Metrics = make(map[string]float64, nmetrics)
+			for k := uint32(0); k < nmetrics; k++ {
+				key := str()
+				if err == nil {
+					s.Metrics[key], err = mr.ReadFloat64()
+				}
+			}
+			s.Type = str()
+			if err != nil {
+				return nil, err
+			}
+			lists[i][j] = s
+		}
+	}
+	return lists, nil
+}
+
+// TestPayloadV05 tests that traces pushed into a v0.5 payload can be decoded
+// back and that the reported size matches the encoded payload.
+func TestPayloadV05(t *testing.T) {
+	for _, n := range []int{10, 1 << 10, 1 << 17} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			assert := assert.New(t)
+			p := newPayloadV05()
+			lists := make(spanLists, n)
+			for i := 0; i < n; i++ {
+				list := newSpanList(i%5 + 1)
+				list[0].Meta["key"] = "value"
+				list[0].Error = 1
+				lists[i] = list
+				p.push(list)
+			}
+			assert.Equal(n, p.itemCount())
+
+			b, err := io.ReadAll(p)
+			assert.NoError(err)
+			assert.Equal(len(b), p.size())
+
+			got, err := decodeV05(bytes.NewReader(b))
+			assert.NoError(err)
+			assert.Len(got, n)
+			for i, list := range lists {
+				assert.Len(got[i], len(list))
+				for j, s := range list {
+					g := got[i][j]
+					assert.Equal(s.Service, g.Service)
+					assert.Equal(s.Name, g.Name)
+					assert.Equal(s.Resource, g.Resource)
+					assert.Equal(s.SpanID, g.SpanID)
+					assert.Equal(s.Start, g.Start)
+					assert.Equal(s.Error, g.Error)
+					assert.Equal(s.Meta, g.Meta)
+					assert.Equal(s.Metrics, g.Metrics)
+					assert.Equal(s.Type, g.Type)
+				}
+			}
+		})
+	}
+
+	t.Run("reset", func(t *testing.T) {
+		p := newPayloadV05()
+		p.push(newSpanList(3))
+		first, err := io.ReadAll(p)
+		assert.NoError(t, err)
+		p.reset()
+		second, err := io.ReadAll(p)
+		assert.NoError(t, err)
+		assert.Equal(t, first, second)
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		p := newPayloadV05()
+		p.push(newSpanList(3))
+		p.clear()
+		// the string table is left in a usable state
+		assert.NotPanics(t, func() { p.strings.add("key") })
+		assert.Equal(t, uint32(1), p.strings.add("key"))
+	})
+}
+
+func BenchmarkPayloadEncoding(b *testing.B) {
+	for name, newp := range map[string]func() *payload{
+		"v0.4": newPayload,
+		"v0.5": newPayloadV05,
+	} {
+		b.Run(name, func(b *testing.B) {
+			trace := newSpanList(5)
+			for _, s := range trace {
+				s.Meta["http.url"] = "http://example.com/api/users"
+				s.Meta["component"] = "net/http"
+			}
+			b.ReportAllocs()
+			var size int
+			for i := 0; i < b.N; i++ {
+				p := newp()
+				for j := 0; j < 1000; j++ {
+					p.push(trace)
+				}
+				size = p.size()
+			}
+			b.ReportMetric(float64(size), "bytes/payload")
+		})
+	}
+}
+
 func BenchmarkPayloadThroughput(b *testing.B) {
 	b.Run("10K", benchmarkPayloadThroughput(1))
 	b.Run("100K", benchmarkPayloadThroughput(10))
@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/tinylib/msgp/msgp"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+const (
+	// traceProtocolV04 is the default trace payload format, where every span
+	// is a msgpack map holding all of its strings.
+	traceProtocolV04 = 0.4
+
+	// traceProtocolV05 is the trace payload format in which the strings of all
+	// spans are deduplicated into a dictionary sent along the traces. See
+	// https://github.com/DataDog/datadog-agent/blob/main/pkg/trace/api/version.go
+	traceProtocolV05 = 0.5
+)
+
+// v05SpanFields is the number of fields of a span encoded in the v0.5 format:
+// service, name, resource, trace_id, span_id, parent_id, start, duration,
+// error, meta, metrics and type, in this order.
+const v05SpanFields = 12
+
+// stringTable deduplicates the strings of a v0.5 payload. Each distinct string
+// is stored once in the dictionary and spans refer to it by its index.
+//
+// stringTable is not safe for concurrent use.
+type stringTable struct {
+	// index maps every string in the table to its position in the dictionary.
+	index map[string]uint32
+
+	// buf holds the msgpack-encoded strings of the dictionary, in index order.
+	buf bytes.Buffer
+
+	// scratch is used to encode strings before they are written to buf.
+	scratch []byte
+}
+
+func newStringTable() *stringTable {
+	t := &stringTable{index: make(map[string]uint32)}
+	// like the other tracers, reserve index 0 for the empty string
+	t.add("")
+	return t
+}
+
+// add returns the index of s in the table, adding it if it isn't present yet.
+func (t *stringTable) add(s string) uint32 {
+	if i, ok := t.index[s]; ok {
+		return i
+	}
+	i := uint32(len(t.index))
+	t.index[s] = i
+	t.scratch = msgp.AppendString(t.scratch[:0], s)
+	t.buf.Write(t.scratch)
+	return i
+}
+
+// len returns the number of strings in the table.
+func (t *stringTable) len() uint32 {
+	return uint32(len(t.index))
+}
+
+// newPayloadV05 returns a ready to use payload which encodes traces in the
+// v0.5 format. The payload is read as a two-element array holding the string
+// dictionary followed by the array of traces.
+func newPayloadV05() *payload {
+	p := newPayload()
+	p.strings = newStringTable()
+	return p
+}
+
+// pushV05 encodes the trace t in the v0.5 format into the payload buffer.
+func (p *payload) pushV05(t spanList) {
+	st := p.strings
+	b := p.scratch[:0]
+	b = msgp.AppendArrayHeader(b, uint32(len(t)))
+	for _, s := range t {
+		b = msgp.AppendArrayHeader(b, v05SpanFields)
+		b = msgp.AppendUint32(b, st.add(s.Service))
+		b = msgp.AppendUint32(b, st.add(s.Name))
+		b = msgp.AppendUint32(b, st.add(s.Resource))
+		b = msgp.AppendUint64(b, s.TraceID)
+		b = msgp.AppendUint64(b, s.SpanID)
+		b = msgp.AppendUint64(b, s.ParentID)
+		b = msgp.AppendInt64(b, s.Start)
+		b = msgp.AppendInt64(b, s.Duration)
+		b = msgp.AppendInt32(b, s.Error)
+		b = msgp.AppendMapHeader(b, uint32(len(s.Meta)+len(s.MetaStruct)))
+		for k, v := range s.Meta {
+			b = msgp.AppendUint32(b, st.add(k))
+			b = msgp.AppendUint32(b, st.add(v))
+		}
+		// The v0.5 format has no meta_struct field, so structured values are
+		// sent as JSON strings in meta, as done by the logTraceWriter.
+		for k, v := range s.MetaStruct {
+			jsonValue, err := json.Marshal(v)
+			if err != nil {
+				// the entry is still written, empty, as it is accounted
+				// for in the map header
+				log.Error("Error marshaling value %q: %v", v, err)
+			}
+			b = msgp.AppendUint32(b, st.add(k))
+			b = msgp.AppendUint32(b, st.add(string(jsonValue)))
+		}
+		b = msgp.AppendMapHeader(b, uint32(len(s.Metrics)))
+		for k, v := range s.Metrics {
+			b = msgp.AppendUint32(b, st.add(k))
+			b = msgp.AppendFloat64(b, v)
+		}
+		b = msgp.AppendUint32(b, st.add(s.Type))
+	}
+	p.buf.Write(b)
+	p.scratch = b
+}
+
+// sizeV05 returns the size in bytes of the v0.5 payload.
+func (p *payload) sizeV05() int {
+	return 1 + arrayHeaderSize(p.strings.len()) + p.strings.buf.Len() +
+		arrayHeaderSize(uint32(p.itemCount())) + p.buf.Len()
+}
+
+// newReaderV05 returns a reader over the full v0.5 payload.
+func (p *payload) newReaderV05() io.Reader {
+	prefix := msgp.AppendArrayHeader(nil, 2)
+	prefix = msgp.AppendArrayHeader(prefix, p.strings.len())
+	return io.MultiReader(
+		bytes.NewReader(prefix),
+		bytes.NewReader(p.strings.buf.Bytes()),
+		bytes.NewReader(msgp.AppendArrayHeader(nil, uint32(p.itemCount()))),
+		bytes.NewReader(p.buf.Bytes()),
+	)
+}
+
+// arrayHeaderSize returns the size of the msgpack header of an array of n items.
+func arrayHeaderSize(n uint32) int {
+	switch {
+	case n <= 15:
+		return 1
+	case n <= 1<<16-1:
+		return 3
+	default:
+		return 5
+	}
+}
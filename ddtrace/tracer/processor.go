@@ -0,0 +1,153 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+// SpanProcessor is a hook into the lifecycle of all the spans created by the
+// tracer, whatever the integration creating them. It can be used to scrub
+// sensitive data or to add computed tags before spans leave the process.
+// Processors are registered with WithSpanProcessor and are called in the order
+// in which they were registered.
+//
+// Processors are called synchronously, by the goroutines starting and finishing
+// spans, and must be safe for concurrent use. They should be fast, as they delay
+// the application.
+type SpanProcessor interface {
+	// OnStart is called when a span is started, after its start options and
+	// the global tags have been applied and its sampling priority has been
+	// decided, before it is returned to the caller.
+	OnStart(s ProcessedSpan)
+
+	// OnFinish is called with each chunk of finished spans, before it is
+	// sampled and encoded. A chunk holds all the spans of a trace or, when
+	// partial flushing is enabled, those finished so far. OnFinish returns the
+	// spans to keep, which must be a subset of spans: returning an empty slice
+	// drops the whole chunk. Dropping a span doesn't drop its children.
+	// OnFinish may start new spans, including in the trace being processed.
+	//
+	// When client-side stats are computed, they are computed from the spans
+	// returned by the last processor.
+	OnFinish(spans []ProcessedSpan) []ProcessedSpan
+}
+
+// ProcessedSpan gives a SpanProcessor access to a span. It must not be retained
+// after the processor returns.
+type ProcessedSpan interface {
+	// SpanID returns the identifier of the span.
+	SpanID() uint64
+	// TraceID returns the lower 64 bits of the span's trace id.
+	TraceID() uint64
+	// ParentID returns the identifier of the span's parent, zero for a root span.
+	ParentID() uint64
+
+	// OperationName returns the operation name of the span.
+	OperationName() string
+	// SetOperationName sets the operation name of the span.
+	SetOperationName(name string)
+	// Service returns the service name of the span.
+	Service() string
+	// Resource returns the resource name of the span.
+	Resource() string
+	// SetResource sets the resource name of the span.
+	SetResource(resource string)
+	// SpanType returns the type of the span, such as "web" or "db".
+	SpanType() string
+	// IsError reports whether the span is marked as errored.
+	IsError() bool
+
+	// Tag returns the value of the string tag key, if set.
+	Tag(key string) (string, bool)
+	// SetTag sets the string tag key. The ext.ServiceName, ext.ResourceName,
+	// ext.SpanName and ext.SpanType keys set the matching span fields.
+	SetTag(key, value string)
+	// Metric returns the value of the numeric tag key, if set.
+	Metric(key string) (float64, bool)
+	// SetMetric sets the numeric tag key.
+	SetMetric(key string, value float64)
+	// DeleteTag removes the string or numeric tag key.
+	DeleteTag(key string)
+	// Tags returns a copy of the string tags of the span.
+	Tags() map[string]string
+}
+
+// processedSpan implements ProcessedSpan. Spans are accessed without locking:
+// processors are called either before the span is returned to the caller or
+// after it has finished, when it can no longer be modified through the public
+// API.
+type processedSpan struct {
+	s *span
+}
+
+var _ ProcessedSpan = (*processedSpan)(nil)
+
+func (p *processedSpan) SpanID() uint64                  { return p.s.SpanID }
+func (p *processedSpan) TraceID() uint64                 { return p.s.TraceID }
+func (p *processedSpan) ParentID() uint64                { return p.s.ParentID }
+func (p *processedSpan) OperationName() string           { return p.s.Name }
+func (p *processedSpan) SetOperationName(name string)    { p.s.Name = name }
+func (p *processedSpan) Service() string                 { return p.s.Service }
+func (p *processedSpan) Resource() string                { return p.s.Resource }
+func (p *processedSpan) SetResource(resource string)     { p.s.Resource = resource }
+func (p *processedSpan) SpanType() string                { return p.s.Type }
+func (p *processedSpan) IsError() bool                   { return p.s.Error != 0 }
+func (p *processedSpan) SetTag(key, value string)        { p.s.setMeta(key, value) }
+func (p *processedSpan) SetMetric(key string, v float64) { p.s.setMetric(key, v) }
+
+func (p *processedSpan) Tag(key string) (string, bool) {
+	v, ok := p.s.Meta[key]
+	return v, ok
+}
+
+func (p *processedSpan) Metric(key string) (float64, bool) {
+	v, ok := p.s.Metrics[key]
+	return v, ok
+}
+
+func (p *processedSpan) DeleteTag(key string) {
+	delete(p.s.Meta, key)
+	delete(p.s.Metrics, key)
+}
+
+func (p *processedSpan) Tags() map[string]string {
+	tags := make(map[string]string, len(p.s.Meta))
+	for k, v := range p.s.Meta {
+		tags[k] = v
+	}
+	return tags
+}
+
+// processStart runs the OnStart hook of the span processors on the new span s.
+func processStart(processors []SpanProcessor, s *span) {
+	ps := &processedSpan{s: s}
+	for _, p := range processors {
+		p.OnStart(ps)
+	}
+}
+
+// processChunk runs the OnFinish hook of the span processors on the finished
+// spans of a chunk, returning the spans which are kept.
+func processChunk(processors []SpanProcessor, spans []*span) []*span {
+	pspans := make([]ProcessedSpan, len(spans))
+	for i, s := range spans {
+		pspans[i] = &processedSpan{s: s}
+	}
+	for _, p := range processors {
+		pspans = p.OnFinish(pspans)
+		if len(pspans) == 0 {
+			return nil
+		}
+	}
+	kept := make([]*span, 0, len(pspans))
+	seen := make(map[*span]bool, len(pspans))
+	for _, ps := range pspans {
+		// spans which are not from the chunk, or returned more than
+		// once, are ignored
+		if ps, ok := ps.(*processedSpan); ok && !seen[ps.s] {
+			seen[ps.s] = true
+			kept = append(kept, ps.s)
+		}
+	}
+	return kept
+}
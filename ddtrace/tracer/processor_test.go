@@ -0,0 +1,234 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// funcProcessor is a SpanProcessor calling the given functions, if set.
+type funcProcessor struct {
+	onStart  func(ProcessedSpan)
+	onFinish func([]ProcessedSpan) []ProcessedSpan
+
+	mu     sync.Mutex
+	chunks [][]string
+}
+
+func (p *funcProcessor) OnStart(s ProcessedSpan) {
+	if p.onStart != nil {
+		p.onStart(s)
+	}
+}
+
+func (p *funcProcessor) OnFinish(spans []ProcessedSpan) []ProcessedSpan {
+	p.mu.Lock()
+	names := make([]string, len(spans))
+	for i, s := range spans {
+		names[i] = s.OperationName()
+	}
+	p.chunks = append(p.chunks, names)
+	p.mu.Unlock()
+	if p.onFinish != nil {
+		return p.onFinish(spans)
+	}
+	return spans
+}
+
+// dropNamed returns an OnFinish function dropping the spans named name.
+func dropNamed(name string) func([]ProcessedSpan) []ProcessedSpan {
+	return func(spans []ProcessedSpan) []ProcessedSpan {
+		var kept []ProcessedSpan
+		for _, s := range spans {
+			if s.OperationName() != name {
+				kept = append(kept, s)
+			}
+		}
+		return kept
+	}
+}
+
+func TestSpanProcessor(t *testing.T) {
+	t.Run("mutate", func(t *testing.T) {
+		assert := assert.New(t)
+		email := regexp.MustCompile(`[^@\s]+@[^@\s]+`)
+		p := &funcProcessor{
+			onStart: func(s ProcessedSpan) {
+				s.SetTag("team", "core")
+			},
+			onFinish: func(spans []ProcessedSpan) []ProcessedSpan {
+				for _, s := range spans {
+					for k, v := range s.Tags() {
+						if email.MatchString(v) {
+							s.SetTag(k, email.ReplaceAllString(v, "?"))
+						}
+					}
+					s.SetResource("redacted")
+					s.DeleteTag("secret")
+					s.SetMetric("processed", 1)
+				}
+				return spans
+			},
+		}
+		tracer, transport, flush, stop := startTestTracer(t, WithSpanProcessor(p))
+		defer stop()
+
+		root := tracer.StartSpan("web.request", ResourceName("GET /users/bob@example.com"))
+		root.SetTag("user", "bob@example.com")
+		root.SetTag("secret", "s3cr3t")
+		root.Finish()
+		flush(1)
+
+		traces := transport.Traces()
+		require.Len(t, traces, 1)
+		s := traces[0][0]
+		assert.Equal("core", s.Meta["team"])
+		assert.Equal("?", s.Meta["user"])
+		assert.Equal("redacted", s.Resource)
+		assert.NotContains(s.Meta, "secret")
+		assert.Equal(1.0, s.Metrics["processed"])
+	})
+
+	t.Run("drop-span", func(t *testing.T) {
+		assert := assert.New(t)
+		p := &funcProcessor{onFinish: dropNamed("child")}
+		tracer, transport, flush, stop := startTestTracer(t, WithSpanProcessor(p))
+		defer stop()
+
+		root := tracer.StartSpan("root")
+		tracer.StartSpan("child", ChildOf(root.Context())).Finish()
+		root.Finish()
+		flush(1)
+
+		traces := transport.Traces()
+		require.Len(t, traces, 1)
+		require.Len(t, traces[0], 1)
+		assert.Equal("root", traces[0][0].Name)
+	})
+
+	t.Run("drop-first", func(t *testing.T) {
+		assert := assert.New(t)
+		p := &funcProcessor{onFinish: dropNamed("root")}
+		tracer, transport, flush, stop := startTestTracer(t, WithSpanProcessor(p))
+		defer stop()
+
+		root := tracer.StartSpan("root")
+		tracer.StartSpan("child", ChildOf(root.Context())).Finish()
+		root.Finish()
+		flush(1)
+
+		traces := transport.Traces()
+		require.Len(t, traces, 1)
+		require.Len(t, traces[0], 1)
+		child := traces[0][0]
+		assert.Equal("child", child.Name)
+		assert.Contains(child.Metrics, keySamplingPriority)
+		assert.Contains(child.Meta, keyDecisionMaker)
+	})
+
+	t.Run("drop-duplicate", func(t *testing.T) {
+		p := &funcProcessor{onFinish: func(spans []ProcessedSpan) []ProcessedSpan {
+			// drops the child and returns the root twice
+			var root ProcessedSpan
+			for _, s := range spans {
+				if s.OperationName() == "root" {
+					root = s
+				}
+			}
+			return []ProcessedSpan{root, root}
+		}}
+		tracer, transport, flush, stop := startTestTracer(t, WithSpanProcessor(p))
+		defer stop()
+
+		root := tracer.StartSpan("root")
+		tracer.StartSpan("child", ChildOf(root.Context())).Finish()
+		root.Finish()
+		flush(1)
+
+		traces := transport.Traces()
+		require.Len(t, traces, 1)
+		require.Len(t, traces[0], 1)
+		assert.Equal(t, "root", traces[0][0].Name)
+	})
+
+	t.Run("drop-chunk", func(t *testing.T) {
+		p := &funcProcessor{onFinish: func([]ProcessedSpan) []ProcessedSpan { return nil }}
+		tracer, transport, flush, stop := startTestTracer(t, WithSpanProcessor(p))
+		defer stop()
+
+		tracer.StartSpan("dropped").Finish()
+		tracer.StartSpan("dropped").Finish()
+		flush(-1)
+		assert.Empty(t, transport.Traces())
+		assert.Len(t, p.chunks, 2)
+	})
+
+	t.Run("partial-flush", func(t *testing.T) {
+		p := &funcProcessor{}
+		tracer, _, flush, stop := startTestTracer(t, WithSpanProcessor(p), WithPartialFlushing(2))
+		defer stop()
+
+		root := tracer.StartSpan("root")
+		tracer.StartSpan("a", ChildOf(root.Context())).Finish()
+		tracer.StartSpan("b", ChildOf(root.Context())).Finish()
+		root.Finish()
+		flush(2)
+		assert.Equal(t, [][]string{{"a", "b"}, {"root"}}, p.chunks)
+	})
+
+	t.Run("start-span", func(t *testing.T) {
+		p := &funcProcessor{}
+		tracer, transport, flush, stop := startTestTracer(t, WithSpanProcessor(p), WithPartialFlushing(1))
+		defer stop()
+
+		root := tracer.StartSpan("root")
+		p.onFinish = func(spans []ProcessedSpan) []ProcessedSpan {
+			if spans[0].OperationName() == "a" {
+				// the trace of the chunk is unlocked while processors run
+				tracer.StartSpan("audit", ChildOf(root.Context())).Finish()
+			}
+			return spans
+		}
+		done := make(chan struct{})
+		go func() {
+			tracer.StartSpan("a", ChildOf(root.Context())).Finish()
+			root.Finish()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("deadlock finishing the span")
+		}
+		flush(3)
+		assert.Equal(t, [][]string{{"a"}, {"audit"}, {"root"}}, p.chunks)
+		assert.Len(t, transport.Traces(), 3)
+	})
+
+	t.Run("order", func(t *testing.T) {
+		var calls []string
+		first := &funcProcessor{onStart: func(s ProcessedSpan) {
+			calls = append(calls, "first")
+			s.SetTag(ext.ServiceName, "renamed")
+		}}
+		second := &funcProcessor{onStart: func(s ProcessedSpan) {
+			calls = append(calls, "second:"+s.Service())
+		}}
+		tracer, _, _, stop := startTestTracer(t, WithSpanProcessor(first), WithSpanProcessor(second))
+		defer stop()
+
+		tracer.StartSpan("span").Finish()
+		assert.Equal(t, []string{"first", "second:renamed"}, calls)
+	})
+}
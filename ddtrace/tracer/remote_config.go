@@ -318,6 +318,7 @@ func (t *tracer) startRemoteConfig(rcConfig remoteconfig.ClientConfig) error {
 	if err != nil {
 		return err
 	}
+	t.startedRemoteConfig = true
 
 	var dynamicInstrumentationError, apmTracingError error
 
@@ -11,9 +11,11 @@ import (
 	"math"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -31,6 +33,10 @@ type rulesSampler struct {
 	// singleSpanRulesSampler samples individual spans based on a separate user-defined set of rules and
 	// cannot impact the trace sampling decision.
 	spans *singleSpanRulesSampler
+
+	// adaptive samples the traces matching no rule with rates adapted to the throughput of
+	// their service and resource. It is nil unless adaptive sampling is enabled.
+	adaptive *adaptiveSampler
 }
 
 // newRulesSampler configures a *rulesSampler instance using the given set of rules.
@@ -48,6 +54,17 @@ func (r *rulesSampler) SampleTrace(s *span) bool { return r.traces.sampleRules(s
 
 func (r *rulesSampler) SampleTraceGlobalRate(s *span) bool { return r.traces.sampleGlobalRate(s) }
 
+func (r *rulesSampler) SampleTraceAdaptive(s *span) bool {
+	return r.adaptive != nil && r.adaptive.sample(s, nowTime())
+}
+
+// ResampleTraceAdaptive takes the adaptive sampling decision of the trace of s
+// again, if the adaptive sampler took it, as the service or resource of s may
+// have changed since.
+func (r *rulesSampler) ResampleTraceAdaptive(s *span) bool {
+	return r.adaptive != nil && r.adaptive.resample(s, nowTime())
+}
+
 func (r *rulesSampler) SampleSpan(s *span) bool { return r.spans.apply(s) }
 
 func (r *rulesSampler) HasSpanRules() bool { return r.spans.enabled() }
@@ -60,9 +77,11 @@ const (
 	Local    provenance = iota
 	Customer provenance = 1
 	Dynamic  provenance = 2
+	// LocalFile marks the rules read from the file set in DD_TRACE_SAMPLING_RULES_FILE.
+	LocalFile provenance = 3
 )
 
-var provenances = []provenance{Local, Customer, Dynamic}
+var provenances = []provenance{Local, Customer, Dynamic, LocalFile}
 
 func (p provenance) String() string {
 	switch p {
@@ -72,6 +91,8 @@ func (p provenance) String() string {
 		return "customer"
 	case Dynamic:
 		return "dynamic"
+	case LocalFile:
+		return "local_file"
 	default:
 		return ""
 	}
@@ -425,8 +446,12 @@ func (rs *traceRulesSampler) setGlobalSampleRate(rate float64) bool {
 	return true
 }
 
-// Assumes the new rules are different from the old rules.
+// setTraceSampleRules replaces the trace sampling rules with the given rules.
+// Spans being sampled concurrently are sampled with either the old or the new
+// set of rules. Returns whether the rules were changed or not.
 func (rs *traceRulesSampler) setTraceSampleRules(rules []SamplingRule) bool {
+	rs.m.Lock()
+	defer rs.m.Unlock()
 	if EqualsFalseNegative(rs.rules, rules) {
 		return false
 	}
@@ -473,9 +498,10 @@ func (rs *traceRulesSampler) sampleRules(span *span) bool {
 	var matched bool
 	rs.m.RLock()
 	rate := rs.globalRate
+	rules := rs.rules
 	rs.m.RUnlock()
 	sampler := samplernames.RuleRate
-	for _, rule := range rs.rules {
+	for _, rule := range rules {
 		if rule.match(span) {
 			matched = true
 			rate = rule.Rate
@@ -699,7 +725,7 @@ func samplingRulesFromEnv() (trace, span []SamplingRule, err error) {
 		if err != nil {
 			errs = append(errs, fmt.Sprintf("Couldn't read file from %s_FILE: %v", env, err))
 		}
-		rules, err = unmarshalSamplingRules(rulesFromEnvFile, spanType)
+		rules, err = unmarshalSamplingRulesFile(rulesFromEnvFile, spanType)
 		if err != nil {
 			errs = append(errs, err.Error())
 		}
@@ -788,6 +814,16 @@ func unmarshalSamplingRules(b []byte, spanType SamplingRuleType) ([]SamplingRule
 	return validateRules(jsonRules, spanType)
 }
 
+// unmarshalSamplingRulesFile unmarshals the content b of a sampling rules file like
+// unmarshalSamplingRules, marking the rules with the LocalFile provenance.
+func unmarshalSamplingRulesFile(b []byte, spanType SamplingRuleType) ([]SamplingRule, error) {
+	rules, err := unmarshalSamplingRules(b, spanType)
+	for i := range rules {
+		rules[i].Provenance = LocalFile
+	}
+	return rules, err
+}
+
 func validateRules(jsonRules []jsonRule, spanType SamplingRuleType) ([]SamplingRule, error) {
 	var errs []string
 	rules := make([]SamplingRule, 0, len(jsonRules))
@@ -883,3 +919,210 @@ func (sr SamplingRule) String() string {
 	}
 	return string(s)
 }
+
+const (
+	// adaptiveSamplingInterval is the interval at which the adaptive sampler
+	// recomputes the rates of its keys.
+	adaptiveSamplingInterval = 10 * time.Second
+
+	// adaptiveSamplingMaxKeys is the maximum number of (service, resource)
+	// keys tracked by the adaptive sampler. Traces of further keys share the
+	// rate of a single overflow key.
+	adaptiveSamplingMaxKeys = 1000
+)
+
+// adaptiveKey identifies the traces whose throughput the adaptive sampler
+// tracks, by the service and resource of their root span.
+type adaptiveKey struct {
+	service, resource string
+}
+
+// adaptiveOverflowKey is the key shared by the traces of the keys exceeding
+// adaptiveSamplingMaxKeys.
+var adaptiveOverflowKey = adaptiveKey{service: "*", resource: "*"}
+
+// adaptiveEntry holds the throughput and rate of an adaptiveKey.
+type adaptiveEntry struct {
+	seen       atomic.Uint64 // number of traces seen since the last recomputation
+	rate       atomic.Uint64 // math.Float64bits of the sampling rate
+	throughput float64       // smoothed throughput in traces per second, guarded by adaptiveSampler.mu
+	idle       int           // number of idle intervals, guarded by adaptiveSampler.mu
+}
+
+// adaptiveSampler samples traces so that the sampled traces fit within a
+// budget, in traces per second, shared fairly between the (service, resource)
+// pairs of their root spans. Every interval, the throughput of each key is
+// measured and the budget is split between the keys: low-throughput keys are
+// kept entirely when possible, and the remainder is shared equally between the
+// others. Each key is guaranteed a minimum of minPerKey traces per second.
+type adaptiveSampler struct {
+	budget    float64
+	minPerKey float64
+	interval  time.Duration
+
+	mu      sync.RWMutex
+	entries map[adaptiveKey]*adaptiveEntry
+	last    time.Time // time of the last recomputation
+}
+
+func newAdaptiveSampler(budget, minPerKey float64) *adaptiveSampler {
+	return &adaptiveSampler{
+		budget:    budget,
+		minPerKey: minPerKey,
+		interval:  adaptiveSamplingInterval,
+		entries:   make(map[adaptiveKey]*adaptiveEntry),
+		last:      nowTime(),
+	}
+}
+
+// sample applies the rate of the key of span to it. It always takes a decision.
+func (as *adaptiveSampler) sample(span *span, now time.Time) bool {
+	e := as.entry(adaptiveKey{service: span.Service, resource: span.Resource})
+	e.seen.Add(1)
+	if span.context != nil && span.context.trace != nil {
+		span.context.trace.setAdaptiveEntry(e)
+	}
+	return as.apply(span, e, now)
+}
+
+// resample takes the decision of span again if it was taken by sample and the
+// key of span has changed since. The trace is then only counted in the
+// throughput of its new key. It reports whether a decision was taken.
+func (as *adaptiveSampler) resample(span *span, now time.Time) bool {
+	if span.context == nil || span.context.trace == nil {
+		return false
+	}
+	prev := span.context.trace.adaptiveEntry()
+	if prev == nil {
+		// the trace wasn't sampled by the adaptive sampler
+		return false
+	}
+	span.RLock()
+	k := adaptiveKey{service: span.Service, resource: span.Resource}
+	span.RUnlock()
+	e := as.entry(k)
+	if e == prev {
+		return true
+	}
+	// the counter may have been reset by a recomputation since
+	for n := prev.seen.Load(); n > 0 && !prev.seen.CompareAndSwap(n, n-1); n = prev.seen.Load() {
+	}
+	e.seen.Add(1)
+	span.context.trace.setAdaptiveEntry(e)
+	return as.apply(span, e, now)
+}
+
+// apply applies the rate of e to span, recomputing the rates first if due.
+func (as *adaptiveSampler) apply(span *span, e *adaptiveEntry, now time.Time) bool {
+	as.mu.RLock()
+	due := now.Sub(as.last) >= as.interval
+	as.mu.RUnlock()
+	if due {
+		as.recompute(now)
+	}
+	rate := math.Float64frombits(e.rate.Load())
+
+	span.Lock()
+	defer span.Unlock()
+	delete(span.Metrics, keySamplingPriorityRate)
+	span.setMetric(keyAdaptiveSamplerRate, rate)
+	if sampledByRate(span.TraceID, rate) {
+		span.setSamplingPriorityLocked(ext.PriorityAutoKeep, samplernames.Adaptive)
+	} else {
+		span.setSamplingPriorityLocked(ext.PriorityAutoReject, samplernames.Adaptive)
+	}
+	return true
+}
+
+// entry returns the entry of key k, creating it if needed. New keys are kept
+// entirely until the next recomputation.
+func (as *adaptiveSampler) entry(k adaptiveKey) *adaptiveEntry {
+	as.mu.RLock()
+	e, ok := as.entries[k]
+	as.mu.RUnlock()
+	if ok {
+		return e
+	}
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if e, ok := as.entries[k]; ok {
+		return e
+	}
+	if len(as.entries) >= adaptiveSamplingMaxKeys {
+		k = adaptiveOverflowKey
+		if e, ok := as.entries[k]; ok {
+			return e
+		}
+	}
+	e = &adaptiveEntry{}
+	e.rate.Store(math.Float64bits(1))
+	as.entries[k] = e
+	return e
+}
+
+// recompute updates the throughput of every key and splits the budget between
+// them. Keys idle for several intervals are forgotten.
+func (as *adaptiveSampler) recompute(now time.Time) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	elapsed := now.Sub(as.last).Seconds()
+	if elapsed < as.interval.Seconds() {
+		// already recomputed by another goroutine
+		return
+	}
+	as.last = now
+	active := make([]*adaptiveEntry, 0, len(as.entries))
+	for k, e := range as.entries {
+		seen := float64(e.seen.Swap(0))
+		if seen == 0 {
+			if e.idle++; e.idle >= 3 {
+				delete(as.entries, k)
+				continue
+			}
+		} else {
+			e.idle = 0
+		}
+		cur := seen / elapsed
+		if e.throughput == 0 {
+			e.throughput = cur
+		} else {
+			e.throughput = (e.throughput + cur) / 2
+		}
+		if e.throughput > 0 {
+			active = append(active, e)
+		}
+	}
+	allocs := adaptiveAllocate(as.budget, as.minPerKey, active)
+	for i, e := range active {
+		e.rate.Store(math.Float64bits(math.Min(1, allocs[i]/e.throughput)))
+	}
+}
+
+// adaptiveAllocate splits budget between entries according to their
+// throughput, returning the number of traces per second allocated to each.
+// Each entry first gets up to minPerKey, then the remainder is shared using
+// max-min fairness: entries needing less than an equal share get all they
+// need, and the others share what is left equally.
+func adaptiveAllocate(budget, minPerKey float64, entries []*adaptiveEntry) []float64 {
+	allocs := make([]float64, len(entries))
+	order := make([]int, len(entries))
+	remaining := budget
+	for i, e := range entries {
+		allocs[i] = math.Min(e.throughput, minPerKey)
+		remaining -= allocs[i]
+		order[i] = i
+	}
+	if remaining <= 0 {
+		return allocs
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return entries[order[a]].throughput-allocs[order[a]] < entries[order[b]].throughput-allocs[order[b]]
+	})
+	for n, i := range order {
+		share := remaining / float64(len(order)-n)
+		extra := math.Min(entries[i].throughput-allocs[i], share)
+		allocs[i] += extra
+		remaining -= extra
+	}
+	return allocs
+}
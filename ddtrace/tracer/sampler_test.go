@@ -1721,3 +1721,113 @@ func TestSampleTagsRootOnly(t *testing.T) {
 		assert.NotContains(child.(*span).Metrics, keyRulesSamplerLimiterRate)
 	})
 }
+
+func TestAdaptiveSampler(t *testing.T) {
+	t.Run("allocate", func(t *testing.T) {
+		entries := func(throughputs ...float64) []*adaptiveEntry {
+			es := make([]*adaptiveEntry, len(throughputs))
+			for i, tp := range throughputs {
+				es[i] = &adaptiveEntry{throughput: tp}
+			}
+			return es
+		}
+		for _, tc := range []struct {
+			budget, min float64
+			in, want    []float64
+		}{
+			{budget: 100, min: 1, in: []float64{10, 20}, want: []float64{10, 20}},
+			{budget: 100, min: 1, in: []float64{1000, 10, 1000}, want: []float64{45, 10, 45}},
+			{budget: 10, min: 1, in: []float64{1000, 0.5, 1000}, want: []float64{4.75, 0.5, 4.75}},
+			{budget: 2, min: 1, in: []float64{100, 100, 100}, want: []float64{1, 1, 1}},
+		} {
+			t.Run("", func(t *testing.T) {
+				assert.InDeltaSlice(t, tc.want, adaptiveAllocate(tc.budget, tc.min, entries(tc.in...)), 1e-9)
+			})
+		}
+	})
+
+	t.Run("recompute", func(t *testing.T) {
+		assert := assert.New(t)
+		as := newAdaptiveSampler(20, 1)
+		start := as.last
+		hot := newSpan("http.request", "web", "GET /health", 1, 1, 0)
+		admin := newSpan("http.request", "web", "POST /admin", 2, 2, 0)
+		for i := 0; i < 1000; i++ {
+			as.sample(hot, start)
+		}
+		for i := 0; i < 10; i++ {
+			as.sample(admin, start)
+		}
+		as.sample(hot, start.Add(adaptiveSamplingInterval))
+
+		rate := func(s *span) float64 {
+			e := as.entries[adaptiveKey{service: s.Service, resource: s.Resource}]
+			return math.Float64frombits(e.rate.Load())
+		}
+		assert.InDelta(19./100, rate(hot), 1e-3)
+		assert.Equal(1., rate(admin))
+		assert.InDelta(19./100, hot.Metrics[keyAdaptiveSamplerRate], 1e-3)
+
+		// idle keys are forgotten
+		for i := 2; i <= 4; i++ {
+			as.sample(hot, start.Add(time.Duration(i)*adaptiveSamplingInterval))
+		}
+		assert.Len(as.entries, 1)
+	})
+
+	t.Run("tracer", func(t *testing.T) {
+		assert := assert.New(t)
+		tr, _, _, stop := startTestTracer(t, WithAdaptiveSampling(10, 1))
+		defer stop()
+
+		s := tr.StartSpan("http.request", ResourceName("GET /")).(*span)
+		assert.Equal(1., s.Metrics[keyAdaptiveSamplerRate])
+		assert.Equal(float64(ext.PriorityAutoKeep), s.Metrics[keySamplingPriority])
+		assert.Equal("-14", s.context.trace.propagatingTags[keyDecisionMaker])
+
+		// sampling rules take precedence
+		tr, _, _, stop = startTestTracer(t, WithAdaptiveSampling(10, 1), WithSamplingRules([]SamplingRule{RateRule(0)}))
+		defer stop()
+		s = tr.StartSpan("http.request").(*span)
+		assert.NotContains(s.Metrics, keyAdaptiveSamplerRate)
+	})
+
+	t.Run("resource-after-start", func(t *testing.T) {
+		assert := assert.New(t)
+		tr, _, _, stop := startTestTracer(t, WithAdaptiveSampling(10, 1))
+		defer stop()
+		as := tr.rulesSampling.adaptive
+		health := as.entry(adaptiveKey{service: "web", resource: "GET /health"})
+		health.rate.Store(math.Float64bits(0))
+
+		// re-evaluated on injection
+		s := tr.StartSpan("http.request", ServiceName("web")).(*span)
+		assert.Equal(1., s.Metrics[keyAdaptiveSamplerRate])
+		assert.Equal(float64(ext.PriorityAutoKeep), s.Metrics[keySamplingPriority])
+		s.SetTag(ext.ResourceName, "GET /health")
+		assert.NoError(tr.Inject(s.Context(), TextMapCarrier(map[string]string{})))
+		assert.Equal(0., s.Metrics[keyAdaptiveSamplerRate])
+		assert.Equal(float64(ext.PriorityAutoReject), s.Metrics[keySamplingPriority])
+		assert.Equal(uint64(1), health.seen.Load())
+		assert.Zero(as.entry(adaptiveKey{service: "web", resource: "http.request"}).seen.Load())
+		s.Finish()
+		assert.Equal(uint64(1), health.seen.Load())
+
+		// re-evaluated when the root span finishes
+		s = tr.StartSpan("http.request", ServiceName("web")).(*span)
+		assert.Equal(float64(ext.PriorityAutoKeep), s.Metrics[keySamplingPriority])
+		s.SetTag(ext.ResourceName, "GET /health")
+		s.Finish()
+		assert.Equal(float64(ext.PriorityAutoReject), s.Metrics[keySamplingPriority])
+		assert.Equal(uint64(2), health.seen.Load())
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("DD_TRACE_ADAPTIVE_SAMPLING_BUDGET", "50")
+		t.Setenv("DD_TRACE_ADAPTIVE_SAMPLING_MIN_PER_KEY", "2")
+		tr := newUnstartedTracer()
+		defer tr.statsd.Close()
+		assert.Equal(t, 50., tr.rulesSampling.adaptive.budget)
+		assert.Equal(t, 2., tr.rulesSampling.adaptive.minPerKey)
+	})
+}
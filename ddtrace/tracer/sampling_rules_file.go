@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"os"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
+)
+
+// samplingRulesFilePollInterval is the interval at which the file set in
+// DD_TRACE_SAMPLING_RULES_FILE is checked for changes.
+const samplingRulesFilePollInterval = 5 * time.Second
+
+// samplingRulesFileWatcher reloads the trace sampling rules from a file
+// whenever it changes.
+type samplingRulesFileWatcher struct {
+	path  string
+	rules *dynamicConfig[[]SamplingRule]
+
+	// modTime and size identify the last version of the file which was read.
+	modTime time.Time
+	size    int64
+}
+
+func newSamplingRulesFileWatcher(path string, rules *dynamicConfig[[]SamplingRule]) *samplingRulesFileWatcher {
+	w := &samplingRulesFileWatcher{path: path, rules: rules}
+	// the rules were read from the file at startup
+	if fi, err := os.Stat(path); err == nil {
+		w.modTime, w.size = fi.ModTime(), fi.Size()
+	}
+	return w
+}
+
+// run checks the file for changes at every interval, until stop is closed.
+func (w *samplingRulesFileWatcher) run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.reload()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// reload re-reads the rules if the file changed since it was last read. The
+// rules are replaced all at once, and only if the whole file is valid:
+// otherwise the current rules are kept. It reports whether the rules changed.
+func (w *samplingRulesFileWatcher) reload() bool {
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		// the file may be temporarily missing while it is being replaced
+		log.Debug("Couldn't check the sampling rules file %s: %v", w.path, err)
+		return false
+	}
+	if fi.ModTime().Equal(w.modTime) && fi.Size() == w.size {
+		return false
+	}
+	w.modTime, w.size = fi.ModTime(), fi.Size()
+	b, err := os.ReadFile(w.path)
+	if err != nil {
+		log.Warn("Couldn't read the sampling rules file %s: %v", w.path, err)
+		return false
+	}
+	rules, err := unmarshalSamplingRulesFile(b, SamplingRuleTrace)
+	if err != nil {
+		log.Warn("Ignoring the updated sampling rules file %s: %v", w.path, err)
+		return false
+	}
+	if !w.rules.updateStartup(rules, telemetry.OriginLocalFile) {
+		return false
+	}
+	log.Info("Reloaded %d trace sampling rules from %s", len(rules), w.path)
+	telemetry.RegisterAppConfigs(w.rules.toTelemetry())
+	return true
+}
@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
+)
+
+func TestSamplingRulesFile(t *testing.T) {
+	// writeRules writes rules to path, changing its modification time so that
+	// the change is detected even within the resolution of the filesystem.
+	writeRules := func(t *testing.T, path, rules string) {
+		require.NoError(t, os.WriteFile(path, []byte(rules), 0600))
+		mtime := time.Now().Add(time.Duration(len(rules)) * time.Second)
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+
+	t.Run("reload", func(t *testing.T) {
+		assert := assert.New(t)
+		path := filepath.Join(t.TempDir(), "rules.json")
+		writeRules(t, path, `[{"service": "web", "sample_rate": 1}]`)
+		t.Setenv("DD_TRACE_SAMPLING_RULES_FILE", path)
+
+		tracer := newUnstartedTracer()
+		defer tracer.statsd.Close()
+		assert.Equal(path, tracer.config.traceRulesFile)
+		require.Len(t, tracer.config.traceRules, 1)
+		assert.Equal(LocalFile, tracer.config.traceRules[0].Provenance)
+		assert.Equal(telemetry.OriginLocalFile, tracer.config.traceSampleRules.toTelemetry().Origin)
+
+		w := newSamplingRulesFileWatcher(path, &tracer.config.traceSampleRules)
+		assert.False(w.reload())
+
+		writeRules(t, path, `[{"service": "web", "sample_rate": 0}, {"service": "db", "sample_rate": 0.5}]`)
+		assert.True(w.reload())
+		rules := tracer.rulesSampling.traces.rules
+		require.Len(t, rules, 2)
+		assert.Equal(0., rules[0].Rate)
+		assert.Equal(LocalFile, rules[1].Provenance)
+		assert.Equal(telemetry.OriginLocalFile, tracer.config.traceSampleRules.toTelemetry().Origin)
+
+		s := newSpan("http.request", "web", "/", 1, 1, 0)
+		assert.True(tracer.rulesSampling.SampleTrace(s))
+		assert.Equal(float64(ext.PriorityUserReject), s.Metrics[keySamplingPriority])
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		writeRules(t, path, `[{"service": "web", "sample_rate": 0.5}]`)
+		rs := newTraceRulesSampler(nil, 1, defaultRateLimit)
+		dc := newDynamicConfig("trace_sample_rules", nil, rs.setTraceSampleRules, EqualsFalseNegative)
+		w := newSamplingRulesFileWatcher(path, &dc)
+		w.modTime = time.Time{}
+		require.True(t, w.reload())
+
+		// the whole file is rejected if any rule is invalid
+		writeRules(t, path, `[{"service": "web", "sample_rate": 0}, {"service": "db", "sample_rate": 2}]`)
+		assert.False(t, w.reload())
+		writeRules(t, path, `[{`)
+		assert.False(t, w.reload())
+		require.NoError(t, os.Remove(path))
+		assert.False(t, w.reload())
+		require.Len(t, rs.rules, 1)
+		assert.Equal(t, 0.5, rs.rules[0].Rate)
+	})
+
+	t.Run("remote-config", func(t *testing.T) {
+		assert := assert.New(t)
+		path := filepath.Join(t.TempDir(), "rules.json")
+		writeRules(t, path, `[{"service": "web", "sample_rate": 0.5}]`)
+		rs := newTraceRulesSampler(nil, 1, defaultRateLimit)
+		dc := newDynamicConfig("trace_sample_rules", nil, rs.setTraceSampleRules, EqualsFalseNegative)
+		w := newSamplingRulesFileWatcher(path, &dc)
+
+		remote := []SamplingRule{ServiceRule("web", 1)}
+		remote[0].Provenance = Customer
+		assert.True(dc.handleRC(&remote))
+
+		// remote config takes precedence over the reloaded rules, until it is removed
+		writeRules(t, path, `[{"service": "web", "sample_rate": 0.1}]`)
+		assert.False(w.reload())
+		assert.Equal(Customer, rs.rules[0].Provenance)
+		assert.True(dc.handleRC(nil))
+		assert.Equal(0.1, rs.rules[0].Rate)
+		assert.Equal(LocalFile, rs.rules[0].Provenance)
+	})
+
+	t.Run("precedence", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		writeRules(t, path, `[{"service": "web", "sample_rate": 1}]`)
+		t.Setenv("DD_TRACE_SAMPLING_RULES_FILE", path)
+		t.Setenv("DD_TRACE_SAMPLING_RULES", `[{"service": "web", "sample_rate": 0}]`)
+
+		tracer := newUnstartedTracer()
+		defer tracer.statsd.Close()
+		assert.Empty(t, tracer.config.traceRulesFile)
+		assert.Equal(t, Local, tracer.config.traceRules[0].Provenance)
+	})
+}
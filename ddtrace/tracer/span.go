@@ -10,6 +10,7 @@ package tracer
 import (
 	"context"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -80,6 +81,7 @@ type span struct {
 	ParentID   uint64             `msg:"parent_id"`             // identifier of the span's direct parent
 	Error      int32              `msg:"error"`                 // error status of the span; 0 means no errors
 	SpanLinks  []ddtrace.SpanLink `msg:"span_links,omitempty"`  // links to other spans
+	SpanEvents []spanEvent        `msg:"span_events,omitempty"` // events which occurred during the span
 
 	goExecTraced bool         `msg:"-"`
 	noDebugStack bool         `msg:"-"` // disables debug stack traces
@@ -90,7 +92,8 @@ type span struct {
 	pprofCtxActive  context.Context `msg:"-"` // contains pprof.WithLabel labels to tell the profiler more about this span
 	pprofCtxRestore context.Context `msg:"-"` // contains pprof.WithLabel labels of the parent span (if any) that need to be restored when this span finishes
 
-	taskEnd func() // ends execution tracer (runtime/trace) task, if started
+	taskEnd   func() // ends execution tracer (runtime/trace) task, if started
+	watchDone func() // stops watching for slow local root spans, if started
 }
 
 type SpanWithLinks interface {
@@ -100,11 +103,115 @@ type SpanWithLinks interface {
 	AddSpanLink(link ddtrace.SpanLink)
 }
 
+// SpanWithAddLink represents a Span which can be linked to the span of a
+// SpanContext.
+type SpanWithAddLink interface {
+	ddtrace.Span
+
+	// AddLink links the span to the span of ctx, with the given attributes. The
+	// link is built with SpanLinkFromContext. Links added after the span has
+	// finished are ignored.
+	AddLink(ctx ddtrace.SpanContext, attrs map[string]string)
+}
+
 // Context yields the SpanContext for this Span. Note that the return
 // value of Context() is still valid after a call to Finish(). This is
 // called the span context and it is different from Go's context.
 func (s *span) Context() ddtrace.SpanContext { return s.context }
 
+var _ ddtrace.ReadOnlySpan = (*span)(nil)
+
+// OperationName implements ddtrace.ReadOnlySpan.
+func (s *span) OperationName() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.Name
+}
+
+// ServiceName implements ddtrace.ReadOnlySpan.
+func (s *span) ServiceName() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.Service
+}
+
+// ResourceName implements ddtrace.ReadOnlySpan.
+func (s *span) ResourceName() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.Resource
+}
+
+// SpanType implements ddtrace.ReadOnlySpan.
+func (s *span) SpanType() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.Type
+}
+
+// StartTime implements ddtrace.ReadOnlySpan.
+func (s *span) StartTime() time.Time {
+	s.RLock()
+	defer s.RUnlock()
+	return time.Unix(0, s.Start)
+}
+
+// Elapsed implements ddtrace.ReadOnlySpan.
+func (s *span) Elapsed() time.Duration {
+	s.RLock()
+	defer s.RUnlock()
+	return time.Duration(s.Duration)
+}
+
+// StringTags implements ddtrace.ReadOnlySpan.
+func (s *span) StringTags() map[string]string {
+	s.RLock()
+	defer s.RUnlock()
+	meta := make(map[string]string, len(s.Meta))
+	for k, v := range s.Meta {
+		meta[k] = v
+	}
+	return meta
+}
+
+// NumericTags implements ddtrace.ReadOnlySpan.
+func (s *span) NumericTags() map[string]float64 {
+	s.RLock()
+	defer s.RUnlock()
+	metrics := make(map[string]float64, len(s.Metrics))
+	for k, v := range s.Metrics {
+		metrics[k] = v
+	}
+	return metrics
+}
+
+// Links implements ddtrace.ReadOnlySpan.
+func (s *span) Links() []ddtrace.SpanLink {
+	s.RLock()
+	defer s.RUnlock()
+	if len(s.SpanLinks) == 0 {
+		return nil
+	}
+	links := make([]ddtrace.SpanLink, len(s.SpanLinks))
+	for i, l := range s.SpanLinks {
+		links[i] = l
+		if l.Attributes != nil {
+			links[i].Attributes = make(map[string]string, len(l.Attributes))
+			for k, v := range l.Attributes {
+				links[i].Attributes[k] = v
+			}
+		}
+	}
+	return links
+}
+
+// IsError implements ddtrace.ReadOnlySpan.
+func (s *span) IsError() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.Error != 0
+}
+
 // SetBaggageItem sets a key/value pair as baggage on the span. Baggage items
 // are propagated down to descendant spans and injected cross-process. Use with
 // care as it adds extra load onto your tracing layer.
@@ -482,9 +589,68 @@ func (s *span) setMetric(key string, v float64) {
 
 // AddSpanLink appends the given link to the span's span links.
 func (s *span) AddSpanLink(link ddtrace.SpanLink) {
+	s.Lock()
+	defer s.Unlock()
+	s.SpanLinks = append(s.SpanLinks, link)
+}
+
+// AddLink links the span to the span of ctx, with the given attributes, such as
+// in consumers receiving batches of messages from different traces. Unlike
+// WithSpanLinks, it can be used after the span has started.
+func (s *span) AddLink(ctx ddtrace.SpanContext, attrs map[string]string) {
+	if ctx == nil {
+		return
+	}
+	link := SpanLinkFromContext(ctx, attrs)
+	s.Lock()
+	defer s.Unlock()
+	if s.finished {
+		return
+	}
 	s.SpanLinks = append(s.SpanLinks, link)
 }
 
+// SpanLinkFromContext returns a link to the span of ctx, with the given attributes.
+// For the contexts created or extracted by this package, the link also holds the
+// W3C tracestate and, as trace flags, the sampling decision of the linked trace.
+// Other contexts can provide them by implementing the Tracestate() string and
+// SamplingPriority() (int, bool) methods.
+func SpanLinkFromContext(ctx ddtrace.SpanContext, attrs map[string]string) ddtrace.SpanLink {
+	link := ddtrace.SpanLink{
+		TraceID: ctx.TraceID(),
+		SpanID:  ctx.SpanID(),
+	}
+	if w3c, ok := ctx.(ddtrace.SpanContextW3C); ok {
+		id := w3c.TraceID128Bytes()
+		link.TraceIDHigh = binary.BigEndian.Uint64(id[:8])
+	}
+	if len(attrs) > 0 {
+		link.Attributes = make(map[string]string, len(attrs))
+		for k, v := range attrs {
+			link.Attributes[k] = v
+		}
+	}
+	switch c := ctx.(type) {
+	case *spanContext:
+		if c.trace != nil {
+			link.Tracestate = c.trace.propagatingTag(tracestateHeader)
+		}
+	case interface{ Tracestate() string }:
+		link.Tracestate = c.Tracestate()
+	}
+	if c, ok := ctx.(interface{ SamplingPriority() (int, bool) }); ok {
+		if p, ok := c.SamplingPriority(); ok {
+			// The highest bit distinguishes flags which are set from those which
+			// aren't, as no sampling decision is also reported as zero.
+			link.Flags = 1 << 31
+			if p > 0 {
+				link.Flags |= 1
+			}
+		}
+	}
+	return link
+}
+
 // serializeSpanLinksInMeta saves span links as a JSON string under `Span[meta][_dd.span_links]`.
 func (s *span) serializeSpanLinksInMeta() {
 	if len(s.SpanLinks) == 0 {
@@ -543,15 +709,27 @@ func (s *span) Finish(opts ...ddtrace.FinishOption) {
 		s.SetTag("go_execution_traced", "partial")
 	}
 
+	tr, ok := internal.GetGlobalTracer().(*tracer)
 	if s.root() == s {
-		if tr, ok := internal.GetGlobalTracer().(*tracer); ok && tr.rulesSampling.traces.enabled() {
+		if ok && tr.rulesSampling != nil {
 			if !s.context.trace.isLocked() && s.context.trace.propagatingTag(keyDecisionMaker) != "-4" {
-				tr.rulesSampling.SampleTrace(s)
+				if !tr.rulesSampling.traces.enabled() || !tr.rulesSampling.SampleTrace(s) {
+					tr.rulesSampling.ResampleTraceAdaptive(s)
+				}
 			}
 		}
 	}
 
+	if ok && len(tr.config.baggageTagKeys) > 0 {
+		if tr.config.baggageTagsOnAllSpans || s.root() == s {
+			s.setBaggageTags(tr.config.baggageTagKeys)
+		}
+	}
+
 	s.serializeSpanLinksInMeta()
+	if !ok || !tr.config.canSendSpanEvents() {
+		s.serializeSpanEventsInMeta()
+	}
 
 	s.finish(t)
 	orchestrion.GLSPopValue(sharedinternal.ActiveSpanKey)
@@ -590,6 +768,9 @@ func (s *span) finish(finishTime int64) {
 	if s.taskEnd != nil {
 		s.taskEnd()
 	}
+	if s.watchDone != nil {
+		s.watchDone()
+	}
 
 	keep := true
 	if t, ok := internal.GetGlobalTracer().(*tracer); ok {
@@ -597,17 +778,10 @@ func (s *span) finish(finishTime int64) {
 			return
 		}
 		// we have an active tracer
-		if t.config.canComputeStats() {
-			statSpan, shouldCalc := t.stats.newTracerStatSpan(s, t.obfuscator)
-			if shouldCalc {
-				// the agent supports computed stats
-				select {
-				case t.stats.In <- statSpan:
-					// ok
-				default:
-					log.Error("Stats channel full, disregarding span.")
-				}
-			}
+		if t.config.canComputeStats() && len(t.config.spanProcessors) == 0 {
+			// with span processors, stats are computed when the chunk is
+			// finished, from the processed spans
+			t.submitStats(s)
 		}
 		if t.config.canDropP0s() {
 			// the agent supports dropping p0's in the client
@@ -781,7 +955,9 @@ const (
 	keyHostname                = "_dd.hostname"
 	keyRulesSamplerAppliedRate = "_dd.rule_psr"
 	keyRulesSamplerLimiterRate = "_dd.limit_psr"
-	keyMeasured                = "_dd.measured"
+	// keyAdaptiveSamplerRate holds the rate applied by the adaptive sampler.
+	keyAdaptiveSamplerRate = "_dd.adaptive_psr"
+	keyMeasured            = "_dd.measured"
 	// keyTopLevel is the key of top level metric indicating if a span is top level.
 	// A top level span is a local root (parent span of the local trace) or the first span of each service.
 	keyTopLevel = "_dd.top_level"
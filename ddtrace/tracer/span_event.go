@@ -0,0 +1,458 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+// keySpanEvents is the meta key holding the JSON-encoded span events, when the
+// agent can't receive them natively.
+const keySpanEvents = "events"
+
+// Types of the span event attribute values, as expected by the agent.
+const (
+	spanEventAttrString = 0
+	spanEventAttrBool   = 1
+	spanEventAttrInt    = 2
+	spanEventAttrDouble = 3
+	spanEventAttrArray  = 4
+)
+
+var _ msgp.Encodable = (*spanEvent)(nil)
+var _ msgp.Decodable = (*spanEvent)(nil)
+
+// spanEvent is an event recorded on a span. Its attribute values are
+// normalized to the types the agent supports: string, bool, int64, float64,
+// and slices of these types.
+type spanEvent struct {
+	Name         string                 `json:"name"`
+	TimeUnixNano uint64                 `json:"time_unix_nano"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+}
+
+func newSpanEvent(name string, timestamp time.Time, attributes map[string]interface{}) spanEvent {
+	e := spanEvent{Name: name, TimeUnixNano: uint64(timestamp.UnixNano())}
+	if len(attributes) > 0 {
+		e.Attributes = make(map[string]interface{}, len(attributes))
+		for k, v := range attributes {
+			e.Attributes[k] = normalizeSpanEventAttribute(v)
+		}
+	}
+	return e
+}
+
+// normalizeSpanEventAttribute converts v to one of the supported attribute types.
+func normalizeSpanEventAttribute(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string, bool, int64, float64, []string, []bool, []int64, []float64:
+		return v
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint:
+		return uint64Attribute(uint64(v))
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		return uint64Attribute(v)
+	case float32:
+		return float64(v)
+	case []int:
+		vs := make([]int64, len(v))
+		for i, x := range v {
+			vs[i] = int64(x)
+		}
+		return vs
+	case []interface{}:
+		vs := make([]string, len(v))
+		for i, x := range v {
+			vs[i] = fmt.Sprint(x)
+		}
+		return vs
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// uint64Attribute returns v as an int64, or as a float64 when it doesn't fit
+// in an int64 so that it isn't wrapped to a negative value.
+func uint64Attribute(v uint64) interface{} {
+	if v > math.MaxInt64 {
+		return float64(v)
+	}
+	return int64(v)
+}
+
+// toPublic returns the event as a ddtrace.SpanEvent.
+func (e *spanEvent) toPublic() ddtrace.SpanEvent {
+	pe := ddtrace.SpanEvent{
+		Name: e.Name,
+		Time: time.Unix(0, int64(e.TimeUnixNano)),
+	}
+	if len(e.Attributes) > 0 {
+		pe.Attributes = make(map[string]interface{}, len(e.Attributes))
+		for k, v := range e.Attributes {
+			pe.Attributes[k] = v
+		}
+	}
+	return pe
+}
+
+// EncodeMsg implements msgp.Encodable, encoding the event in the format of the
+// span events natively supported by the agent.
+func (e *spanEvent) EncodeMsg(en *msgp.Writer) error {
+	n := uint32(2)
+	if len(e.Attributes) > 0 {
+		n++
+	}
+	if err := en.WriteMapHeader(n); err != nil {
+		return err
+	}
+	if err := en.WriteString("time_unix_nano"); err != nil {
+		return err
+	}
+	if err := en.WriteUint64(e.TimeUnixNano); err != nil {
+		return err
+	}
+	if err := en.WriteString("name"); err != nil {
+		return err
+	}
+	if err := en.WriteString(e.Name); err != nil {
+		return err
+	}
+	if len(e.Attributes) == 0 {
+		return nil
+	}
+	if err := en.WriteString("attributes"); err != nil {
+		return err
+	}
+	if err := en.WriteMapHeader(uint32(len(e.Attributes))); err != nil {
+		return err
+	}
+	for k, v := range e.Attributes {
+		if err := en.WriteString(k); err != nil {
+			return err
+		}
+		if err := encodeSpanEventAttribute(en, v); err != nil {
+			return msgp.WrapError(err, "Attributes", k)
+		}
+	}
+	return nil
+}
+
+// encodeSpanEventAttribute encodes the normalized attribute value v as an
+// agent AttributeAnyValue.
+func encodeSpanEventAttribute(en *msgp.Writer, v interface{}) error {
+	var values []interface{}
+	switch v := v.(type) {
+	case []string:
+		values = make([]interface{}, len(v))
+		for i, x := range v {
+			values[i] = x
+		}
+	case []bool:
+		values = make([]interface{}, len(v))
+		for i, x := range v {
+			values[i] = x
+		}
+	case []int64:
+		values = make([]interface{}, len(v))
+		for i, x := range v {
+			values[i] = x
+		}
+	case []float64:
+		values = make([]interface{}, len(v))
+		for i, x := range v {
+			values[i] = x
+		}
+	default:
+		return encodeSpanEventScalar(en, v)
+	}
+	if err := en.WriteMapHeader(2); err != nil {
+		return err
+	}
+	if err := en.WriteString("type"); err != nil {
+		return err
+	}
+	if err := en.WriteInt(spanEventAttrArray); err != nil {
+		return err
+	}
+	if err := en.WriteString("array_value"); err != nil {
+		return err
+	}
+	if err := en.WriteMapHeader(1); err != nil {
+		return err
+	}
+	if err := en.WriteString("values"); err != nil {
+		return err
+	}
+	if err := en.WriteArrayHeader(uint32(len(values))); err != nil {
+		return err
+	}
+	for _, x := range values {
+		if err := encodeSpanEventScalar(en, x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeSpanEventScalar encodes the normalized scalar attribute value v.
+func encodeSpanEventScalar(en *msgp.Writer, v interface{}) error {
+	if err := en.WriteMapHeader(2); err != nil {
+		return err
+	}
+	if err := en.WriteString("type"); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case bool:
+		if err := en.WriteInt(spanEventAttrBool); err != nil {
+			return err
+		}
+		if err := en.WriteString("bool_value"); err != nil {
+			return err
+		}
+		return en.WriteBool(v)
+	case int64:
+		if err := en.WriteInt(spanEventAttrInt); err != nil {
+			return err
+		}
+		if err := en.WriteString("int_value"); err != nil {
+			return err
+		}
+		return en.WriteInt64(v)
+	case float64:
+		if err := en.WriteInt(spanEventAttrDouble); err != nil {
+			return err
+		}
+		if err := en.WriteString("double_value"); err != nil {
+			return err
+		}
+		return en.WriteFloat64(v)
+	default:
+		if err := en.WriteInt(spanEventAttrString); err != nil {
+			return err
+		}
+		if err := en.WriteString("string_value"); err != nil {
+			return err
+		}
+		s, _ := v.(string)
+		return en.WriteString(s)
+	}
+}
+
+// DecodeMsg implements msgp.Decodable.
+func (e *spanEvent) DecodeMsg(dc *msgp.Reader) error {
+	n, err := dc.ReadMapHeader()
+	if err != nil {
+		return err
+	}
+	*e = spanEvent{}
+	for ; n > 0; n-- {
+		field, err := dc.ReadMapKeyPtr()
+		if err != nil {
+			return err
+		}
+		switch msgp.UnsafeString(field) {
+		case "time_unix_nano":
+			if e.TimeUnixNano, err = dc.ReadUint64(); err != nil {
+				return msgp.WrapError(err, "TimeUnixNano")
+			}
+		case "name":
+			if e.Name, err = dc.ReadString(); err != nil {
+				return msgp.WrapError(err, "Name")
+			}
+		case "attributes":
+			sz, err := dc.ReadMapHeader()
+			if err != nil {
+				return msgp.WrapError(err, "Attributes")
+			}
+			e.Attributes = make(map[string]interface{}, sz)
+			for ; sz > 0; sz-- {
+				k, err := dc.ReadString()
+				if err != nil {
+					return msgp.WrapError(err, "Attributes")
+				}
+				if e.Attributes[k], err = decodeSpanEventAttribute(dc); err != nil {
+					return msgp.WrapError(err, "Attributes", k)
+				}
+			}
+		default:
+			if err := dc.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeSpanEventAttribute decodes an agent AttributeAnyValue.
+func decodeSpanEventAttribute(dc *msgp.Reader) (interface{}, error) {
+	n, err := dc.ReadMapHeader()
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	for ; n > 0; n-- {
+		field, err := dc.ReadMapKeyPtr()
+		if err != nil {
+			return nil, err
+		}
+		switch msgp.UnsafeString(field) {
+		case "string_value":
+			v, err = dc.ReadString()
+		case "bool_value":
+			v, err = dc.ReadBool()
+		case "int_value":
+			v, err = dc.ReadInt64()
+		case "double_value":
+			v, err = dc.ReadFloat64()
+		case "array_value":
+			v, err = decodeSpanEventArray(dc)
+		default:
+			err = dc.Skip()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// decodeSpanEventArray decodes an agent AttributeArray into a typed slice.
+func decodeSpanEventArray(dc *msgp.Reader) (interface{}, error) {
+	var values []interface{}
+	n, err := dc.ReadMapHeader()
+	if err != nil {
+		return nil, err
+	}
+	for ; n > 0; n-- {
+		field, err := dc.ReadMapKeyPtr()
+		if err != nil {
+			return nil, err
+		}
+		if msgp.UnsafeString(field) != "values" {
+			if err := dc.Skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		sz, err := dc.ReadArrayHeader()
+		if err != nil {
+			return nil, err
+		}
+		values = make([]interface{}, sz)
+		for i := range values {
+			if values[i], err = decodeSpanEventAttribute(dc); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(values) == 0 {
+		return []string{}, nil
+	}
+	switch values[0].(type) {
+	case bool:
+		return typedSlice[bool](values), nil
+	case int64:
+		return typedSlice[int64](values), nil
+	case float64:
+		return typedSlice[float64](values), nil
+	default:
+		return typedSlice[string](values), nil
+	}
+}
+
+func typedSlice[T any](values []interface{}) []T {
+	vs := make([]T, len(values))
+	for i, v := range values {
+		vs[i], _ = v.(T)
+	}
+	return vs
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by
+// the serialized message.
+func (e *spanEvent) Msgsize() int {
+	s := 1 + 15 + msgp.Uint64Size + 5 + msgp.StringPrefixSize + len(e.Name) + 11 + msgp.MapHeaderSize
+	for k, v := range e.Attributes {
+		s += msgp.StringPrefixSize + len(k) + spanEventAttributeSize(v)
+	}
+	return s
+}
+
+// spanEventAttributeSize returns an upper bound of the size of the encoded
+// attribute value v.
+func spanEventAttributeSize(v interface{}) int {
+	const scalar = 1 + 5 + msgp.IntSize + 13 // map header, type and the longest value key
+	switch v := v.(type) {
+	case string:
+		return scalar + msgp.StringPrefixSize + len(v)
+	case []string:
+		s := scalar + 12 + 7 + msgp.ArrayHeaderSize
+		for _, x := range v {
+			s += scalar + msgp.StringPrefixSize + len(x)
+		}
+		return s
+	case []bool:
+		return scalar + 12 + 7 + msgp.ArrayHeaderSize + len(v)*(scalar+msgp.BoolSize)
+	case []int64:
+		return scalar + 12 + 7 + msgp.ArrayHeaderSize + len(v)*(scalar+msgp.Int64Size)
+	case []float64:
+		return scalar + 12 + 7 + msgp.ArrayHeaderSize + len(v)*(scalar+msgp.Float64Size)
+	default:
+		return scalar + msgp.Float64Size
+	}
+}
+
+// AddEvent records an event named name, which occurred at the given time, on the span.
+// Events are sent natively to agents supporting them, and as JSON in the "events" tag otherwise.
+func (s *span) AddEvent(name string, timestamp time.Time, attributes map[string]interface{}) {
+	s.Lock()
+	defer s.Unlock()
+	if s.finished {
+		return
+	}
+	s.SpanEvents = append(s.SpanEvents, newSpanEvent(name, timestamp, attributes))
+}
+
+// serializeSpanEventsInMeta moves the span events to the "events" meta as a JSON string,
+// for agents which can't receive them natively.
+func (s *span) serializeSpanEventsInMeta() {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.SpanEvents) == 0 {
+		return
+	}
+	b, err := json.Marshal(s.SpanEvents)
+	if err != nil {
+		log.Debug("Unable to marshal span events. Not adding span events to span meta.")
+		return
+	}
+	s.setMeta(keySpanEvents, string(b))
+	s.SpanEvents = nil
+}
@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+)
+
+func TestSpanEvents(t *testing.T) {
+	ts := time.Unix(1700000000, 123)
+	attrs := map[string]interface{}{
+		"str":     "value",
+		"bool":    true,
+		"int":     42,
+		"uint8":   uint8(7),
+		"uint64":  uint64(math.MaxUint64),
+		"float":   float32(1.5),
+		"strs":    []string{"a", "b"},
+		"ints":    []int{1, 2},
+		"floats":  []float64{0.5},
+		"bools":   []bool{false, true},
+		"err":     errors.New("boom"),
+		"mixed":   []interface{}{1, "two"},
+		"time":    time.Second,
+		"nothing": nil,
+	}
+	wantAttrs := map[string]interface{}{
+		"str":     "value",
+		"bool":    true,
+		"int":     int64(42),
+		"uint8":   int64(7),
+		"uint64":  float64(math.MaxUint64),
+		"float":   1.5,
+		"strs":    []string{"a", "b"},
+		"ints":    []int64{1, 2},
+		"floats":  []float64{0.5},
+		"bools":   []bool{false, true},
+		"err":     "boom",
+		"mixed":   []string{"1", "two"},
+		"time":    "1s",
+		"nothing": "<nil>",
+	}
+
+	t.Run("encoding", func(t *testing.T) {
+		s := newBasicSpan("span")
+		s.AddEvent("evt", ts, attrs)
+		s.AddEvent("empty", ts, nil)
+		p, err := encode([][]*span{{s}})
+		require.NoError(t, err)
+		traces, err := decode(p)
+		require.NoError(t, err)
+		require.Len(t, traces[0][0].SpanEvents, 2)
+		assert.Equal(t, spanEvent{Name: "evt", TimeUnixNano: uint64(ts.UnixNano()), Attributes: wantAttrs}, traces[0][0].SpanEvents[0])
+		assert.Equal(t, spanEvent{Name: "empty", TimeUnixNano: uint64(ts.UnixNano())}, traces[0][0].SpanEvents[1])
+		assert.GreaterOrEqual(t, s.Msgsize(), p.size()-arrayHeaderSize(1)-arrayHeaderSize(1))
+	})
+
+	t.Run("native", func(t *testing.T) {
+		tracer, transport, flush, stop := startTestTracer(t)
+		defer stop()
+		tracer.config.agent.spanEventsAvailable = true
+
+		sp := tracer.StartSpan("span")
+		sp.(ddtrace.SpanWithEvents).AddEvent("evt", ts, map[string]interface{}{"k": "v"})
+		sp.Finish()
+		sp.(ddtrace.SpanWithEvents).AddEvent("late", ts, nil)
+		flush(1)
+
+		s := transport.Traces()[0][0]
+		assert.NotContains(t, s.Meta, keySpanEvents)
+		assert.Equal(t, []spanEvent{{Name: "evt", TimeUnixNano: uint64(ts.UnixNano()), Attributes: map[string]interface{}{"k": "v"}}}, s.SpanEvents)
+	})
+
+	t.Run("meta", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			available bool
+			protocol  float64
+		}{
+			"unsupported": {available: false, protocol: traceProtocolV04},
+			"v0.5":        {available: true, protocol: traceProtocolV05},
+		} {
+			t.Run(name, func(t *testing.T) {
+				tracer, _, _, stop := startTestTracer(t)
+				defer stop()
+				tracer.config.agent.spanEventsAvailable = tc.available
+				tracer.config.traceProtocol = tc.protocol
+
+				sp := tracer.StartSpan("span").(*span)
+				sp.AddEvent("evt", ts, map[string]interface{}{"k": 1})
+				sp.Finish()
+
+				assert.Nil(t, sp.SpanEvents)
+				var events []map[string]interface{}
+				require.NoError(t, json.Unmarshal([]byte(sp.Meta[keySpanEvents]), &events))
+				assert.Equal(t, []map[string]interface{}{{
+					"name":           "evt",
+					"time_unix_nano": float64(ts.UnixNano()),
+					"attributes":     map[string]interface{}{"k": 1.},
+				}}, events)
+			})
+		}
+	})
+}
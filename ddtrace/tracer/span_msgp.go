@@ -259,6 +259,25 @@ func (z *span) DecodeMsg(dc *msgp.Reader) (err error) {
 					return
 				}
 			}
+		case "span_events":
+			var zb0005 uint32
+			zb0005, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "SpanEvents")
+				return
+			}
+			if cap(z.SpanEvents) >= int(zb0005) {
+				z.SpanEvents = (z.SpanEvents)[:zb0005]
+			} else {
+				z.SpanEvents = make([]spanEvent, zb0005)
+			}
+			for za0006 := range z.SpanEvents {
+				err = z.SpanEvents[za0006].DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "SpanEvents", za0006)
+					return
+				}
+			}
 		default:
 			err = dc.Skip()
 			if err != nil {
@@ -273,8 +292,8 @@ func (z *span) DecodeMsg(dc *msgp.Reader) (err error) {
 // EncodeMsg implements msgp.Encodable
 func (z *span) EncodeMsg(en *msgp.Writer) (err error) {
 	// check for omitted fields
-	zb0001Len := uint32(14)
-	var zb0001Mask uint16 /* 14 bits */
+	zb0001Len := uint32(15)
+	var zb0001Mask uint16 /* 15 bits */
 	_ = zb0001Mask
 	if z.Meta == nil {
 		zb0001Len--
@@ -288,189 +307,213 @@ func (z *span) EncodeMsg(en *msgp.Writer) (err error) {
 		zb0001Len--
 		zb0001Mask |= 0x2000
 	}
+	if z.SpanEvents == nil {
+		zb0001Len--
+		zb0001Mask |= 0x4000
+	}
 	// variable map header, size zb0001Len
 	err = en.Append(0x80 | uint8(zb0001Len))
 	if err != nil {
 		return
 	}
-	if zb0001Len == 0 {
-		return
-	}
-	// write "name"
-	err = en.Append(0xa4, 0x6e, 0x61, 0x6d, 0x65)
-	if err != nil {
-		return
-	}
-	err = en.WriteString(z.Name)
-	if err != nil {
-		err = msgp.WrapError(err, "Name")
-		return
-	}
-	// write "service"
-	err = en.Append(0xa7, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65)
-	if err != nil {
-		return
-	}
-	err = en.WriteString(z.Service)
-	if err != nil {
-		err = msgp.WrapError(err, "Service")
-		return
-	}
-	// write "resource"
-	err = en.Append(0xa8, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65)
-	if err != nil {
-		return
-	}
-	err = en.WriteString(z.Resource)
-	if err != nil {
-		err = msgp.WrapError(err, "Resource")
-		return
-	}
-	// write "type"
-	err = en.Append(0xa4, 0x74, 0x79, 0x70, 0x65)
-	if err != nil {
-		return
-	}
-	err = en.WriteString(z.Type)
-	if err != nil {
-		err = msgp.WrapError(err, "Type")
-		return
-	}
-	// write "start"
-	err = en.Append(0xa5, 0x73, 0x74, 0x61, 0x72, 0x74)
-	if err != nil {
-		return
-	}
-	err = en.WriteInt64(z.Start)
-	if err != nil {
-		err = msgp.WrapError(err, "Start")
-		return
-	}
-	// write "duration"
-	err = en.Append(0xa8, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e)
-	if err != nil {
-		return
-	}
-	err = en.WriteInt64(z.Duration)
-	if err != nil {
-		err = msgp.WrapError(err, "Duration")
-		return
-	}
-	if (zb0001Mask & 0x40) == 0 { // if not omitted
-		// write "meta"
-		err = en.Append(0xa4, 0x6d, 0x65, 0x74, 0x61)
+
+	// skip if no fields are to be emitted
+	if zb0001Len != 0 {
+		// write "name"
+		err = en.Append(0xa4, 0x6e, 0x61, 0x6d, 0x65)
 		if err != nil {
 			return
 		}
-		err = en.WriteMapHeader(uint32(len(z.Meta)))
+		err = en.WriteString(z.Name)
 		if err != nil {
-			err = msgp.WrapError(err, "Meta")
+			err = msgp.WrapError(err, "Name")
 			return
 		}
-		for za0001, za0002 := range z.Meta {
-			err = en.WriteString(za0001)
+		// write "service"
+		err = en.Append(0xa7, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65)
+		if err != nil {
+			return
+		}
+		err = en.WriteString(z.Service)
+		if err != nil {
+			err = msgp.WrapError(err, "Service")
+			return
+		}
+		// write "resource"
+		err = en.Append(0xa8, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65)
+		if err != nil {
+			return
+		}
+		err = en.WriteString(z.Resource)
+		if err != nil {
+			err = msgp.WrapError(err, "Resource")
+			return
+		}
+		// write "type"
+		err = en.Append(0xa4, 0x74, 0x79, 0x70, 0x65)
+		if err != nil {
+			return
+		}
+		err = en.WriteString(z.Type)
+		if err != nil {
+			err = msgp.WrapError(err, "Type")
+			return
+		}
+		// write "start"
+		err = en.Append(0xa5, 0x73, 0x74, 0x61, 0x72, 0x74)
+		if err != nil {
+			return
+		}
+		err = en.WriteInt64(z.Start)
+		if err != nil {
+			err = msgp.WrapError(err, "Start")
+			return
+		}
+		// write "duration"
+		err = en.Append(0xa8, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e)
+		if err != nil {
+			return
+		}
+		err = en.WriteInt64(z.Duration)
+		if err != nil {
+			err = msgp.WrapError(err, "Duration")
+			return
+		}
+		if (zb0001Mask & 0x40) == 0 { // if not omitted
+			// write "meta"
+			err = en.Append(0xa4, 0x6d, 0x65, 0x74, 0x61)
 			if err != nil {
-				err = msgp.WrapError(err, "Meta")
 				return
 			}
-			err = en.WriteString(za0002)
+			err = en.WriteMapHeader(uint32(len(z.Meta)))
 			if err != nil {
-				err = msgp.WrapError(err, "Meta", za0001)
+				err = msgp.WrapError(err, "Meta")
 				return
 			}
+			for za0001, za0002 := range z.Meta {
+				err = en.WriteString(za0001)
+				if err != nil {
+					err = msgp.WrapError(err, "Meta")
+					return
+				}
+				err = en.WriteString(za0002)
+				if err != nil {
+					err = msgp.WrapError(err, "Meta", za0001)
+					return
+				}
+			}
 		}
-	}
-	// write "meta_struct"
-	err = en.Append(0xab, 0x6d, 0x65, 0x74, 0x61, 0x5f, 0x73, 0x74, 0x72, 0x75, 0x63, 0x74)
-	if err != nil {
-		return
-	}
-	err = z.MetaStruct.EncodeMsg(en)
-	if err != nil {
-		err = msgp.WrapError(err, "MetaStruct")
-		return
-	}
-	if (zb0001Mask & 0x100) == 0 { // if not omitted
-		// write "metrics"
-		err = en.Append(0xa7, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73)
+		// write "meta_struct"
+		err = en.Append(0xab, 0x6d, 0x65, 0x74, 0x61, 0x5f, 0x73, 0x74, 0x72, 0x75, 0x63, 0x74)
 		if err != nil {
 			return
 		}
-		err = en.WriteMapHeader(uint32(len(z.Metrics)))
+		err = z.MetaStruct.EncodeMsg(en)
 		if err != nil {
-			err = msgp.WrapError(err, "Metrics")
+			err = msgp.WrapError(err, "MetaStruct")
 			return
 		}
-		for za0003, za0004 := range z.Metrics {
-			err = en.WriteString(za0003)
+		if (zb0001Mask & 0x100) == 0 { // if not omitted
+			// write "metrics"
+			err = en.Append(0xa7, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73)
 			if err != nil {
-				err = msgp.WrapError(err, "Metrics")
 				return
 			}
-			err = en.WriteFloat64(za0004)
+			err = en.WriteMapHeader(uint32(len(z.Metrics)))
 			if err != nil {
-				err = msgp.WrapError(err, "Metrics", za0003)
+				err = msgp.WrapError(err, "Metrics")
 				return
 			}
+			for za0003, za0004 := range z.Metrics {
+				err = en.WriteString(za0003)
+				if err != nil {
+					err = msgp.WrapError(err, "Metrics")
+					return
+				}
+				err = en.WriteFloat64(za0004)
+				if err != nil {
+					err = msgp.WrapError(err, "Metrics", za0003)
+					return
+				}
+			}
 		}
-	}
-	// write "span_id"
-	err = en.Append(0xa7, 0x73, 0x70, 0x61, 0x6e, 0x5f, 0x69, 0x64)
-	if err != nil {
-		return
-	}
-	err = en.WriteUint64(z.SpanID)
-	if err != nil {
-		err = msgp.WrapError(err, "SpanID")
-		return
-	}
-	// write "trace_id"
-	err = en.Append(0xa8, 0x74, 0x72, 0x61, 0x63, 0x65, 0x5f, 0x69, 0x64)
-	if err != nil {
-		return
-	}
-	err = en.WriteUint64(z.TraceID)
-	if err != nil {
-		err = msgp.WrapError(err, "TraceID")
-		return
-	}
-	// write "parent_id"
-	err = en.Append(0xa9, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x5f, 0x69, 0x64)
-	if err != nil {
-		return
-	}
-	err = en.WriteUint64(z.ParentID)
-	if err != nil {
-		err = msgp.WrapError(err, "ParentID")
-		return
-	}
-	// write "error"
-	err = en.Append(0xa5, 0x65, 0x72, 0x72, 0x6f, 0x72)
-	if err != nil {
-		return
-	}
-	err = en.WriteInt32(z.Error)
-	if err != nil {
-		err = msgp.WrapError(err, "Error")
-		return
-	}
-	if (zb0001Mask & 0x2000) == 0 { // if not omitted
-		// write "span_links"
-		err = en.Append(0xaa, 0x73, 0x70, 0x61, 0x6e, 0x5f, 0x6c, 0x69, 0x6e, 0x6b, 0x73)
+		// write "span_id"
+		err = en.Append(0xa7, 0x73, 0x70, 0x61, 0x6e, 0x5f, 0x69, 0x64)
+		if err != nil {
+			return
+		}
+		err = en.WriteUint64(z.SpanID)
+		if err != nil {
+			err = msgp.WrapError(err, "SpanID")
+			return
+		}
+		// write "trace_id"
+		err = en.Append(0xa8, 0x74, 0x72, 0x61, 0x63, 0x65, 0x5f, 0x69, 0x64)
+		if err != nil {
+			return
+		}
+		err = en.WriteUint64(z.TraceID)
+		if err != nil {
+			err = msgp.WrapError(err, "TraceID")
+			return
+		}
+		// write "parent_id"
+		err = en.Append(0xa9, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x5f, 0x69, 0x64)
+		if err != nil {
+			return
+		}
+		err = en.WriteUint64(z.ParentID)
+		if err != nil {
+			err = msgp.WrapError(err, "ParentID")
+			return
+		}
+		// write "error"
+		err = en.Append(0xa5, 0x65, 0x72, 0x72, 0x6f, 0x72)
 		if err != nil {
 			return
 		}
-		err = en.WriteArrayHeader(uint32(len(z.SpanLinks)))
+		err = en.WriteInt32(z.Error)
 		if err != nil {
-			err = msgp.WrapError(err, "SpanLinks")
+			err = msgp.WrapError(err, "Error")
 			return
 		}
-		for za0005 := range z.SpanLinks {
-			err = z.SpanLinks[za0005].EncodeMsg(en)
+		if (zb0001Mask & 0x2000) == 0 { // if not omitted
+			// write "span_links"
+			err = en.Append(0xaa, 0x73, 0x70, 0x61, 0x6e, 0x5f, 0x6c, 0x69, 0x6e, 0x6b, 0x73)
 			if err != nil {
-				err = msgp.WrapError(err, "SpanLinks", za0005)
 				return
 			}
+			err = en.WriteArrayHeader(uint32(len(z.SpanLinks)))
+			if err != nil {
+				err = msgp.WrapError(err, "SpanLinks")
+				return
+			}
+			for za0005 := range z.SpanLinks {
+				err = z.SpanLinks[za0005].EncodeMsg(en)
+				if err != nil {
+					err = msgp.WrapError(err, "SpanLinks", za0005)
+					return
+				}
+			}
+		}
+		if (zb0001Mask & 0x4000) == 0 { // if not omitted
+			// write "span_events"
+			err = en.Append(0xab, 0x73, 0x70, 0x61, 0x6e, 0x5f, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73)
+			if err != nil {
+				return
+			}
+			err = en.WriteArrayHeader(uint32(len(z.SpanEvents)))
+			if err != nil {
+				err = msgp.WrapError(err, "SpanEvents")
+				return
+			}
+			for za0006 := range z.SpanEvents {
+				err = z.SpanEvents[za0006].EncodeMsg(en)
+				if err != nil {
+					err = msgp.WrapError(err, "SpanEvents", za0006)
+					return
+				}
+			}
 		}
 	}
 	return
@@ -496,6 +539,10 @@ func (z *span) Msgsize() (s int) {
 	for za0005 := range z.SpanLinks {
 		s += z.SpanLinks[za0005].Msgsize()
 	}
+	s += 12 + msgp.ArrayHeaderSize
+	for za0006 := range z.SpanEvents {
+		s += z.SpanEvents[za0006].Msgsize()
+	}
 	return
 }
 
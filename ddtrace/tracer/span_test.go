@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -1116,6 +1117,135 @@ func testConcurrentSpanSetTag(t *testing.T) {
 	wg.Wait()
 }
 
+func TestSpanReadOnly(t *testing.T) {
+	tracer := newTracer(WithService("svc"))
+	defer tracer.Stop()
+
+	start := time.Now().Add(-time.Second)
+	sp := tracer.StartSpan("op",
+		ResourceName("res"),
+		SpanType("web"),
+		StartTime(start),
+		Tag("k", "v"),
+		Tag("n", 2),
+	)
+	sp.(SpanWithLinks).AddSpanLink(ddtrace.SpanLink{SpanID: 1, TraceID: 2, Attributes: map[string]string{"k": "v"}})
+	ro, ok := sp.(ddtrace.ReadOnlySpan)
+	require.True(t, ok)
+
+	assert := assert.New(t)
+	assert.Equal("op", ro.OperationName())
+	assert.Equal("svc", ro.ServiceName())
+	assert.Equal("res", ro.ResourceName())
+	assert.Equal("web", ro.SpanType())
+	assert.Equal(start.UnixNano(), ro.StartTime().UnixNano())
+	assert.Zero(ro.Elapsed())
+	assert.False(ro.IsError())
+	assert.Equal("v", ro.StringTags()["k"])
+	assert.Equal(2.0, ro.NumericTags()["n"])
+	assert.Equal([]ddtrace.SpanLink{{SpanID: 1, TraceID: 2, Attributes: map[string]string{"k": "v"}}}, ro.Links())
+	assert.Equal(sp.Context(), ro.Context())
+
+	// the accessors return copies
+	ro.StringTags()["k"] = "changed"
+	ro.NumericTags()["n"] = 3
+	ro.Links()[0].Attributes["k"] = "changed"
+	assert.Equal("v", ro.StringTags()["k"])
+	assert.Equal(2.0, ro.NumericTags()["n"])
+	assert.Equal("v", ro.Links()[0].Attributes["k"])
+
+	sp.Finish(WithError(errors.New("boom")), FinishTime(start.Add(time.Second)))
+	assert.Equal(time.Second, ro.Elapsed())
+	assert.True(ro.IsError())
+	assert.Equal("boom", ro.StringTags()[ext.ErrorMsg])
+}
+
+func TestSpanReadOnlyConcurrency(t *testing.T) {
+	tracer := newTracer()
+	defer tracer.Stop()
+
+	sp := tracer.StartSpan("op")
+	ro := sp.(ddtrace.ReadOnlySpan)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			sp.SetTag("k", i)
+			sp.SetTag(ext.ResourceName, strconv.Itoa(i))
+			sp.(SpanWithLinks).AddSpanLink(ddtrace.SpanLink{SpanID: uint64(i)})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_ = ro.NumericTags()
+			_ = ro.ResourceName()
+			_ = ro.Links()
+		}
+	}()
+	wg.Wait()
+	sp.Finish()
+}
+
+func TestSpanAddLink(t *testing.T) {
+	tracer := newTracer()
+	defer tracer.Stop()
+
+	carrier := TextMapCarrier{
+		traceparentHeader: "00-000000000000000100000000000000ff-0000000000000002-01",
+		tracestateHeader:  "dd=s:1,othervendor=t61rcWkgMzE",
+	}
+	ctx, err := tracer.Extract(carrier)
+	require.NoError(t, err)
+
+	sp := tracer.StartSpan("consume")
+	attrs := map[string]string{"messaging.operation": "receive"}
+	sp.(SpanWithAddLink).AddLink(ctx, attrs)
+	sp.(SpanWithAddLink).AddLink(nil, nil)
+	attrs["messaging.operation"] = "changed"
+	sp.Finish()
+	sp.(SpanWithAddLink).AddLink(ctx, nil)
+
+	links := sp.(*span).Links()
+	require.Len(t, links, 1)
+	assert.Equal(t, ddtrace.SpanLink{
+		TraceID:     0xff,
+		TraceIDHigh: 1,
+		SpanID:      2,
+		Attributes:  map[string]string{"messaging.operation": "receive"},
+		Tracestate:  "dd=s:1,othervendor=t61rcWkgMzE",
+		Flags:       0x80000001,
+	}, links[0])
+}
+
+type customSpanContext struct{ ddtrace.SpanContext }
+
+func (customSpanContext) Tracestate() string { return "vendor=value" }
+
+func TestSpanLinkFromContext(t *testing.T) {
+	t.Run("local", func(t *testing.T) {
+		tracer := newTracer()
+		defer tracer.Stop()
+		sp := tracer.StartSpan("op")
+		sp.(*span).setSamplingPriority(ext.PriorityUserReject, samplernames.Manual)
+
+		link := SpanLinkFromContext(sp.Context(), nil)
+		assert.Equal(t, sp.Context().TraceID(), link.TraceID)
+		assert.Equal(t, sp.Context().SpanID(), link.SpanID)
+		assert.Nil(t, link.Attributes)
+		assert.Equal(t, uint32(0x80000000), link.Flags)
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		ctx := customSpanContext{SpanContext: &internal.NoopSpanContext{}}
+		link := SpanLinkFromContext(ctx, map[string]string{"k": "v"})
+		assert.Equal(t, "vendor=value", link.Tracestate)
+		assert.Zero(t, link.Flags)
+		assert.Equal(t, map[string]string{"k": "v"}, link.Attributes)
+	})
+}
+
 func TestSpanLinksInMeta(t *testing.T) {
 	t.Run("no_links", func(t *testing.T) {
 		tracer := newTracer()
@@ -270,6 +270,8 @@ type trace struct {
 	priority         *float64          // sampling priority
 	locked           bool              // specifies if the sampling priority can be altered
 	samplingDecision samplingDecision  // samplingDecision indicates whether to send the trace to the agent.
+	exceptionEvents  int               // the number of exception events recorded on the spans of the trace
+	adaptive         *adaptiveEntry    // the adaptive sampler entry the trace was counted in, if it took the decision
 
 	// root specifies the root of the trace, if known; it is nil when a span
 	// context is extracted from a carrier, at which point there are no spans in
@@ -389,6 +391,18 @@ func (t *trace) setLocked(locked bool) {
 	t.locked = locked
 }
 
+func (t *trace) adaptiveEntry() *adaptiveEntry {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.adaptive
+}
+
+func (t *trace) setAdaptiveEntry(e *adaptiveEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.adaptive = e
+}
+
 // push pushes a new span into the trace. If the buffer is full, it returns
 // a errBufferFull error.
 func (t *trace) push(sp *span) {
@@ -440,8 +454,20 @@ func (t *trace) setTraceTags(s *span, tr *tracer) {
 // if enabled and the total number of finished spans is greater than or equal to the partial flush limit.
 // The provided span must be locked.
 func (t *trace) finishedOne(s *span) {
+	var (
+		tr    *tracer
+		ch    *chunk
+		final bool
+	)
 	t.mu.Lock()
-	defer t.mu.Unlock()
+	defer func() {
+		t.mu.Unlock()
+		if ch != nil {
+			// the chunk is finished without holding the lock, as span
+			// processors may use the trace, e.g. to start new spans.
+			t.finishChunk(tr, ch, final)
+		}
+	}()
 	s.finished = true
 	if t.full {
 		// capacity has been reached, the buffer is no longer tracking
@@ -481,10 +507,11 @@ func (t *trace) finishedOne(s *span) {
 	}
 
 	if len(t.spans) == t.finished { // perform a full flush of all spans
-		t.finishChunk(tr, &chunk{
+		ch, final = &chunk{
 			spans:    t.spans,
 			willSend: decisionKeep == samplingDecision(atomic.LoadUint32((*uint32)(&t.samplingDecision))),
-		})
+		}, true
+		t.finished = 0 // important, because a buffer can be used for several flushes
 		t.spans = nil
 		return
 	}
@@ -511,16 +538,49 @@ func (t *trace) finishedOne(s *span) {
 		// Make sure the first span in the chunk has the trace-level tags
 		t.setTraceTags(finishedSpans[0], tr)
 	}
-	t.finishChunk(tr, &chunk{
+	ch = &chunk{
 		spans:    finishedSpans,
 		willSend: decisionKeep == samplingDecision(atomic.LoadUint32((*uint32)(&t.samplingDecision))),
-	})
+	}
+	t.finished = 0
 	t.spans = leftoverSpans
 }
 
-func (t *trace) finishChunk(tr *tracer, ch *chunk) {
+// finishChunk passes the finished chunk ch to the tracer. final reports whether
+// ch holds the last spans of the trace. It must be called without holding t.mu.
+func (t *trace) finishChunk(tr *tracer, ch *chunk, final bool) {
+	if len(tr.config.spanProcessors) > 0 {
+		first := ch.spans[0]
+		ch.spans = processChunk(tr.config.spanProcessors, ch.spans)
+		if len(ch.spans) == 0 {
+			tr.statsd.Incr("datadog.tracer.traces_dropped", []string{"reason:span_processor"}, 1)
+			return
+		}
+		if ch.spans[0] != first {
+			// the span carrying the trace-level tags was dropped
+			if p, ok := first.Metrics[keySamplingPriority]; ok {
+				ch.spans[0].setMetric(keySamplingPriority, p)
+			}
+			t.mu.RLock()
+			t.setTraceTags(ch.spans[0], tr)
+			t.mu.RUnlock()
+		}
+		if tr.config.canComputeStats() {
+			// stats are computed once the spans have been processed
+			for _, s := range ch.spans {
+				tr.submitStats(s)
+			}
+		}
+	}
+	if tr.tailSampler != nil {
+		t.mu.Lock()
+		ch = tr.tailSampler.sample(t, ch, final, tr.pushChunk)
+		t.mu.Unlock()
+		if ch == nil {
+			return // buffered until the trace is complete
+		}
+	}
 	tr.pushChunk(ch)
-	t.finished = 0 // important, because a buffer can be used for several flushes
 }
 
 // setPeerService sets the peer.service, _dd.peer.service.source, and _dd.peer.service.remapped_from
@@ -162,7 +162,7 @@ func TestTraceFinishChunk(t *testing.T) {
 	for i := 0; i < payloadQueueSize+1; i++ {
 		trace.mu.Lock()
 		c := chunk{spans: make([]*span, 1)}
-		trace.finishChunk(tracer, &c)
+		trace.finishChunk(tracer, &c, true)
 		trace.mu.Unlock()
 	}
 	assert.Equal(uint32(1), tracer.totalTracesDropped)
@@ -0,0 +1,301 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+
+	globalinternal "gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+const (
+	// defaultSpoolMaxSize is the default maximum size of all the spooled payloads.
+	defaultSpoolMaxSize = 100 * 1024 * 1024
+
+	// defaultSpoolMaxFiles is the default maximum number of spooled payloads.
+	defaultSpoolMaxFiles = 1000
+
+	// defaultSpoolTTL is the default duration for which a payload is spooled.
+	defaultSpoolTTL = 24 * time.Hour
+
+	// spoolFileExt is the extension of the spooled payload files.
+	spoolFileExt = ".ddtraces"
+
+	// spoolReplayMaxFiles is the maximum number of spooled payloads sent by
+	// a single replay, so that a large backlog is sent over several flushes.
+	spoolReplayMaxFiles = 20
+
+	// spoolReplayMaxDuration is the duration after which a replay stops
+	// sending spooled payloads, so that it doesn't delay the tracer shutdown.
+	spoolReplayMaxDuration = 5 * time.Second
+)
+
+// traceSpool keeps the trace payloads which could not be sent to the agent on
+// disk, and sends them again in the order they were spooled once the agent can
+// be reached again.
+//
+// Each payload is kept in its own file, named after the time it was spooled,
+// so that the lexical order of the files is the order in which they were
+// spooled, followed by the number of traces it holds. A file holds a msgpack
+// array made of the trace protocol of the payload, the number of traces it
+// contains, its v0.5 string dictionary (empty for v0.4) and its encoded traces.
+type traceSpool struct {
+	dir      string
+	maxSize  int64
+	maxFiles int
+	ttl      time.Duration
+	protocol float64
+
+	transport transport
+	statsd    globalinternal.StatsdClient
+
+	// mu guards the files of the spool directory.
+	mu  sync.Mutex
+	seq uint64
+
+	// replaying is set while spooled payloads are being sent.
+	replaying atomic.Bool
+
+	// replayMaxFiles and replayMaxDuration bound a single replay.
+	replayMaxFiles    int
+	replayMaxDuration time.Duration
+}
+
+// spoolFile describes a payload file found in the spool directory.
+type spoolFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+	traces  int64
+}
+
+// newTraceSpool returns the trace spool configured in c, or nil if the spool is
+// disabled or its directory can't be created.
+func newTraceSpool(c *config, statsd globalinternal.StatsdClient) *traceSpool {
+	if c.spoolDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(c.spoolDir, 0o700); err != nil {
+		log.Error("Trace spool disabled, cannot create directory %q: %v", c.spoolDir, err)
+		return nil
+	}
+	s := &traceSpool{
+		dir:       c.spoolDir,
+		maxSize:   c.spoolMaxSize,
+		maxFiles:  c.spoolMaxFiles,
+		ttl:       c.spoolTTL,
+		protocol:  c.traceProtocol,
+		transport: c.transport,
+		statsd:    statsd,
+
+		replayMaxFiles:    spoolReplayMaxFiles,
+		replayMaxDuration: spoolReplayMaxDuration,
+	}
+	if s.maxSize <= 0 {
+		s.maxSize = defaultSpoolMaxSize
+	}
+	if s.maxFiles <= 0 {
+		s.maxFiles = defaultSpoolMaxFiles
+	}
+	if s.ttl <= 0 {
+		s.ttl = defaultSpoolTTL
+	}
+	return s
+}
+
+// store writes the payload p to the spool, dropping the oldest spooled payloads
+// if needed to stay within the limits. It returns an error if p could not be
+// spooled.
+func (s *traceSpool) store(p *payload) error {
+	var dict []byte
+	if p.strings != nil {
+		dict = p.strings.buf.Bytes()
+	}
+	b := msgp.AppendArrayHeader(nil, 4)
+	b = msgp.AppendFloat64(b, s.protocol)
+	b = msgp.AppendUint32(b, uint32(p.itemCount()))
+	b = msgp.AppendBytes(b, dict)
+	b = msgp.AppendBytes(b, p.buf.Bytes())
+	if int64(len(b)) > s.maxSize {
+		return fmt.Errorf("payload of %d bytes exceeds the spool size limit of %d bytes", len(b), s.maxSize)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	for len(files) > 0 && (len(files) >= s.maxFiles || total+int64(len(b)) > s.maxSize) {
+		total -= files[0].size
+		s.drop(files[0], "spool_full")
+		files = files[1:]
+	}
+	s.seq++
+	name := fmt.Sprintf("%020d-%010d-%d", time.Now().UnixNano(), s.seq, p.itemCount())
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name+spoolFileExt)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// replay sends the spooled payloads to the agent, oldest first, stopping at the
+// first failure, or once s.replayMaxFiles payloads have been sent or
+// s.replayMaxDuration has elapsed. The remaining payloads are sent by the next
+// replay. Payloads which are sent successfully or which have expired are
+// removed from the spool. Only one replay runs at a time.
+func (s *traceSpool) replay() {
+	if !s.replaying.CompareAndSwap(false, true) {
+		return
+	}
+	defer s.replaying.Store(false)
+
+	s.mu.Lock()
+	files, err := s.files()
+	s.mu.Unlock()
+	if err != nil {
+		log.Error("Failed to list spooled traces: %v", err)
+		return
+	}
+	start := time.Now()
+	sent := 0
+	for _, f := range files {
+		if sent >= s.replayMaxFiles || time.Since(start) > s.replayMaxDuration {
+			return
+		}
+		if time.Since(f.modTime) > s.ttl {
+			s.mu.Lock()
+			s.drop(f, "spool_expired")
+			s.mu.Unlock()
+			continue
+		}
+		p, err := s.load(f.path)
+		if err != nil {
+			log.Error("Discarding spooled traces %q: %v", f.path, err)
+			s.mu.Lock()
+			s.drop(f, "spool_invalid")
+			s.mu.Unlock()
+			continue
+		}
+		rc, err := s.transport.send(p)
+		if err != nil {
+			log.Debug("Failed to send spooled traces, will retry later: %v", err)
+			return
+		}
+		rc.Close()
+		sent++
+		s.statsd.Count("datadog.tracer.spool.replayed_traces", int64(p.itemCount()), nil, 1)
+		s.mu.Lock()
+		os.Remove(f.path)
+		s.mu.Unlock()
+	}
+}
+
+// load reads the spooled payload from the file at path.
+func (s *traceSpool) load(path string) (*payload, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, b, err = msgp.ReadArrayHeaderBytes(b); err != nil {
+		return nil, err
+	}
+	var protocol float64
+	if protocol, b, err = msgp.ReadFloat64Bytes(b); err != nil {
+		return nil, err
+	}
+	if protocol != s.protocol {
+		return nil, fmt.Errorf("payload was encoded with trace protocol %v, using %v", protocol, s.protocol)
+	}
+	var count uint32
+	if count, b, err = msgp.ReadUint32Bytes(b); err != nil {
+		return nil, err
+	}
+	var dict, traces []byte
+	if dict, b, err = msgp.ReadBytesZC(b); err != nil {
+		return nil, err
+	}
+	if traces, _, err = msgp.ReadBytesZC(b); err != nil {
+		return nil, err
+	}
+	p := newPayload()
+	if protocol == traceProtocolV05 {
+		p = newPayloadV05()
+		for len(dict) > 0 {
+			var str string
+			if str, dict, err = msgp.ReadStringBytes(dict); err != nil {
+				return nil, err
+			}
+			p.strings.add(str)
+		}
+	}
+	p.buf.Write(traces)
+	atomic.StoreUint32(&p.count, count)
+	p.updateHeader()
+	return p, nil
+}
+
+// files returns the spooled payload files, oldest first. s.mu must be held.
+func (s *traceSpool) files() ([]spoolFile, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]spoolFile, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), spoolFileExt) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), spoolFileExt)
+		traces, _ := strconv.ParseInt(name[strings.LastIndexByte(name, '-')+1:], 10, 64)
+		files = append(files, spoolFile{
+			path:    filepath.Join(s.dir, e.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+			traces:  traces,
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files, nil
+}
+
+// drop removes the spooled payload f, reporting its traces as dropped for the
+// given reason. s.mu must be held.
+func (s *traceSpool) drop(f spoolFile, reason string) {
+	if err := os.Remove(f.path); err != nil {
+		if !os.IsNotExist(err) {
+			log.Error("Failed to remove spooled traces %q: %v", f.path, err)
+		}
+		// the traces were already sent or dropped
+		return
+	}
+	s.statsd.Count("datadog.tracer.traces_dropped", f.traces, []string{"reason:" + reason}, 1)
+}
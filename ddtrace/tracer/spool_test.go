@@ -0,0 +1,193 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/statsdtest"
+)
+
+// toggleTransport fails sending traces until it is told the agent is up.
+type toggleTransport struct {
+	dummyTransport
+	mu sync.Mutex
+	up bool
+}
+
+func (t *toggleTransport) setUp(up bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.up = up
+}
+
+func (t *toggleTransport) send(p *payload) (io.ReadCloser, error) {
+	t.mu.Lock()
+	up := t.up
+	t.mu.Unlock()
+	if !up {
+		return nil, errors.New("agent unreachable")
+	}
+	return t.dummyTransport.send(p)
+}
+
+func spooledFiles(t *testing.T, dir string) int {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	return len(entries)
+}
+
+func TestTraceSpool(t *testing.T) {
+	t.Run("replay", func(t *testing.T) {
+		assert := assert.New(t)
+		dir := t.TempDir()
+		tr := &toggleTransport{}
+		var statsd statsdtest.TestStatsdClient
+		c := newConfig(withTransport(tr), WithTraceSpool(dir, 0, 0, 0))
+		h := newAgentTraceWriter(c, newPrioritySampler(), &statsd)
+
+		for _, name := range []string{"first", "second"} {
+			h.add([]*span{newBasicSpan(name)})
+			h.flush()
+			h.wg.Wait()
+		}
+		assert.Equal(2, spooledFiles(t, dir))
+		assert.Equal(int64(2), statsd.Counts()["datadog.tracer.traces_spooled"])
+		assert.Zero(statsd.Counts()["datadog.tracer.traces_dropped"])
+
+		tr.setUp(true)
+		h.add([]*span{newBasicSpan("third")})
+		h.stop()
+
+		traces := tr.dummyTransport.traces
+		require.Len(t, traces, 3)
+		assert.Equal("third", traces[0][0].Name)
+		assert.Equal("first", traces[1][0].Name)
+		assert.Equal("second", traces[2][0].Name)
+		assert.Equal(0, spooledFiles(t, dir))
+		assert.Equal(int64(2), statsd.Counts()["datadog.tracer.spool.replayed_traces"])
+	})
+
+	t.Run("v0.5", func(t *testing.T) {
+		tr := &toggleTransport{up: true}
+		c := newConfig(withTransport(tr), WithTraceSpool(t.TempDir(), 0, 0, 0))
+		c.traceProtocol = traceProtocolV05
+		s := newTraceSpool(c, &statsdtest.TestStatsdClient{})
+
+		p := newPayloadV05()
+		p.push(newSpanList(3))
+		p.push(newSpanList(2))
+		want, err := io.ReadAll(p)
+		require.NoError(t, err)
+		p.reset()
+		require.NoError(t, s.store(p))
+
+		files, err := s.files()
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		assert.Equal(t, int64(2), files[0].traces)
+		got, err := s.load(files[0].path)
+		require.NoError(t, err)
+		assert.Equal(t, 2, got.itemCount())
+		b, err := io.ReadAll(got)
+		require.NoError(t, err)
+		assert.Equal(t, want, b)
+	})
+
+	t.Run("max-files", func(t *testing.T) {
+		dir := t.TempDir()
+		var statsd statsdtest.TestStatsdClient
+		c := newConfig(withTransport(&toggleTransport{}), WithTraceSpool(dir, 0, 2, 0))
+		s := newTraceSpool(c, &statsd)
+		for i := 0; i < 3; i++ {
+			p, err := encode([][]*span{{newBasicSpan("a")}, {newBasicSpan("b")}})
+			require.NoError(t, err)
+			require.NoError(t, s.store(p))
+		}
+		assert.Equal(t, 2, spooledFiles(t, dir))
+		calls := statsd.GetCallsByName("datadog.tracer.traces_dropped")
+		assert.Equal(t, int64(2), statsd.CountCallsByTag(calls, "reason:spool_full"))
+	})
+
+	t.Run("max-size", func(t *testing.T) {
+		dir := t.TempDir()
+		c := newConfig(withTransport(&toggleTransport{}), WithTraceSpool(dir, 10, 0, 0))
+		s := newTraceSpool(c, &statsdtest.TestStatsdClient{})
+		p, err := encode([][]*span{{newBasicSpan("a")}})
+		require.NoError(t, err)
+		assert.Error(t, s.store(p))
+		assert.Equal(t, 0, spooledFiles(t, dir))
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		dir := t.TempDir()
+		tr := &toggleTransport{up: true}
+		var statsd statsdtest.TestStatsdClient
+		c := newConfig(withTransport(tr), WithTraceSpool(dir, 0, 0, time.Millisecond))
+		s := newTraceSpool(c, &statsd)
+		p, err := encode([][]*span{{newBasicSpan("a")}})
+		require.NoError(t, err)
+		require.NoError(t, s.store(p))
+		time.Sleep(10 * time.Millisecond)
+
+		s.replay()
+		assert.Equal(t, 0, spooledFiles(t, dir))
+		assert.Equal(t, 0, tr.Len())
+		calls := statsd.GetCallsByName("datadog.tracer.traces_dropped")
+		assert.Equal(t, int64(1), statsd.CountCallsByTag(calls, "reason:spool_expired"))
+	})
+
+	t.Run("replay-bounded", func(t *testing.T) {
+		dir := t.TempDir()
+		tr := &toggleTransport{up: true}
+		c := newConfig(withTransport(tr), WithTraceSpool(dir, 0, 0, 0))
+		s := newTraceSpool(c, &statsdtest.TestStatsdClient{})
+		s.replayMaxFiles = 2
+		for i := 0; i < 3; i++ {
+			p, err := encode([][]*span{{newBasicSpan("a")}})
+			require.NoError(t, err)
+			require.NoError(t, s.store(p))
+		}
+		s.replay()
+		assert.Equal(t, 2, tr.Len())
+		assert.Equal(t, 1, spooledFiles(t, dir))
+		s.replay()
+		assert.Equal(t, 3, tr.Len())
+		assert.Equal(t, 0, spooledFiles(t, dir))
+	})
+
+	t.Run("drop-removed", func(t *testing.T) {
+		var statsd statsdtest.TestStatsdClient
+		c := newConfig(withTransport(&toggleTransport{}), WithTraceSpool(t.TempDir(), 0, 0, 0))
+		s := newTraceSpool(c, &statsd)
+		// a file removed concurrently isn't counted as dropped twice
+		s.drop(spoolFile{path: filepath.Join(s.dir, "missing"+spoolFileExt), traces: 3}, "spool_full")
+		assert.Empty(t, statsd.GetCallsByName("datadog.tracer.traces_dropped"))
+	})
+
+	t.Run("env", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv("DD_TRACE_SPOOL_DIR", dir)
+		t.Setenv("DD_TRACE_SPOOL_MAX_FILES", "5")
+		t.Setenv("DD_TRACE_SPOOL_TTL", "1h")
+		c := newConfig(withTransport(&toggleTransport{}))
+		s := newTraceSpool(c, &statsdtest.TestStatsdClient{})
+		require.NotNil(t, s)
+		assert.Equal(t, dir, s.dir)
+		assert.Equal(t, 5, s.maxFiles)
+		assert.Equal(t, time.Hour, s.ttl)
+		assert.Equal(t, int64(defaultSpoolMaxSize), s.maxSize)
+	})
+}
@@ -44,6 +44,10 @@ type concentrator struct {
 
 	aggregationKey stats.PayloadAggregationKey
 
+	// peerTags lists the tags from which the peer tags that stats are aggregated on
+	// are derived, on client, producer and consumer spans.
+	peerTags []string
+
 	wg           sync.WaitGroup        // waits for any active goroutines
 	bucketSize   int64                 // the size of a bucket in nanoseconds
 	stop         chan struct{}         // closing this channel triggers shutdown
@@ -51,6 +55,54 @@ type concentrator struct {
 	statsdClient internal.StatsdClient // statsd client for sending metrics.
 }
 
+// defaultPeerTags lists the tags from which peer tags are derived when the agent
+// doesn't advertise them, such as in agentless mode. It matches the agent's defaults.
+var defaultPeerTags = []string{
+	"_dd.base_service",
+	"active_record.db.vendor",
+	"amqp.destination",
+	"amqp.exchange",
+	"amqp.queue",
+	"aws.queue.name",
+	"aws.s3.bucket",
+	"bucketname",
+	"cassandra.keyspace",
+	"db.cassandra.contact.points",
+	"db.couchbase.seed.nodes",
+	"db.hostname",
+	"db.instance",
+	"db.name",
+	"db.namespace",
+	"db.system",
+	"db.type",
+	"dns.hostname",
+	"grpc.host",
+	"hostname",
+	"http.host",
+	"http.server_name",
+	"messaging.destination",
+	"messaging.destination.name",
+	"messaging.kafka.bootstrap.servers",
+	"messaging.rabbitmq.exchange",
+	"messaging.system",
+	"mongodb.db",
+	"msmq.queue.path",
+	"net.peer.name",
+	"network.destination.ip",
+	"network.destination.name",
+	"out.host",
+	"peer.hostname",
+	"peer.service",
+	"queuename",
+	"rpc.service",
+	"rpc.system",
+	"sequel.db.vendor",
+	"server.address",
+	"streamname",
+	"tablename",
+	"topicname",
+}
+
 type tracerStatSpan struct {
 	statSpan *stats.StatSpan
 	origin   string
@@ -60,9 +112,15 @@ type tracerStatSpan struct {
 // configuration c. It creates buckets of bucketSize nanoseconds duration.
 func newConcentrator(c *config, bucketSize int64, statsdClient internal.StatsdClient) *concentrator {
 	sCfg := &stats.SpanConcentratorConfig{
-		ComputeStatsBySpanKind: false,
+		// Compute stats on client, server, producer and consumer spans, as the agent does,
+		// so that the service map has the edges to the peers of the service.
+		ComputeStatsBySpanKind: true,
 		BucketInterval:         defaultStatsBucketSize,
 	}
+	peerTags := c.agent.peerTags
+	if len(peerTags) == 0 && c.agentless {
+		peerTags = defaultPeerTags
+	}
 	env := c.agent.defaultEnv
 	if c.env != "" {
 		env = c.env
@@ -94,6 +152,7 @@ func newConcentrator(c *config, bucketSize int64, statsdClient internal.StatsdCl
 		stopped:          1,
 		cfg:              c,
 		aggregationKey:   aggKey,
+		peerTags:         peerTags,
 		spanConcentrator: spanConcentrator,
 		statsdClient:     statsdClient,
 	}
@@ -160,13 +219,16 @@ func (c *concentrator) runIngester() {
 	}
 }
 
+// newTracerStatSpan returns the stats of s, aggregated on its service, resource,
+// name, type, span.kind, HTTP status code and peer tags, or false if no stats are
+// computed for s.
 func (c *concentrator) newTracerStatSpan(s *span, obfuscator *obfuscate.Obfuscator) (*tracerStatSpan, bool) {
 	resource := s.Resource
 	if c.shouldObfuscate() {
 		resource = obfuscatedResource(obfuscator, s.Type, s.Resource)
 	}
 	statSpan, ok := c.spanConcentrator.NewStatSpan(s.Service, resource,
-		s.Name, s.Type, s.ParentID, s.Start, s.Duration, s.Error, s.Meta, s.Metrics, c.cfg.agent.peerTags)
+		s.Name, s.Type, s.ParentID, s.Start, s.Duration, s.Error, s.Meta, s.Metrics, c.peerTags)
 	if !ok {
 		return nil, false
 	}
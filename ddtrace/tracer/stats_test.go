@@ -14,6 +14,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/obfuscate"
 	"github.com/DataDog/datadog-go/v5/statsd"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/civisibility/constants"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/civisibility/utils"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/statsdtest"
@@ -132,6 +133,60 @@ func TestConcentrator(t *testing.T) {
 	})
 }
 
+func TestConcentratorAggregation(t *testing.T) {
+	bucketSize := int64(500_000)
+	client := span{
+		Name:     "redis.command",
+		Start:    time.Now().UnixNano() + 3*bucketSize,
+		Duration: 1,
+		Meta: map[string]string{
+			ext.SpanKind:    ext.SpanKindClient,
+			ext.HTTPCode:    "503",
+			"peer.hostname": "cache-1",
+			"component":     "redis",
+		},
+	}
+
+	t.Run("span-kind", func(t *testing.T) {
+		c := newConcentrator(&config{env: "someEnv"}, bucketSize, &statsd.NoOpClientDirect{})
+		_, ok := c.newTracerStatSpan(&client, nil)
+		assert.True(t, ok)
+		internalSpan := span{Name: "internal", Meta: map[string]string{ext.SpanKind: ext.SpanKindInternal}}
+		_, ok = c.newTracerStatSpan(&internalSpan, nil)
+		assert.False(t, ok)
+	})
+
+	t.Run("dimensions", func(t *testing.T) {
+		transport := newDummyTransport()
+		c := newConcentrator(&config{transport: transport, env: "someEnv", agent: agentFeatures{peerTags: []string{"peer.hostname"}}}, bucketSize, &statsd.NoOpClientDirect{})
+		ss, ok := c.newTracerStatSpan(&client, nil)
+		assert.True(t, ok)
+		c.Start()
+		c.In <- ss
+		c.Stop()
+
+		actualStats := transport.Stats()
+		assert.Len(t, actualStats, 1)
+		assert.Len(t, actualStats[0].Stats, 1)
+		assert.Len(t, actualStats[0].Stats[0].Stats, 1)
+		gs := actualStats[0].Stats[0].Stats[0]
+		assert.Equal(t, ext.SpanKindClient, gs.SpanKind)
+		assert.EqualValues(t, 503, gs.HTTPStatusCode)
+		assert.Equal(t, []string{"peer.hostname:cache-1"}, gs.PeerTags)
+	})
+
+	t.Run("agentless-peer-tags", func(t *testing.T) {
+		c := newConcentrator(&config{agentless: true}, bucketSize, &statsd.NoOpClientDirect{})
+		assert.Equal(t, defaultPeerTags, c.peerTags)
+
+		c = newConcentrator(&config{agentless: true, agent: agentFeatures{peerTags: []string{"peer.service"}}}, bucketSize, &statsd.NoOpClientDirect{})
+		assert.Equal(t, []string{"peer.service"}, c.peerTags)
+
+		c = newConcentrator(&config{}, bucketSize, &statsd.NoOpClientDirect{})
+		assert.Empty(t, c.peerTags)
+	})
+}
+
 func TestShouldObfuscate(t *testing.T) {
 	bucketSize := int64(500_000)
 	tsp := newDummyTransport()
@@ -0,0 +1,268 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	globalinternal "gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/samplernames"
+)
+
+// defaultTailSamplingMaxSpans is the default maximum number of spans buffered
+// by the tail sampler.
+const defaultTailSamplingMaxSpans = 10000
+
+// TailSamplingRule is a rule evaluated by the tail sampler on complete traces.
+// A trace matches a rule when it matches all of the rule's conditions which
+// are set. A rule without any condition is ignored.
+type TailSamplingRule struct {
+	// Error matches the traces in which at least one span is errored.
+	Error bool
+
+	// MinDuration matches the traces lasting at least MinDuration, from the
+	// start of their first span to the end of their last span.
+	MinDuration time.Duration
+
+	// Tag matches the traces in which at least one span has the tag Tag, with
+	// the value TagValue if it is not empty.
+	Tag      string
+	TagValue string
+}
+
+// match reports whether the complete trace made of spans matches the rule.
+func (r *TailSamplingRule) match(spans []*span) bool {
+	if r.Error && !anySpan(spans, func(s *span) bool { return s.Error != 0 }) {
+		return false
+	}
+	if r.MinDuration > 0 && traceDuration(spans) < r.MinDuration {
+		return false
+	}
+	if r.Tag != "" && !anySpan(spans, func(s *span) bool {
+		v, ok := s.Meta[r.Tag]
+		if !ok {
+			_, ok = s.Metrics[r.Tag]
+			return ok && r.TagValue == ""
+		}
+		return r.TagValue == "" || v == r.TagValue
+	}) {
+		return false
+	}
+	return true
+}
+
+// empty reports whether the rule has no condition set.
+func (r *TailSamplingRule) empty() bool {
+	return !r.Error && r.MinDuration <= 0 && r.Tag == ""
+}
+
+// TailSamplingSpillPolicy specifies what the tail sampler does with the oldest
+// buffered spans when its buffer is full.
+type TailSamplingSpillPolicy int
+
+const (
+	// TailSamplingSpillFlush sends the buffered spans of the oldest incomplete
+	// trace with the sampling decision taken when the trace started.
+	TailSamplingSpillFlush TailSamplingSpillPolicy = iota
+
+	// TailSamplingSpillDrop drops the buffered spans of the oldest incomplete trace.
+	TailSamplingSpillDrop
+)
+
+// TailSamplingConfig configures the tail sampler enabled with WithTailSampling.
+type TailSamplingConfig struct {
+	// Rules are evaluated on every complete trace which wasn't kept when it
+	// started. A trace matching any of them is kept, with the sampling priority
+	// ext.PriorityUserKeep.
+	Rules []TailSamplingRule
+
+	// MaxBufferedSpans is the maximum number of spans held by the tail sampler
+	// for incomplete traces. It defaults to 10000.
+	MaxBufferedSpans int
+
+	// SpillPolicy specifies what to do with the oldest incomplete trace when
+	// the buffer is full. It defaults to TailSamplingSpillFlush.
+	SpillPolicy TailSamplingSpillPolicy
+}
+
+// tailSampler holds the chunks of the traces flushed before they complete, for
+// partial flushing, until the trace completes and its sampling decision can be
+// revised by evaluating the tail sampling rules on all of its spans.
+type tailSampler struct {
+	rules    []TailSamplingRule
+	maxSpans int
+	spill    TailSamplingSpillPolicy
+	statsd   globalinternal.StatsdClient
+
+	mu sync.Mutex
+	// pending holds the buffered chunks of the incomplete traces.
+	pending map[*trace][]*chunk
+	// order holds the incomplete traces, oldest first. It may contain traces
+	// which are no longer pending.
+	order []*trace
+	// spans is the number of buffered spans.
+	spans int
+}
+
+func newTailSampler(c TailSamplingConfig, statsd globalinternal.StatsdClient) *tailSampler {
+	ts := &tailSampler{
+		maxSpans: c.MaxBufferedSpans,
+		spill:    c.SpillPolicy,
+		statsd:   statsd,
+		pending:  make(map[*trace][]*chunk),
+	}
+	if ts.maxSpans <= 0 {
+		ts.maxSpans = defaultTailSamplingMaxSpans
+	}
+	for _, r := range c.Rules {
+		if r.empty() {
+			log.Warn("Ignoring tail sampling rule without any condition")
+			continue
+		}
+		ts.rules = append(ts.rules, r)
+	}
+	return ts
+}
+
+// sample processes the chunk ch of the trace t. Chunks of incomplete traces are
+// buffered, in which case sample returns nil. Once the trace is complete, it
+// returns a chunk holding all of its buffered spans, kept if the trace matches
+// any of the rules. Chunks of spilled traces are passed to push. t.mu must be held.
+func (ts *tailSampler) sample(t *trace, ch *chunk, final bool, push func(*chunk)) *chunk {
+	ts.mu.Lock()
+	chunks := ts.pending[t]
+	if !final {
+		if chunks == nil {
+			ts.order = append(ts.order, t)
+		}
+		ts.pending[t] = append(chunks, ch)
+		ts.spans += len(ch.spans)
+		spilled := ts.spillLocked()
+		ts.mu.Unlock()
+		for _, c := range spilled {
+			push(c)
+		}
+		return nil
+	}
+	if chunks != nil {
+		delete(ts.pending, t)
+		for _, c := range chunks {
+			ts.spans -= len(c.spans)
+		}
+		ts.compactLocked()
+	}
+	ts.mu.Unlock()
+
+	if len(chunks) > 0 {
+		spans := make([]*span, 0, len(ch.spans)*(len(chunks)+1))
+		for _, c := range chunks {
+			spans = append(spans, c.spans...)
+		}
+		ch.spans = append(spans, ch.spans...)
+	}
+	if p, ok := t.samplingPriorityLocked(); ok && p > 0 {
+		return ch // already kept
+	}
+	for i := range ts.rules {
+		if ts.rules[i].match(ch.spans) {
+			t.keepByTailSamplingLocked(ch.spans)
+			ch.willSend = true
+			ts.statsd.Incr("datadog.tracer.tail_sampling.kept", nil, 1)
+			break
+		}
+	}
+	return ch
+}
+
+// spillLocked evicts the oldest incomplete traces until the buffered spans fit
+// within the limit, returning the chunks which should be sent. ts.mu must be held.
+func (ts *tailSampler) spillLocked() []*chunk {
+	var spilled []*chunk
+	for ts.spans > ts.maxSpans && len(ts.order) > 0 {
+		t := ts.order[0]
+		ts.order = ts.order[1:]
+		chunks, ok := ts.pending[t]
+		if !ok {
+			continue
+		}
+		delete(ts.pending, t)
+		n := 0
+		for _, c := range chunks {
+			n += len(c.spans)
+		}
+		ts.spans -= n
+		ts.statsd.Count("datadog.tracer.tail_sampling.spilled_spans", int64(n), nil, 1)
+		if ts.spill == TailSamplingSpillDrop {
+			ts.statsd.Incr("datadog.tracer.traces_dropped", []string{"reason:tail_sampling_spill"}, 1)
+			continue
+		}
+		spilled = append(spilled, chunks...)
+	}
+	ts.compactLocked()
+	return spilled
+}
+
+// compactLocked removes the traces which are no longer pending from ts.order,
+// once they make up most of it. ts.mu must be held.
+func (ts *tailSampler) compactLocked() {
+	if len(ts.order) <= 2*len(ts.pending)+16 {
+		return
+	}
+	order := ts.order[:0]
+	for _, t := range ts.order {
+		if _, ok := ts.pending[t]; ok {
+			order = append(order, t)
+		}
+	}
+	clear(ts.order[len(order):])
+	ts.order = order
+}
+
+// keepByTailSamplingLocked overrides the sampling decision of the trace, once
+// it is complete, to keep it. spans are all the spans of the trace. t.mu must be held.
+func (t *trace) keepByTailSamplingLocked(spans []*span) {
+	locked := t.locked
+	t.locked = false
+	t.setSamplingPriorityLocked(ext.PriorityUserKeep, samplernames.TailSampling)
+	t.locked = locked
+	for _, s := range spans {
+		if _, ok := s.Metrics[keySamplingPriority]; ok {
+			s.setMetric(keySamplingPriority, ext.PriorityUserKeep)
+		}
+	}
+	spans[0].setMeta(keyDecisionMaker, samplerToDM(samplernames.TailSampling))
+}
+
+// anySpan reports whether fn returns true for any of spans.
+func anySpan(spans []*span, fn func(*span) bool) bool {
+	for _, s := range spans {
+		if fn(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// traceDuration returns the duration of the trace made of spans, from the
+// start of the first span to the end of the last one.
+func traceDuration(spans []*span) time.Duration {
+	if len(spans) == 0 {
+		return 0
+	}
+	start, end := spans[0].Start, spans[0].Start+spans[0].Duration
+	for _, s := range spans[1:] {
+		if s.Start < start {
+			start = s.Start
+		}
+		if e := s.Start + s.Duration; e > end {
+			end = e
+		}
+	}
+	return time.Duration(end - start)
+}
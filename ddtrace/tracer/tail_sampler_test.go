@@ -0,0 +1,141 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package tracer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/samplernames"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/statsdtest"
+)
+
+func TestTailSampling(t *testing.T) {
+	dropAll := WithSamplingRules([]SamplingRule{RateRule(0)})
+
+	t.Run("rules", func(t *testing.T) {
+		assert := assert.New(t)
+		tracer, transport, flush, stop := startTestTracer(t, dropAll, WithTailSampling(TailSamplingConfig{
+			Rules: []TailSamplingRule{
+				{Error: true},
+				{MinDuration: 2 * time.Second},
+				{Tag: "customer.tier", TagValue: "gold"},
+				{}, // ignored
+			},
+		}))
+		defer stop()
+
+		start := time.Now()
+		root := tracer.StartSpan("errored")
+		tracer.StartSpan("child", ChildOf(root.Context())).Finish(WithError(errors.New("boom")))
+		root.Finish()
+		tracer.StartSpan("slow", StartTime(start)).Finish(FinishTime(start.Add(3 * time.Second)))
+		tracer.StartSpan("fast", StartTime(start)).Finish(FinishTime(start.Add(time.Second)))
+		tracer.StartSpan("gold", Tag("customer.tier", "gold")).Finish()
+		tracer.StartSpan("silver", Tag("customer.tier", "silver")).Finish()
+		flush(5)
+
+		priorities := make(map[string]float64)
+		for _, trace := range transport.Traces() {
+			priorities[trace[0].Name] = trace[0].Metrics[keySamplingPriority]
+			if trace[0].Metrics[keySamplingPriority] == ext.PriorityUserKeep {
+				assert.Equal("-13", trace[0].Meta[keyDecisionMaker])
+			}
+		}
+		assert.Equal(map[string]float64{
+			"errored": ext.PriorityUserKeep,
+			"slow":    ext.PriorityUserKeep,
+			"fast":    ext.PriorityUserReject,
+			"gold":    ext.PriorityUserKeep,
+			"silver":  ext.PriorityUserReject,
+		}, priorities)
+	})
+
+	t.Run("kept", func(t *testing.T) {
+		tracer, transport, flush, stop := startTestTracer(t, WithTailSampling(TailSamplingConfig{
+			Rules: []TailSamplingRule{{Tag: "any"}},
+		}))
+		defer stop()
+
+		tracer.StartSpan("span", Tag("any", 1)).Finish()
+		flush(1)
+		s := transport.Traces()[0][0]
+		assert.Equal(t, float64(ext.PriorityAutoKeep), s.Metrics[keySamplingPriority])
+		assert.NotEqual(t, "-13", s.Meta[keyDecisionMaker])
+	})
+
+	t.Run("partial-flush", func(t *testing.T) {
+		assert := assert.New(t)
+		tracer, transport, flush, stop := startTestTracer(t, dropAll, WithPartialFlushing(2), WithTailSampling(TailSamplingConfig{
+			Rules: []TailSamplingRule{{Error: true}},
+		}))
+		defer stop()
+
+		root := tracer.StartSpan("root")
+		for i := 0; i < 4; i++ {
+			tracer.StartSpan("child", ChildOf(root.Context())).Finish()
+		}
+		assert.Equal(4, tracer.tailSampler.spans)
+		root.SetTag(ext.Error, true)
+		root.Finish()
+		flush(1)
+
+		traces := transport.Traces()
+		require.Len(t, traces, 1)
+		assert.Len(traces[0], 5)
+		for _, s := range traces[0] {
+			if p, ok := s.Metrics[keySamplingPriority]; ok {
+				assert.Equal(float64(ext.PriorityUserKeep), p)
+			}
+		}
+		assert.Zero(tracer.tailSampler.spans)
+		assert.Empty(tracer.tailSampler.pending)
+	})
+}
+
+func TestTailSamplerSpill(t *testing.T) {
+	newTrace := func() *trace {
+		s := newBasicSpan("span")
+		s.context.trace.setSamplingPriority(ext.PriorityAutoReject, samplernames.AgentRate)
+		return s.context.trace
+	}
+	newChunk := func(n int) *chunk {
+		return &chunk{spans: make([]*span, n)}
+	}
+
+	for _, tc := range []struct {
+		policy TailSamplingSpillPolicy
+		pushed int
+	}{
+		{policy: TailSamplingSpillFlush, pushed: 2},
+		{policy: TailSamplingSpillDrop, pushed: 0},
+	} {
+		var statsd statsdtest.TestStatsdClient
+		ts := newTailSampler(TailSamplingConfig{MaxBufferedSpans: 5, SpillPolicy: tc.policy}, &statsd)
+		var pushed []*chunk
+		push := func(c *chunk) { pushed = append(pushed, c) }
+
+		t1, t2 := newTrace(), newTrace()
+		assert.Nil(t, ts.sample(t1, newChunk(2), false, push))
+		assert.Nil(t, ts.sample(t1, newChunk(2), false, push))
+		assert.Nil(t, ts.sample(t2, newChunk(2), false, push))
+
+		// t1 is the oldest trace, it was spilled
+		assert.Len(t, pushed, tc.pushed)
+		assert.Equal(t, 2, ts.spans)
+		assert.Equal(t, int64(4), statsd.Counts()["datadog.tracer.tail_sampling.spilled_spans"])
+
+		ch := ts.sample(t2, newChunk(1), true, push)
+		require.NotNil(t, ch)
+		assert.Len(t, ch.spans, 3)
+		assert.Zero(t, ts.spans)
+	}
+}
@@ -41,6 +41,7 @@ func startTelemetry(c *config) {
 		{Name: "stats_computation_enabled", Value: c.canComputeStats()},
 		{Name: "dogstatsd_port", Value: c.agent.StatsdPort},
 		{Name: "lambda_mode", Value: c.logToStdout},
+		{Name: "trace_agentless_enabled", Value: c.agentless},
 		{Name: "send_retries", Value: c.sendRetries},
 		{Name: "retry_interval", Value: c.retryInterval},
 		{Name: "trace_startup_logs_enabled", Value: c.logStartup},
@@ -66,6 +67,11 @@ func startTelemetry(c *config) {
 		c.globalTags.toTelemetry(),
 		c.traceSampleRules.toTelemetry(),
 		{Name: "span_sample_rules", Value: c.spanRules},
+		{Name: "trace_baggage_max_items", Value: c.baggageMaxItems},
+		{Name: "trace_baggage_max_bytes", Value: c.baggageMaxBytes},
+		{Name: "trace_baggage_allowed_keys", Value: strings.Join(c.baggageAllowedKeys, ",")},
+		{Name: "trace_baggage_tag_keys", Value: strings.Join(c.baggageTagKeys, ",")},
+		{Name: "trace_baggage_tag_all_spans", Value: c.baggageTagsOnAllSpans},
 	}
 	var peerServiceMapping []string
 	for key, value := range c.peerServiceMappings {
@@ -117,6 +123,9 @@ func startTelemetry(c *config) {
 	if c.logToStdout || c.ciVisibilityAgentless {
 		cfg.APIKey = os.Getenv("DD_API_KEY")
 	}
+	if c.agentless {
+		cfg.APIKey = c.apiKey
+	}
 	client, err := telemetry.NewClient(c.serviceName, c.env, c.version, cfg)
 	if err != nil {
 		log.Debug("tracer: failed to create telemetry client: %v", err)
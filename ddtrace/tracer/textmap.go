@@ -13,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
@@ -137,6 +138,18 @@ type PropagatorConfig struct {
 	// BaggageHeader specifies the map key that will be used to store the baggage key-value pairs.
 	// It defaults to DefaultBaggageHeader.
 	BaggageHeader string
+
+	// BaggageMaxItems specifies the maximum number of baggage items injected in the
+	// baggage header, and extracted from a carrier. It defaults to 64.
+	BaggageMaxItems int
+
+	// BaggageMaxBytes specifies the maximum size in bytes of the baggage header
+	// injected, and of the baggage extracted from a carrier. It defaults to 8192.
+	BaggageMaxBytes int
+
+	// BaggageAllowedKeys lists the baggage keys accepted when extracting a span
+	// context from a carrier, other items being dropped. If empty, all keys are accepted.
+	BaggageAllowedKeys []string
 }
 
 // NewPropagator returns a new propagator which uses TextMap to inject
@@ -168,7 +181,13 @@ func NewPropagator(cfg *PropagatorConfig, propagators ...Propagator) Propagator
 	if cfg.BaggageHeader == "" {
 		cfg.BaggageHeader = DefaultBaggageHeader
 	}
-	cp := new(chainedPropagator)
+	if cfg.BaggageMaxItems <= 0 {
+		cfg.BaggageMaxItems = baggageMaxItems
+	}
+	if cfg.BaggageMaxBytes <= 0 {
+		cfg.BaggageMaxBytes = baggageMaxBytes
+	}
+	cp := &chainedPropagator{cfg: cfg}
 	cp.onlyExtractFirst = internal.BoolEnv("DD_TRACE_PROPAGATION_EXTRACT_FIRST", false)
 	if len(propagators) > 0 {
 		cp.injectors = propagators
@@ -196,6 +215,7 @@ func NewPropagator(cfg *PropagatorConfig, propagators ...Propagator) Propagator
 // When injecting, all injectors are called to propagate the span context.
 // When extracting, it tries each extractor, selecting the first successful one.
 type chainedPropagator struct {
+	cfg              *PropagatorConfig
 	injectors        []Propagator
 	extractors       []Propagator
 	injectorNames    string
@@ -209,7 +229,7 @@ type chainedPropagator struct {
 // a warning and be ignored.
 func getPropagators(cfg *PropagatorConfig, ps string) ([]Propagator, string) {
 	dd := &propagator{cfg}
-	defaultPs := []Propagator{dd, &propagatorW3c{}, &propagatorBaggage{}}
+	defaultPs := []Propagator{dd, &propagatorW3c{}, &propagatorBaggage{cfg}}
 	defaultPsName := "datadog,tracecontext,baggage"
 	if cfg.B3 {
 		defaultPs = append(defaultPs, &propagatorB3{})
@@ -241,7 +261,7 @@ func getPropagators(cfg *PropagatorConfig, ps string) ([]Propagator, string) {
 			list = append(list, &propagatorW3c{})
 			listNames = append(listNames, v)
 		case "baggage":
-			list = append(list, &propagatorBaggage{})
+			list = append(list, &propagatorBaggage{cfg})
 			listNames = append(listNames, v)
 		case "b3", "b3multi":
 			if !cfg.B3 {
@@ -252,6 +272,12 @@ func getPropagators(cfg *PropagatorConfig, ps string) ([]Propagator, string) {
 		case "b3 single header":
 			list = append(list, &propagatorB3SingleHeader{})
 			listNames = append(listNames, v)
+		case "xray":
+			list = append(list, &propagatorXRay{})
+			listNames = append(listNames, v)
+		case "jaeger":
+			list = append(list, &propagatorJaeger{cfg})
+			listNames = append(listNames, v)
 		case "none":
 			log.Warn("Propagator \"none\" has no effect when combined with other propagators. " +
 				"To disable the propagator, set to `none`")
@@ -312,6 +338,9 @@ func (p *chainedPropagator) Extract(carrier interface{}) (ddtrace.SpanContext, e
 				}
 			}
 			if p.onlyExtractFirst {
+				if ctx, ok := extractedCtx.(*spanContext); ok {
+					limitExtractedBaggage(p.cfg, ctx)
+				}
 				return extractedCtx, nil
 			}
 			ctx = extractedCtx
@@ -337,6 +366,16 @@ func (p *chainedPropagator) Extract(carrier interface{}) (ddtrace.SpanContext, e
 						overrideDatadogParentID(ctx2, extractedCtx2, ddCtx)
 					}
 				}
+				if _, ok := v.(*propagatorJaeger); ok {
+					// Jaeger baggage travels in its own uberctx-* headers: keep the
+					// items which weren't already extracted for the same trace.
+					extractedCtx2.ForeachBaggageItem(func(k, v string) bool {
+						if ctx2.baggageItem(k) == "" {
+							ctx2.setBaggageItem(k, v)
+						}
+						return true
+					})
+				}
 			} else { // Trace IDs do not match - create span links
 				link := ddtrace.SpanLink{TraceID: extractedCtx2.TraceID(), SpanID: extractedCtx2.SpanID(), TraceIDHigh: extractedCtx2.TraceIDUpper(), Attributes: map[string]string{"reason": "terminated_context", "context_headers": getPropagatorName(v)}}
 				if trace := extractedCtx2.trace; trace != nil {
@@ -365,8 +404,11 @@ func (p *chainedPropagator) Extract(carrier interface{}) (ddtrace.SpanContext, e
 	if ctx == nil {
 		return nil, ErrSpanContextNotFound
 	}
-	if spCtx, ok := ctx.(*spanContext); ok && len(links) > 0 {
-		spCtx.spanLinks = links
+	if spCtx, ok := ctx.(*spanContext); ok {
+		if len(links) > 0 {
+			spCtx.spanLinks = links
+		}
+		limitExtractedBaggage(p.cfg, spCtx)
 	}
 	log.Debug("Extracted span context: %#v", ctx)
 	return ctx, nil
@@ -384,6 +426,10 @@ func getPropagatorName(p Propagator) string {
 		return "tracecontext"
 	case *propagatorBaggage:
 		return "baggage"
+	case *propagatorXRay:
+		return "xray"
+	case *propagatorJaeger:
+		return "jaeger"
 	default:
 		return ""
 	}
@@ -446,11 +492,8 @@ func (p *propagator) injectTextMap(spanCtx ddtrace.SpanContext, writer TextMapWr
 	if ctx.origin != "" {
 		writer.Set(originHeader, ctx.origin)
 	}
-	ctx.ForeachBaggageItem(func(k, v string) bool {
-		// Propagate OpenTracing baggage.
-		writer.Set(p.cfg.BaggagePrefix+k, v)
-		return true
-	})
+	// Propagate OpenTracing baggage.
+	injectBaggageHeaders(p.cfg, "datadog", ctx, writer, p.cfg.BaggagePrefix, nil)
 	if p.cfg.MaxTagsHeaderLen <= 0 {
 		return nil
 	}
@@ -1345,7 +1388,9 @@ func urlEncode(input string, safeCharacters string) string {
 
 // propagatorBaggage implements Propagator and injects/extracts span contexts
 // using baggage headers.
-type propagatorBaggage struct{}
+type propagatorBaggage struct {
+	cfg *PropagatorConfig
+}
 
 func (p *propagatorBaggage) Inject(spanCtx ddtrace.SpanContext, carrier interface{}) error {
 	switch c := carrier.(type) {
@@ -1359,14 +1404,15 @@ func (p *propagatorBaggage) Inject(spanCtx ddtrace.SpanContext, carrier interfac
 // injectTextMap propagates baggage items from the span context into the writer,
 // in the format of a single HTTP "baggage" header. Baggage consists of key=value pairs,
 // separated by commas. This function enforces a maximum number of baggage items and a maximum overall size.
-// If either limit is exceeded, excess items or bytes are dropped, and a warning is logged.
+// If either limit is exceeded, excess items or bytes are dropped, a warning is logged,
+// and the truncation is reported through telemetry.
 //
 // Example of a single "baggage" header:
 // baggage: foo=bar,baz=qux
 //
 // Each key and value pair is encoded and added to the existing baggage header in <key>=<value> format,
-// joined together by commas,
-func (*propagatorBaggage) injectTextMap(spanCtx ddtrace.SpanContext, writer TextMapWriter) error {
+// joined together by commas, in the order of the keys.
+func (p *propagatorBaggage) injectTextMap(spanCtx ddtrace.SpanContext, writer TextMapWriter) error {
 	ctx, _ := spanCtx.(*spanContext)
 	if ctx == nil {
 		return nil
@@ -1385,33 +1431,15 @@ func (*propagatorBaggage) injectTextMap(spanCtx ddtrace.SpanContext, writer Text
 		return nil
 	}
 
+	l := newBaggageLimiter(p.cfg)
 	baggageItems := make([]string, 0, len(baggageCopy))
-	totalSize := 0
-	count := 0
-
-	for key, value := range baggageCopy {
-		if count >= baggageMaxItems {
-			log.Warn("Baggage item limit exceeded. Only the first %d items will be propagated.", baggageMaxItems)
-			break
-		}
-
-		encodedKey := encodeKey(key)
-		encodedValue := encodeValue(value)
-		item := fmt.Sprintf("%s=%s", encodedKey, encodedValue)
-
-		itemSize := len(item)
-		if count > 0 {
-			itemSize++ // account for the comma separator
-		}
-
-		if totalSize+itemSize > baggageMaxBytes {
-			log.Warn("Baggage size limit exceeded. Only the first %d bytes will be propagated.", baggageMaxBytes)
+	for _, key := range sortedKeys(baggageCopy) {
+		item := encodeKey(key) + "=" + encodeValue(baggageCopy[key])
+		if !l.add(item) {
+			log.Warn("Baggage %s limit exceeded. Only the first %d items and %d bytes will be propagated.", l.truncated, l.maxItems, l.maxBytes)
 			break
 		}
-
 		baggageItems = append(baggageItems, item)
-		totalSize += itemSize
-		count++
 	}
 
 	if len(baggageItems) > 0 {
@@ -1430,7 +1458,10 @@ func (p *propagatorBaggage) Extract(carrier interface{}) (ddtrace.SpanContext, e
 	}
 }
 
-func (*propagatorBaggage) extractTextMap(reader TextMapReader) (ddtrace.SpanContext, error) {
+// extractTextMap extracts the baggage items of the "baggage" header of the reader.
+// Items whose key isn't allowed are dropped, and items exceeding the limits of the
+// configuration are truncated, in the order of the header.
+func (p *propagatorBaggage) extractTextMap(reader TextMapReader) (ddtrace.SpanContext, error) {
 	var baggageHeader string
 	var ctx spanContext
 	err := reader.ForeachKey(func(k, v string) error {
@@ -1449,6 +1480,7 @@ func (*propagatorBaggage) extractTextMap(reader TextMapReader) (ddtrace.SpanCont
 		return &ctx, nil
 	}
 
+	l := newBaggageLimiter(p.cfg)
 	pairs := strings.Split(baggageHeader, ",")
 	for _, pair := range pairs {
 		pair = strings.TrimSpace(pair)
@@ -1466,6 +1498,17 @@ func (*propagatorBaggage) extractTextMap(reader TextMapReader) (ddtrace.SpanCont
 		if errKey != nil || errVal != nil {
 			return nil, fmt.Errorf("Invalid baggage item: %s", pair)
 		}
+		if !l.allowed(decKey) {
+			continue
+		}
+		if l.truncated != "" {
+			// keep validating the rest of the header
+			continue
+		}
+		if !l.add(encodeKey(decKey) + "=" + encodeValue(decVal)) {
+			log.Debug("Baggage %s limit exceeded. Only the first %d items and %d bytes will be extracted.", l.truncated, l.maxItems, l.maxBytes)
+			continue
+		}
 		ctx.baggage[decKey] = decVal
 	}
 	if len(ctx.baggage) > 0 {
@@ -1473,3 +1516,260 @@ func (*propagatorBaggage) extractTextMap(reader TextMapReader) (ddtrace.SpanCont
 	}
 	return &ctx, nil
 }
+
+const (
+	xrayTraceHeader = "x-amzn-trace-id"
+
+	xrayRootKey    = "Root"
+	xrayParentKey  = "Parent"
+	xraySampledKey = "Sampled"
+	xrayVersion    = "1"
+)
+
+// propagatorXRay implements Propagator and injects/extracts span contexts
+// using the AWS X-Ray trace header. Only TextMap carriers are supported.
+// See https://docs.aws.amazon.com/xray/latest/devguide/xray-concepts.html#xray-concepts-tracingheader
+type propagatorXRay struct{}
+
+func (p *propagatorXRay) Inject(spanCtx ddtrace.SpanContext, carrier interface{}) error {
+	switch c := carrier.(type) {
+	case TextMapWriter:
+		return p.injectTextMap(spanCtx, c)
+	default:
+		return ErrInvalidCarrier
+	}
+}
+
+// injectTextMap propagates span context attributes into the writer, in the format
+// of the X-Amzn-Trace-Id header: `Root=1-{epoch}-{unique};Parent={spanID};Sampled={0|1}`.
+// The 8 hex-encoded digits of the epoch and the 24 of the unique identifier are the
+// 128-bit trace ID, the upper bits of which hold the epoch of the trace in the IDs
+// generated by the tracer. When the upper bits are unset, as with 64-bit trace IDs,
+// the epoch is the start time of the trace instead, as X-Ray rejects a zero epoch.
+func (*propagatorXRay) injectTextMap(spanCtx ddtrace.SpanContext, writer TextMapWriter) error {
+	ctx, ok := spanCtx.(*spanContext)
+	if !ok || ctx.traceID.Empty() || ctx.spanID == 0 {
+		return ErrInvalidSpanContext
+	}
+	traceID := ctx.TraceID128()
+	epoch := traceID[:8]
+	if !ctx.traceID.HasUpper() {
+		epoch = fmt.Sprintf("%08x", uint32(xrayEpoch(ctx)))
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s=%s-%s-%s;%s=%016x", xrayRootKey, xrayVersion, epoch, traceID[8:], xrayParentKey, ctx.spanID))
+	if p, ok := ctx.SamplingPriority(); ok {
+		if p >= ext.PriorityAutoKeep {
+			sb.WriteString(";" + xraySampledKey + "=1")
+		} else {
+			sb.WriteString(";" + xraySampledKey + "=0")
+		}
+	}
+	writer.Set(xrayTraceHeader, sb.String())
+	return nil
+}
+
+// xrayEpoch returns the start time of the trace of ctx in seconds, taken from
+// the root span so that all the spans of the trace inject the same epoch.
+func xrayEpoch(ctx *spanContext) int64 {
+	switch {
+	case ctx.trace != nil && ctx.trace.root != nil:
+		return ctx.trace.root.Start / int64(time.Second)
+	case ctx.span != nil:
+		return ctx.span.Start / int64(time.Second)
+	default:
+		return now() / int64(time.Second)
+	}
+}
+
+func (p *propagatorXRay) Extract(carrier interface{}) (ddtrace.SpanContext, error) {
+	switch c := carrier.(type) {
+	case TextMapReader:
+		return p.extractTextMap(c)
+	default:
+		return nil, ErrInvalidCarrier
+	}
+}
+
+func (*propagatorXRay) extractTextMap(reader TextMapReader) (ddtrace.SpanContext, error) {
+	var ctx spanContext
+	err := reader.ForeachKey(func(k, v string) error {
+		if strings.ToLower(k) != xrayTraceHeader {
+			return nil
+		}
+		return parseXRayTraceHeader(&ctx, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ctx.traceID.Empty() || ctx.spanID == 0 {
+		return nil, ErrSpanContextNotFound
+	}
+	return &ctx, nil
+}
+
+// parseXRayTraceHeader parses the semicolon-separated key=value pairs of an
+// X-Amzn-Trace-Id header into ctx. Unknown keys, such as Self or Lineage, are ignored.
+// A Sampled value of "?" defers the sampling decision, leaving the priority unset.
+func parseXRayTraceHeader(ctx *spanContext, header string) error {
+	for _, part := range strings.Split(header, ";") {
+		key, val, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case xrayRootKey:
+			// Root=1-{8 hex digits epoch}-{24 hex digits unique identifier}
+			fields := strings.Split(val, "-")
+			if len(fields) != 3 || fields[0] != xrayVersion || len(fields[1]) != 8 || len(fields[2]) != 24 {
+				return ErrSpanContextCorrupted
+			}
+			tid := fields[1] + fields[2]
+			if !isValidID(tid) {
+				return ErrSpanContextCorrupted
+			}
+			if err := extractTraceID128(ctx, tid); err != nil {
+				return err
+			}
+		case xrayParentKey:
+			if len(val) != 16 || !isValidID(val) {
+				return ErrSpanContextCorrupted
+			}
+			var err error
+			if ctx.spanID, err = strconv.ParseUint(val, 16, 64); err != nil {
+				return ErrSpanContextCorrupted
+			}
+		case xraySampledKey:
+			switch val {
+			case "1":
+				ctx.setSamplingPriority(ext.PriorityAutoKeep, samplernames.Unknown)
+			case "0":
+				ctx.setSamplingPriority(ext.PriorityAutoReject, samplernames.Unknown)
+			case "?":
+			default:
+				return ErrSpanContextCorrupted
+			}
+		}
+	}
+	return nil
+}
+
+const (
+	jaegerTraceHeader   = "uber-trace-id"
+	jaegerBaggagePrefix = "uberctx-"
+
+	jaegerFlagSampled = 0x1
+	jaegerFlagDebug   = 0x2
+)
+
+// propagatorJaeger implements Propagator and injects/extracts span contexts
+// using the Jaeger uber-trace-id header, and baggage using uberctx-* headers.
+// Only TextMap carriers are supported.
+// See https://www.jaegertracing.io/docs/1.21/client-libraries/#propagation-format
+type propagatorJaeger struct {
+	cfg *PropagatorConfig
+}
+
+func (p *propagatorJaeger) Inject(spanCtx ddtrace.SpanContext, carrier interface{}) error {
+	switch c := carrier.(type) {
+	case TextMapWriter:
+		return p.injectTextMap(spanCtx, c)
+	default:
+		return ErrInvalidCarrier
+	}
+}
+
+// injectTextMap propagates span context attributes into the writer, in the format
+// of the uber-trace-id header: `{traceID}:{spanID}:{parentSpanID}:{flags}`. The
+// deprecated parent span ID is always 0. Baggage items are propagated as URL-encoded
+// values of uberctx-{key} headers, within the baggage limits.
+func (p *propagatorJaeger) injectTextMap(spanCtx ddtrace.SpanContext, writer TextMapWriter) error {
+	ctx, ok := spanCtx.(*spanContext)
+	if !ok || ctx.traceID.Empty() || ctx.spanID == 0 {
+		return ErrInvalidSpanContext
+	}
+	var traceID string
+	if !ctx.traceID.HasUpper() { // 64-bit trace id
+		traceID = fmt.Sprintf("%016x", ctx.traceID.Lower())
+	} else { // 128-bit trace id
+		traceID = ctx.TraceID128()
+	}
+	flags := 0
+	if p, ok := ctx.SamplingPriority(); ok && p >= ext.PriorityAutoKeep {
+		flags = jaegerFlagSampled
+	}
+	writer.Set(jaegerTraceHeader, fmt.Sprintf("%s:%016x:0:%x", traceID, ctx.spanID, flags))
+	injectBaggageHeaders(p.cfg, "jaeger", ctx, writer, jaegerBaggagePrefix, url.QueryEscape)
+	return nil
+}
+
+func (p *propagatorJaeger) Extract(carrier interface{}) (ddtrace.SpanContext, error) {
+	switch c := carrier.(type) {
+	case TextMapReader:
+		return p.extractTextMap(c)
+	default:
+		return nil, ErrInvalidCarrier
+	}
+}
+
+func (*propagatorJaeger) extractTextMap(reader TextMapReader) (ddtrace.SpanContext, error) {
+	var ctx spanContext
+	err := reader.ForeachKey(func(k, v string) error {
+		key := strings.ToLower(k)
+		switch {
+		case key == jaegerTraceHeader:
+			return parseJaegerTraceHeader(&ctx, v)
+		case strings.HasPrefix(key, jaegerBaggagePrefix):
+			if unescaped, err := url.QueryUnescape(v); err == nil {
+				v = unescaped
+			}
+			ctx.setBaggageItem(strings.TrimPrefix(key, jaegerBaggagePrefix), v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ctx.traceID.Empty() || ctx.spanID == 0 {
+		return nil, ErrSpanContextNotFound
+	}
+	return &ctx, nil
+}
+
+// parseJaegerTraceHeader parses an uber-trace-id header into ctx. The header may be
+// URL-encoded, and its trace ID may be 64 or 128 bits long, with or without leading zeroes.
+// Both the sampled and the debug flags result in the trace being kept.
+func parseJaegerTraceHeader(ctx *spanContext, header string) error {
+	if strings.Contains(header, "%") {
+		unescaped, err := url.QueryUnescape(header)
+		if err != nil {
+			return ErrSpanContextCorrupted
+		}
+		header = unescaped
+	}
+	parts := strings.Split(strings.ToLower(header), ":")
+	if len(parts) != 4 {
+		return ErrSpanContextCorrupted
+	}
+	tid, sid := parts[0], parts[1]
+	if tid == "" || len(tid) > 32 || !isValidID(tid) || sid == "" || len(sid) > 16 || !isValidID(sid) {
+		return ErrSpanContextCorrupted
+	}
+	if err := extractTraceID128(ctx, tid); err != nil {
+		return err
+	}
+	var err error
+	if ctx.spanID, err = strconv.ParseUint(sid, 16, 64); err != nil {
+		return ErrSpanContextCorrupted
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return ErrSpanContextCorrupted
+	}
+	if flags&(jaegerFlagSampled|jaegerFlagDebug) != 0 {
+		ctx.setSamplingPriority(ext.PriorityAutoKeep, samplernames.Unknown)
+	} else {
+		ctx.setSamplingPriority(ext.PriorityAutoReject, samplernames.Unknown)
+	}
+	return nil
+}
@@ -15,6 +15,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -2228,6 +2229,10 @@ func TestOtelPropagator(t *testing.T) {
 			env:    "jaegar",
 			result: "datadog,tracecontext,baggage",
 		},
+		{
+			env:    "xray, jaeger",
+			result: "xray,jaeger",
+		},
 	}
 	for _, test := range tests {
 		t.Setenv(otelHeaderPropagationStyle, test.env)
@@ -2808,3 +2813,237 @@ func TestInjectBaggageMaxBytes(t *testing.T) {
 	headerSize := len([]byte(headerValue))
 	assert.LessOrEqual(headerSize, baggageMaxBytes)
 }
+
+func TestXRayPropagator(t *testing.T) {
+	t.Run("inject", func(t *testing.T) {
+		t.Setenv(headerPropagationStyleInject, "xray")
+		tracer := newTracer()
+		defer tracer.Stop()
+		assert := assert.New(t)
+
+		root := tracer.StartSpan("web.request").(*span)
+		root.context.traceID.SetUpper(0x5759e98800000000)
+		root.context.traceID.SetLower(0xbd862e3fe1be46a9)
+		root.SpanID = 0x53995c3f42cd8ad8
+		root.context.spanID = root.SpanID
+		root.SetTag(ext.ManualKeep, true)
+		carrier := TextMapCarrier{}
+		assert.NoError(tracer.Inject(root.Context(), carrier))
+		assert.Equal("Root=1-5759e988-00000000bd862e3fe1be46a9;Parent=53995c3f42cd8ad8;Sampled=1", carrier[xrayTraceHeader])
+
+		root.SetTag(ext.ManualDrop, true)
+		assert.NoError(tracer.Inject(root.Context(), carrier))
+		assert.Equal("Root=1-5759e988-00000000bd862e3fe1be46a9;Parent=53995c3f42cd8ad8;Sampled=0", carrier[xrayTraceHeader])
+	})
+
+	t.Run("inject-64bit", func(t *testing.T) {
+		t.Setenv(headerPropagationStyleInject, "xray")
+		tracer := newTracer()
+		defer tracer.Stop()
+		assert := assert.New(t)
+
+		root := tracer.StartSpan("web.request", StartTime(time.Unix(0x5759e988, 5e8))).(*span)
+		root.context.traceID = traceIDFrom64Bits(0xbd862e3fe1be46a9)
+		root.SetTag(ext.ManualKeep, true)
+		carrier := TextMapCarrier{}
+		assert.NoError(tracer.Inject(root.Context(), carrier))
+		assert.Equal(fmt.Sprintf("Root=1-5759e988-00000000bd862e3fe1be46a9;Parent=%016x;Sampled=1", root.SpanID), carrier[xrayTraceHeader])
+
+		// the epoch is the start time of the trace, not of the injecting span
+		child := tracer.StartSpan("db.query", ChildOf(root.Context()), StartTime(time.Unix(0x5759f000, 0))).(*span)
+		assert.NoError(tracer.Inject(child.Context(), carrier))
+		assert.Equal(fmt.Sprintf("Root=1-5759e988-00000000bd862e3fe1be46a9;Parent=%016x;Sampled=1", child.SpanID), carrier[xrayTraceHeader])
+	})
+
+	t.Run("extract", func(t *testing.T) {
+		t.Setenv(headerPropagationStyleExtract, "xray")
+		tracer := newTracer()
+		defer tracer.Stop()
+
+		tests := []struct {
+			header   string
+			tid      string
+			sid      uint64
+			priority int
+			sampled  bool
+		}{
+			{
+				header:   "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+				tid:      "5759e988bd862e3fe1be46a994272793",
+				sid:      0x53995c3f42cd8ad8,
+				priority: 1,
+				sampled:  true,
+			},
+			{
+				header:   "Root=1-5759e988-bd862e3fe1be46a994272793; Parent=53995c3f42cd8ad8; Sampled=0; Lineage=a87bd80c:1",
+				tid:      "5759e988bd862e3fe1be46a994272793",
+				sid:      0x53995c3f42cd8ad8,
+				priority: 0,
+				sampled:  true,
+			},
+			{
+				header: "Self=1-67891234-12456789abcdef012345678;Root=1-00000000-000000000000000000000001;Parent=0000000000000002;Sampled=?",
+				tid:    "00000000000000000000000000000001",
+				sid:    2,
+			},
+		}
+		for _, tc := range tests {
+			t.Run(tc.header, func(t *testing.T) {
+				assert := assert.New(t)
+				ctx, err := tracer.Extract(HTTPHeadersCarrier{"X-Amzn-Trace-Id": []string{tc.header}})
+				assert.NoError(err)
+				sctx, ok := ctx.(*spanContext)
+				assert.True(ok)
+				assert.Equal(tc.tid, sctx.TraceID128())
+				assert.Equal(tc.sid, sctx.SpanID())
+				p, ok := sctx.SamplingPriority()
+				assert.Equal(tc.sampled, ok)
+				assert.Equal(tc.priority, p)
+			})
+		}
+	})
+
+	t.Run("extract invalid", func(t *testing.T) {
+		t.Setenv(headerPropagationStyleExtract, "xray")
+		tracer := newTracer()
+		defer tracer.Stop()
+
+		tests := []struct {
+			header string
+			err    error
+		}{
+			{"", ErrSpanContextNotFound},
+			{"Root=1-5759e988-bd862e3fe1be46a994272793", ErrSpanContextNotFound},
+			{"Root=2-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8", ErrSpanContextCorrupted},
+			{"Root=1-5759e988-bd862e3fe1be46a99427279;Parent=53995c3f42cd8ad8", ErrSpanContextCorrupted},
+			{"Root=1-5759e988-bd862e3fe1be46a99427279z;Parent=53995c3f42cd8ad8", ErrSpanContextCorrupted},
+			{"Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad", ErrSpanContextCorrupted},
+			{"Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=yes", ErrSpanContextCorrupted},
+		}
+		for _, tc := range tests {
+			t.Run(tc.header, func(t *testing.T) {
+				_, err := tracer.Extract(TextMapCarrier{xrayTraceHeader: tc.header})
+				assert.Equal(t, tc.err, err)
+			})
+		}
+	})
+}
+
+func TestJaegerPropagator(t *testing.T) {
+	t.Run("inject", func(t *testing.T) {
+		t.Setenv(headerPropagationStyleInject, "jaeger")
+		tracer := newTracer()
+		defer tracer.Stop()
+		assert := assert.New(t)
+
+		root := tracer.StartSpan("web.request").(*span)
+		root.context.traceID.SetUpper(0)
+		root.context.traceID.SetLower(0xbd862e3fe1be46a9)
+		root.SpanID = 0x53995c3f42cd8ad8
+		root.context.spanID = root.SpanID
+		root.SetTag(ext.ManualKeep, true)
+		root.SetBaggageItem("user", "a b,c")
+		carrier := TextMapCarrier{}
+		assert.NoError(tracer.Inject(root.Context(), carrier))
+		assert.Equal("bd862e3fe1be46a9:53995c3f42cd8ad8:0:1", carrier[jaegerTraceHeader])
+		assert.Equal("a+b%2Cc", carrier["uberctx-user"])
+
+		root.context.traceID.SetUpper(0x5759e98800000000)
+		root.SetTag(ext.ManualDrop, true)
+		assert.NoError(tracer.Inject(root.Context(), carrier))
+		assert.Equal("5759e98800000000bd862e3fe1be46a9:53995c3f42cd8ad8:0:0", carrier[jaegerTraceHeader])
+	})
+
+	t.Run("extract", func(t *testing.T) {
+		t.Setenv(headerPropagationStyleExtract, "jaeger")
+		tracer := newTracer()
+		defer tracer.Stop()
+
+		tests := []struct {
+			header   string
+			tid      string
+			sid      uint64
+			priority int
+		}{
+			{"bd862e3fe1be46a9:53995c3f42cd8ad8:0:1", "0000000000000000bd862e3fe1be46a9", 0x53995c3f42cd8ad8, 1},
+			{"5759e98800000000bd862e3fe1be46a9:53995c3f42cd8ad8:0:0", "5759e98800000000bd862e3fe1be46a9", 0x53995c3f42cd8ad8, 0},
+			{"1:2:0:3", "00000000000000000000000000000001", 2, 1},
+			{"ABC:2:1:2", "00000000000000000000000000000abc", 2, 1},
+			{"abc%3A2%3A0%3A1", "00000000000000000000000000000abc", 2, 1},
+		}
+		for _, tc := range tests {
+			t.Run(tc.header, func(t *testing.T) {
+				assert := assert.New(t)
+				ctx, err := tracer.Extract(HTTPHeadersCarrier{
+					"Uber-Trace-Id":   []string{tc.header},
+					"Uberctx-Account": []string{"a+b%2Cc"},
+				})
+				assert.NoError(err)
+				sctx, ok := ctx.(*spanContext)
+				assert.True(ok)
+				assert.Equal(tc.tid, sctx.TraceID128())
+				assert.Equal(tc.sid, sctx.SpanID())
+				p, ok := sctx.SamplingPriority()
+				assert.True(ok)
+				assert.Equal(tc.priority, p)
+				assert.Equal("a b,c", sctx.baggageItem("account"))
+			})
+		}
+	})
+
+	t.Run("extract invalid", func(t *testing.T) {
+		t.Setenv(headerPropagationStyleExtract, "jaeger")
+		tracer := newTracer()
+		defer tracer.Stop()
+
+		tests := []struct {
+			header string
+			err    error
+		}{
+			{"", ErrSpanContextCorrupted},
+			{"abc:def:0", ErrSpanContextCorrupted},
+			{"abc:def:0:x", ErrSpanContextCorrupted},
+			{"xyz:def:0:1", ErrSpanContextCorrupted},
+			{"abc:53995c3f42cd8ad8a:0:1", ErrSpanContextCorrupted},
+			{"abc%zz:def:0:1", ErrSpanContextCorrupted},
+			{"abc:0:0:1", ErrSpanContextNotFound},
+		}
+		for _, tc := range tests {
+			t.Run(tc.header, func(t *testing.T) {
+				_, err := tracer.Extract(TextMapCarrier{jaegerTraceHeader: tc.header})
+				assert.Equal(t, tc.err, err)
+			})
+		}
+	})
+
+	t.Run("chained", func(t *testing.T) {
+		t.Setenv(headerPropagationStyleExtract, "datadog,jaeger,xray")
+		tracer := newTracer()
+		defer tracer.Stop()
+		assert := assert.New(t)
+
+		ctx, err := tracer.Extract(TextMapCarrier{
+			DefaultTraceIDHeader:  "1",
+			DefaultParentIDHeader: "1",
+			DefaultPriorityHeader: "1",
+			"ot-baggage-user":     "dd",
+			jaegerTraceHeader:     "1:2:0:1",
+			"uberctx-user":        "jaeger",
+			"uberctx-account":     "42",
+			xrayTraceHeader:       "Root=1-00000000-000000000000000000000003;Parent=0000000000000003;Sampled=0",
+		})
+		assert.NoError(err)
+		sctx, ok := ctx.(*spanContext)
+		assert.True(ok)
+		assert.Equal(traceIDFrom64Bits(1), sctx.traceID)
+		assert.Equal(uint64(1), sctx.SpanID())
+		assert.Equal("dd", sctx.baggageItem("user"))
+		assert.Equal("42", sctx.baggageItem("account"))
+		assert.Equal([]ddtrace.SpanLink{{
+			TraceID:    3,
+			SpanID:     3,
+			Flags:      0,
+			Attributes: map[string]string{"reason": "terminated_context", "context_headers": "xray"},
+		}}, sctx.spanLinks)
+	})
+}
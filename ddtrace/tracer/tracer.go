@@ -109,6 +109,10 @@ type tracer struct {
 	// or operation name.
 	rulesSampling *rulesSampler
 
+	// tailSampler holds the chunks of incomplete traces to revise their sampling
+	// decision once they complete. It is nil unless tail sampling is enabled.
+	tailSampler *tailSampler
+
 	// obfuscator holds the obfuscator used to obfuscate resources in aggregated stats.
 	// obfuscator may be nil if disabled.
 	obfuscator *obfuscate.Obfuscator
@@ -127,6 +131,10 @@ type tracer struct {
 	// logFile is closed when tracer stops
 	// by default, tracer logs to stderr and this setting is unused
 	logFile *log.ManagedFile
+
+	// startedRemoteConfig is set when the tracer holds a reference on the
+	// remote configuration client, released when the tracer stops.
+	startedRemoteConfig bool
 }
 
 const (
@@ -191,7 +199,10 @@ func Start(opts ...StartOption) {
 	cfg.Env = t.config.env
 	cfg.HTTP = t.config.httpClient
 	cfg.ServiceName = t.config.serviceName
-	if err := t.startRemoteConfig(cfg); err != nil {
+	if t.config.agentless {
+		// Remote configuration is served by the agent.
+		log.Debug("Remote config is not available in agentless mode")
+	} else if err := t.startRemoteConfig(cfg); err != nil {
 		log.Warn("Remote config startup error: %s", err)
 	}
 
@@ -305,9 +316,19 @@ func newUnstartedTracer(opts ...StartOption) *tracer {
 		writer = newCiVisibilityTraceWriter(c)
 	} else if c.logToStdout {
 		writer = newLogTraceWriter(c, statsd)
+	} else if c.otlpTracesURL != "" {
+		writer = newOTLPTraceWriter(c, statsd)
 	} else {
 		writer = newAgentTraceWriter(c, sampler, statsd)
 	}
+	if len(c.spanExporters) > 0 {
+		ew := newExporterTraceWriter(c.spanExporters, statsd)
+		if c.spanExportersOnly {
+			writer = ew
+		} else {
+			writer = multiTraceWriter{writer, ew}
+		}
+	}
 	traces, spans, err := samplingRulesFromEnv()
 	if err != nil {
 		log.Warn("DIAGNOSTICS Error(s) parsing sampling rules: found errors:%s", err)
@@ -315,11 +336,17 @@ func newUnstartedTracer(opts ...StartOption) *tracer {
 	if traces != nil {
 		c.traceRules = traces
 	}
+	if os.Getenv("DD_TRACE_SAMPLING_RULES") == "" {
+		c.traceRulesFile = os.Getenv("DD_TRACE_SAMPLING_RULES_FILE")
+	}
 	if spans != nil {
 		c.spanRules = spans
 	}
 
 	rulesSampler := newRulesSampler(c.traceRules, c.spanRules, c.globalSampleRate, c.traceRateLimitPerSecond)
+	if c.adaptiveSamplingBudget > 0 {
+		rulesSampler.adaptive = newAdaptiveSampler(c.adaptiveSamplingBudget, c.adaptiveSamplingMinPerKey)
+	}
 	c.traceSampleRate = newDynamicConfig("trace_sample_rate", c.globalSampleRate, rulesSampler.traces.setGlobalSampleRate, equal[float64])
 	// If globalSampleRate returns NaN, it means the environment variable was not set or valid.
 	// We could always set the origin to "env_var" inconditionally, but then it wouldn't be possible
@@ -330,6 +357,9 @@ func newUnstartedTracer(opts ...StartOption) *tracer {
 	}
 	c.traceSampleRules = newDynamicConfig("trace_sample_rules", c.traceRules,
 		rulesSampler.traces.setTraceSampleRules, EqualsFalseNegative)
+	if c.traceRulesFile != "" && traces != nil {
+		c.traceSampleRules.cfgOrigin = telemetry.OriginLocalFile
+	}
 	var dataStreamsProcessor *datastreams.Processor
 	if c.dataStreamsMonitoringEnabled {
 		dataStreamsProcessor = datastreams.NewProcessor(statsd, c.env, c.serviceName, c.version, c.agentURL, c.httpClient)
@@ -367,6 +397,9 @@ func newUnstartedTracer(opts ...StartOption) *tracer {
 		dataStreams: dataStreamsProcessor,
 		logFile:     logFile,
 	}
+	if c.tailSampling != nil {
+		t.tailSampler = newTailSampler(*c.tailSampling, statsd)
+	}
 	return t
 }
 
@@ -416,6 +449,14 @@ func newTracer(opts ...StartOption) *tracer {
 		defer t.wg.Done()
 		t.reportHealthMetricsAtInterval(statsInterval)
 	}()
+	if c.traceRulesFile != "" {
+		w := newSamplingRulesFileWatcher(c.traceRulesFile, &c.traceSampleRules)
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			w.run(samplingRulesFilePollInterval, t.stop)
+		}()
+	}
 	t.stats.Start()
 	return t
 }
@@ -530,6 +571,21 @@ func (t *tracer) sampleChunk(c *chunk) {
 	}
 }
 
+// submitStats passes the finished span s to the stats concentrator, when the
+// agent supports computed stats.
+func (t *tracer) submitStats(s *span) {
+	statSpan, shouldCalc := t.stats.newTracerStatSpan(s, t.obfuscator)
+	if !shouldCalc {
+		return
+	}
+	select {
+	case t.stats.In <- statSpan:
+		// ok
+	default:
+		log.Error("Stats channel full, disregarding span.")
+	}
+}
+
 func (t *tracer) pushChunk(trace *chunk) {
 	select {
 	case <-t.stop:
@@ -685,11 +741,17 @@ func (t *tracer) StartSpan(operationName string, options ...ddtrace.StartSpanOpt
 	if t.config.profilerHotspots || t.config.profilerEndpoints {
 		t.applyPPROFLabels(pprofContext, span)
 	}
+	if isRootSpan {
+		span.watchDone = traceprof.GlobalSlowSpanWatcher().Watch(span.Resource, span.SpanID)
+	}
 	if t.config.serviceMappings != nil {
 		if newSvc, ok := t.config.serviceMappings[span.Service]; ok {
 			span.Service = newSvc
 		}
 	}
+	if len(t.config.spanProcessors) > 0 {
+		processStart(t.config.spanProcessors, span)
+	}
 	if log.DebugEnabled() {
 		// avoid allocating the ...interface{} argument if debug logging is disabled
 		log.Debug("Started Span: %v, Operation: %s, Resource: %s, Tags: %v, %v",
@@ -748,6 +810,22 @@ func spanResourcePIISafe(s *span) bool {
 	return s.Type == ext.SpanTypeWeb || s.Type == ext.AppTypeRPC || s.Type == ""
 }
 
+// GetServiceTags returns the service, env and version tags of the application,
+// as configured in the tracer.
+func (t *tracer) GetServiceTags() []string {
+	var tags []string
+	if t.config.serviceName != "" {
+		tags = append(tags, "service:"+t.config.serviceName)
+	}
+	if t.config.env != "" {
+		tags = append(tags, "env:"+t.config.env)
+	}
+	if t.config.version != "" {
+		tags = append(tags, "version:"+t.config.version)
+	}
+	return tags
+}
+
 // Stop stops the tracer.
 func (t *tracer) Stop() {
 	t.stopOnce.Do(func() {
@@ -763,7 +841,9 @@ func (t *tracer) Stop() {
 		t.dataStreams.Stop()
 	}
 	appsec.Stop()
-	remoteconfig.Stop()
+	if t.startedRemoteConfig {
+		remoteconfig.Stop()
+	}
 	// Close log file last to account for any logs from the above calls
 	if t.logFile != nil {
 		t.logFile.Close()
@@ -815,7 +895,10 @@ func (t *tracer) updateSampling(ctx ddtrace.SpanContext) {
 	// if sampling was successful, need to lock the trace to prevent further re-sampling
 	if t.rulesSampling.SampleTrace(sctx.trace.root) {
 		sctx.trace.setLocked(true)
+		return
 	}
+	// no rule matched, the adaptive sampler decides again if the resource changed
+	t.rulesSampling.ResampleTraceAdaptive(sctx.trace.root)
 }
 
 // Extract uses the configured or default TextMap Propagator.
@@ -858,6 +941,9 @@ func (t *tracer) sample(span *span) {
 	if t.rulesSampling.SampleTrace(span) {
 		return
 	}
+	if t.rulesSampling.SampleTraceAdaptive(span) {
+		return
+	}
 	t.prioritySampling.apply(span)
 }
 
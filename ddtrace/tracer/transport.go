@@ -7,6 +7,7 @@ package tracer
 
 import (
 	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io"
 	"net"
@@ -62,6 +63,7 @@ const (
 	defaultHTTPTimeout       = 10 * time.Second              // defines the current timeout before giving up with the send process
 	traceCountHeader         = "X-Datadog-Trace-Count"       // header containing the number of traces in the payload
 	obfuscationVersionHeader = "Datadog-Obfuscation-Version" // header containing the version of obfuscation used, if any
+	apiKeyHeader             = "DD-API-KEY"                  // header containing the API key authenticating agentless payloads
 )
 
 // transport is an interface for communicating data to the agent.
@@ -81,6 +83,8 @@ type httpTransport struct {
 	statsURL string            // the delivery URL for stats
 	client   *http.Client      // the HTTP client used in the POST
 	headers  map[string]string // the Transport headers
+	apiKey   string            // the API key sent with the payloads, in agentless mode
+	gzip     bool              // whether the payloads are gzip-compressed, in agentless mode
 }
 
 // newTransport returns a new Transport implementation that sends traces to a
@@ -116,15 +120,56 @@ func newHTTPTransport(url string, client *http.Client) *httpTransport {
 	}
 }
 
-func (t *httpTransport) sendStats(p *pb.ClientStatsPayload, tracerObfuscationVersion int) error {
+// newAgentlessHTTPTransport returns a transport sending traces and stats directly
+// to the intake at url, in the same formats as to the agent, authenticated with
+// apiKey and gzip-compressed.
+func newAgentlessHTTPTransport(url, apiKey string, client *http.Client) *httpTransport {
+	t := newHTTPTransport(url, client)
+	t.apiKey = apiKey
+	t.gzip = true
+	return t
+}
+
+// setAgentlessHeaders sets the API key and content encoding headers of req, in
+// agentless mode.
+func (t *httpTransport) setAgentlessHeaders(req *http.Request) {
+	if t.apiKey != "" {
+		req.Header.Set(apiKeyHeader, t.apiKey)
+	}
+	if t.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+}
+
+// compress returns the gzip-compressed content of r.
+func compress(r io.Reader) (*bytes.Buffer, error) {
 	var buf bytes.Buffer
-	if err := msgp.Encode(&buf, p); err != nil {
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.Copy(zw, r); err != nil {
+		return nil, fmt.Errorf("cannot compress request body: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("cannot compress request body: %v", err)
+	}
+	return &buf, nil
+}
+
+func (t *httpTransport) sendStats(p *pb.ClientStatsPayload, tracerObfuscationVersion int) error {
+	buf := new(bytes.Buffer)
+	if err := msgp.Encode(buf, p); err != nil {
 		return err
 	}
-	req, err := http.NewRequest("POST", t.statsURL, &buf)
+	if t.gzip {
+		var err error
+		if buf, err = compress(buf); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest("POST", t.statsURL, buf)
 	if err != nil {
 		return err
 	}
+	t.setAgentlessHeaders(req)
 	if tracerObfuscationVersion > 0 {
 		req.Header.Set(obfuscationVersionHeader, strconv.Itoa(tracerObfuscationVersion))
 	}
@@ -148,14 +193,27 @@ func (t *httpTransport) sendStats(p *pb.ClientStatsPayload, tracerObfuscationVer
 }
 
 func (t *httpTransport) send(p *payload) (body io.ReadCloser, err error) {
-	req, err := http.NewRequest("POST", t.traceURL, p)
-	if err != nil {
-		return nil, fmt.Errorf("cannot create http request: %v", err)
+	var req *http.Request
+	if t.gzip {
+		buf, err := compress(p)
+		if err != nil {
+			return nil, err
+		}
+		req, err = http.NewRequest("POST", t.traceURL, buf)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create http request: %v", err)
+		}
+	} else {
+		req, err = http.NewRequest("POST", t.traceURL, p)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create http request: %v", err)
+		}
+		req.ContentLength = int64(p.size())
 	}
-	req.ContentLength = int64(p.size())
 	for header, value := range t.headers {
 		req.Header.Set(header, value)
 	}
+	t.setAgentlessHeaders(req)
 	req.Header.Set(traceCountHeader, strconv.Itoa(p.itemCount()))
 	req.Header.Set(headerComputedTopLevel, "yes")
 	var tr *tracer
@@ -6,6 +6,8 @@
 package tracer
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io"
 	"net"
@@ -18,8 +20,10 @@ import (
 	"strings"
 	"testing"
 
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
 	traceinternal "gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/statsdtest"
@@ -423,3 +427,56 @@ func TestExternalEnvironment(t *testing.T) {
 	assert.NoError(err)
 	assert.True(found)
 }
+
+func TestAgentlessTransport(t *testing.T) {
+	type request struct {
+		path, apiKey, encoding string
+		body                   []byte
+	}
+	reqs := make(chan request, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		zr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		reqs <- request{
+			path:     r.URL.Path,
+			apiKey:   r.Header.Get(apiKeyHeader),
+			encoding: r.Header.Get("Content-Encoding"),
+			body:     body,
+		}
+	}))
+	defer srv.Close()
+
+	transport := newAgentlessHTTPTransport(srv.URL, "abc", &http.Client{})
+	assert.Equal(t, srv.URL+"/v0.4/traces", transport.endpoint())
+
+	t.Run("traces", func(t *testing.T) {
+		p, err := encode(getTestTrace(1, 1))
+		require.NoError(t, err)
+		want, err := io.ReadAll(p)
+		require.NoError(t, err)
+		p.reset()
+		_, err = transport.send(p)
+		require.NoError(t, err)
+
+		r := <-reqs
+		assert.Equal(t, "/v0.4/traces", r.path)
+		assert.Equal(t, "abc", r.apiKey)
+		assert.Equal(t, "gzip", r.encoding)
+		assert.Equal(t, want, r.body)
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		err := transport.sendStats(&pb.ClientStatsPayload{Hostname: "h"}, 0)
+		require.NoError(t, err)
+
+		r := <-reqs
+		assert.Equal(t, "/v0.6/stats", r.path)
+		assert.Equal(t, "abc", r.apiKey)
+		assert.Equal(t, "gzip", r.encoding)
+		var got pb.ClientStatsPayload
+		require.NoError(t, msgp.Decode(bytes.NewReader(r.body), &got))
+		assert.Equal(t, "h", got.Hostname)
+	})
+}
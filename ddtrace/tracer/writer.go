@@ -32,6 +32,26 @@ type traceWriter interface {
 	stop()
 }
 
+// sampledSpans returns the spans of trace which are kept after sampling: all of
+// them if the trace is kept, otherwise only the spans kept by single span sampling
+// rules. It is used by the writers whose destination doesn't apply the Datadog
+// sampling decisions itself.
+func sampledSpans(trace []*span) []*span {
+	if len(trace) == 0 {
+		return trace
+	}
+	if p, ok := trace[0].context.SamplingPriority(); !ok || p > 0 {
+		return trace
+	}
+	var kept []*span
+	for _, s := range trace {
+		if _, ok := s.Metrics[keySpanSamplingMechanism]; ok {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
 type agentTraceWriter struct {
 	// config holds the tracer configuration
 	config *config
@@ -52,19 +72,32 @@ type agentTraceWriter struct {
 	// statsd is used to send metrics
 	statsd globalinternal.StatsdClient
 
+	// spool keeps the payloads which failed to be sent on disk, when enabled.
+	spool *traceSpool
+
 	tracesQueued uint32
 }
 
 func newAgentTraceWriter(c *config, s *prioritySampler, statsdClient globalinternal.StatsdClient) *agentTraceWriter {
 	return &agentTraceWriter{
 		config:           c,
-		payload:          newPayload(),
+		payload:          newPayloadFor(c),
 		climit:           make(chan struct{}, concurrentConnectionLimit),
 		prioritySampling: s,
 		statsd:           statsdClient,
+		spool:            newTraceSpool(c, statsdClient),
 	}
 }
 
+// newPayloadFor returns a new payload encoding traces with the trace protocol
+// configured in c.
+func newPayloadFor(c *config) *payload {
+	if c.traceProtocol == traceProtocolV05 {
+		return newPayloadV05()
+	}
+	return newPayload()
+}
+
 func (h *agentTraceWriter) add(trace []*span) {
 	if err := h.payload.push(trace); err != nil {
 		h.statsd.Incr("datadog.tracer.traces_dropped", []string{"reason:encoding_error"}, 1)
@@ -91,7 +124,7 @@ func (h *agentTraceWriter) flush() {
 	h.wg.Add(1)
 	h.climit <- struct{}{}
 	oldp := h.payload
-	h.payload = newPayload()
+	h.payload = newPayloadFor(h.config)
 	go func(p *payload) {
 		defer func(start time.Time) {
 			// Once the payload has been used, clear the buffer for garbage
@@ -120,12 +153,31 @@ func (h *agentTraceWriter) flush() {
 				if err := h.prioritySampling.readRatesJSON(rc); err != nil {
 					h.statsd.Incr("datadog.tracer.decode_error", nil, 1)
 				}
+				if h.spool != nil {
+					// The agent is reachable, send what couldn't be sent
+					// before. The replay runs on its own so that it doesn't
+					// hold a connection slot needed by live flushes.
+					h.wg.Add(1)
+					go func() {
+						defer h.wg.Done()
+						h.spool.replay()
+					}()
+				}
 				return
 			}
 			log.Error("failure sending traces (attempt %d), will retry: %v", attempt+1, err)
 			p.reset()
 			time.Sleep(h.config.retryInterval)
 		}
+		if h.spool != nil {
+			serr := h.spool.store(p)
+			if serr == nil {
+				h.statsd.Count("datadog.tracer.traces_spooled", int64(count), nil, 1)
+				log.Warn("spooled %d traces to be sent later: %v", count, err)
+				return
+			}
+			log.Error("failure spooling traces: %v", serr)
+		}
 		h.statsd.Count("datadog.tracer.traces_dropped", int64(count), []string{"reason:send_failed"}, 1)
 		log.Error("lost %d traces: %v", count, err)
 	}(oldp)
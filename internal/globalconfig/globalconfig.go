@@ -27,6 +27,8 @@ type config struct {
 	mu            sync.RWMutex
 	analyticsRate float64
 	serviceName   string
+	env           string
+	version       string
 	runtimeID     string
 	headersAsTags *internal.LockMap
 	dogstatsdAddr string
@@ -63,6 +65,34 @@ func SetServiceName(name string) {
 	cfg.serviceName = name
 }
 
+// Env returns the environment of the running tracer.
+func Env() string {
+	cfg.mu.RLock()
+	defer cfg.mu.RUnlock()
+	return cfg.env
+}
+
+// SetEnv sets the environment of the running tracer.
+func SetEnv(env string) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.env = env
+}
+
+// Version returns the service version of the running tracer.
+func Version() string {
+	cfg.mu.RLock()
+	defer cfg.mu.RUnlock()
+	return cfg.version
+}
+
+// SetVersion sets the service version of the running tracer.
+func SetVersion(version string) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.version = version
+}
+
 // DogstatsdAddr returns the destination for tracer and contrib statsd clients
 func DogstatsdAddr() string {
 	cfg.mu.RLock()
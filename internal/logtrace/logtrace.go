@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+// Package logtrace provides the fields correlating logs and traces which are
+// common to the log integrations.
+package logtrace
+
+import (
+	"context"
+	"os"
+	"strconv"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+)
+
+// Fields holds the fields correlating a log with a span. Empty fields aren't logged.
+type Fields struct {
+	TraceID string
+	SpanID  string
+	Service string
+	Env     string
+	Version string
+}
+
+// FromContext returns the fields of the span found in ctx, or of the active span
+// when automatic instrumentation is enabled. It returns false if there's no span.
+//
+// The trace ID is the decimal lower 64 bits of the trace ID, or the 32 hex
+// characters of the 128-bit trace ID if DD_TRACE_128_BIT_TRACEID_LOGGING_ENABLED
+// is true and its upper 64 bits are set. The service is the global service of
+// the tracer. The environment and version are those of the running tracer,
+// falling back to DD_ENV and DD_VERSION.
+func FromContext(ctx context.Context) (Fields, bool) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	span, ok := tracer.SpanFromContext(ctx)
+	if !ok || span.Context().TraceID() == 0 {
+		return Fields{}, false
+	}
+	sctx := span.Context()
+	f := FromEnv()
+	f.TraceID = traceID(sctx)
+	f.SpanID = strconv.FormatUint(sctx.SpanID(), 10)
+	return f, true
+}
+
+// FromEnv returns the service, environment and version fields, without trace
+// and span IDs, for the logs emitted outside of a span.
+func FromEnv() Fields {
+	f := Fields{
+		Service: globalconfig.ServiceName(),
+		Env:     globalconfig.Env(),
+		Version: globalconfig.Version(),
+	}
+	if f.Env == "" {
+		f.Env = os.Getenv("DD_ENV")
+	}
+	if f.Version == "" {
+		f.Version = os.Getenv("DD_VERSION")
+	}
+	if f.Service == "" {
+		f.Service = os.Getenv("DD_SERVICE")
+	}
+	return f
+}
+
+func traceID(ctx ddtrace.SpanContext) string {
+	if w3c, ok := ctx.(ddtrace.SpanContextW3C); ok && internal.BoolEnv("DD_TRACE_128_BIT_TRACEID_LOGGING_ENABLED", false) {
+		if id := w3c.TraceID128(); len(id) == 32 && id[:16] != "0000000000000000" {
+			return id
+		}
+	}
+	return strconv.FormatUint(ctx.TraceID(), 10)
+}
+
+// ForEach calls fn with the log key and the value of each non-empty field, the
+// trace and span IDs first.
+func (f Fields) ForEach(fn func(key, value string)) {
+	for _, kv := range [...]struct{ k, v string }{
+		{ext.LogKeyTraceID, f.TraceID},
+		{ext.LogKeySpanID, f.SpanID},
+		{ext.LogKeyService, f.Service},
+		{ext.LogKeyEnv, f.Env},
+		{ext.LogKeyVersion, f.Version},
+	} {
+		if kv.v != "" {
+			fn(kv.k, kv.v)
+		}
+	}
+}
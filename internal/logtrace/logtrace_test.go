@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package logtrace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	internallog "gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+func TestFromContext(t *testing.T) {
+	t.Setenv("DD_ENV", "prod")
+	t.Setenv("DD_VERSION", "1.2.3")
+	tracer.Start(tracer.WithService("svc"), tracer.WithLogger(internallog.DiscardLogger{}))
+	defer tracer.Stop()
+
+	t.Run("no-span", func(t *testing.T) {
+		_, ok := FromContext(context.Background())
+		assert.False(t, ok)
+		var nilCtx context.Context
+		_, ok = FromContext(nilCtx)
+		assert.False(t, ok)
+		assert.Equal(t, Fields{Service: "svc", Env: "prod", Version: "1.2.3"}, FromEnv())
+	})
+
+	t.Run("64-bit", func(t *testing.T) {
+		span, ctx := tracer.StartSpanFromContext(context.Background(), "test", tracer.WithSpanID(1234))
+		defer span.Finish()
+
+		f, ok := FromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, Fields{TraceID: "1234", SpanID: "1234", Service: "svc", Env: "prod", Version: "1.2.3"}, f)
+
+		var keys []string
+		f.ForEach(func(k, _ string) { keys = append(keys, k) })
+		assert.Equal(t, []string{"dd.trace_id", "dd.span_id", "dd.service", "dd.env", "dd.version"}, keys)
+	})
+
+	t.Run("128-bit", func(t *testing.T) {
+		t.Setenv("DD_TRACE_128_BIT_TRACEID_LOGGING_ENABLED", "true")
+		span, ctx := tracer.StartSpanFromContext(context.Background(), "test")
+		defer span.Finish()
+
+		f, ok := FromContext(ctx)
+		assert.True(t, ok)
+		assert.Len(t, f.TraceID, 32)
+		assert.Equal(t, span.Context().(interface{ TraceID128() string }).TraceID128(), f.TraceID)
+	})
+
+	t.Run("empty-fields", func(t *testing.T) {
+		var keys []string
+		Fields{TraceID: "1", SpanID: "2"}.ForEach(func(k, _ string) { keys = append(keys, k) })
+		assert.Equal(t, []string{"dd.trace_id", "dd.span_id"}, keys)
+	})
+}
+
+func TestFromEnvTracerOptions(t *testing.T) {
+	t.Setenv("DD_ENV", "prod")
+	t.Setenv("DD_VERSION", "1.2.3")
+	tracer.Start(
+		tracer.WithService("svc"),
+		tracer.WithEnv("staging"),
+		tracer.WithServiceVersion("2.0.0"),
+		tracer.WithLogger(internallog.DiscardLogger{}),
+	)
+	defer tracer.Stop()
+
+	assert.Equal(t, Fields{Service: "svc", Env: "staging", Version: "2.0.0"}, FromEnv())
+}
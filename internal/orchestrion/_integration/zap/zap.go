@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023-present Datadog, Inc.
+
+package zap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/DataDog/dd-trace-go/internal/orchestrion/_integration/internal/trace"
+)
+
+type TestCase struct {
+	logger *zap.Logger
+	logs   *bytes.Buffer
+}
+
+func (tc *TestCase) Setup(context.Context, *testing.T) {
+	tc.logs = new(bytes.Buffer)
+	tc.logger = zap.New(
+		zapcore.NewCore(
+			zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+			zapcore.AddSync(tc.logs),
+			zapcore.DebugLevel,
+		),
+	)
+}
+
+//dd:span
+func Log(_ context.Context, f func(string, ...zap.Field), msg string) {
+	f(msg)
+}
+
+func (tc *TestCase) Run(ctx context.Context, t *testing.T) {
+	Log(ctx, tc.logger.Debug, "debug")
+	Log(ctx, tc.logger.Info, "info")
+	Log(ctx, tc.logger.Warn, "warn")
+	Log(ctx, tc.logger.Error, "error")
+
+	logs := tc.logs.String()
+	t.Logf("got logs: %s", logs)
+	for _, msg := range []string{"debug", "info", "warn", "error"} {
+		want := `"msg":"` + msg + `"`
+		if !strings.Contains(logs, want) {
+			t.Fatalf("missing log message %s", msg)
+		}
+	}
+
+	s := bufio.NewScanner(tc.logs)
+	for s.Scan() {
+		line := s.Bytes()
+		t.Logf("%s", line)
+		if ok, _ := regexp.Match(`"dd.span_id":"\d+"`, line); !ok {
+			t.Errorf("no span ID")
+		}
+		if ok, _ := regexp.Match(`"dd.trace_id":"\d+"`, line); !ok {
+			t.Errorf("no trace ID")
+		}
+	}
+}
+
+func (*TestCase) ExpectedTraces() trace.Traces { return trace.Traces{} }
@@ -153,6 +153,11 @@ var client *Client
 var (
 	startOnce sync.Once
 	stopOnce  sync.Once
+
+	// refs is the number of calls to Start which haven't been matched by a call
+	// to Stop yet: the components sharing the client each hold a reference on it.
+	refs   int
+	refsMu sync.Mutex
 )
 
 // newClient creates a new remoteconfig Client
@@ -181,8 +186,17 @@ func newClient(config ClientConfig) (*Client, error) {
 
 // Start starts the client's update poll loop in a fresh goroutine.
 // Noop if the client has already started.
+// Each successful call takes a reference on the client, shared by the tracer,
+// ASM and the profiler, which must be released with Stop.
 func Start(config ClientConfig) error {
 	var err error
+	defer func() {
+		if err == nil && client != nil {
+			refsMu.Lock()
+			refs++
+			refsMu.Unlock()
+		}
+	}()
 	startOnce.Do(func() {
 		client, err = newClient(config)
 		if err != nil {
@@ -212,8 +226,10 @@ func Start(config ClientConfig) error {
 	return err
 }
 
-// Stop stops the client's update poll loop.
-// Noop if the client has already been stopped.
+// Stop releases the reference taken by Start, and stops the client's update
+// poll loop once no component holds a reference on it anymore.
+// Noop if the client has already been stopped, or if Stop is called more times
+// than Start.
 // The remote config client is supposed to have the same lifecycle as the tracer.
 // It can't be restarted after a call to Stop() unless explicitly calling Reset().
 func Stop() {
@@ -221,6 +237,18 @@ func Stop() {
 		// In case Stop() is called before Start()
 		return
 	}
+	refsMu.Lock()
+	if refs == 0 {
+		refsMu.Unlock()
+		return
+	}
+	refs--
+	n := refs
+	refsMu.Unlock()
+	if n > 0 {
+		log.Debug("remoteconfig: client still used by %d components, not stopping it", n)
+		return
+	}
 	stopOnce.Do(func() {
 		log.Debug("remoteconfig: gracefully stopping the client")
 		client.stop <- struct{}{}
@@ -239,6 +267,9 @@ func Reset() {
 	client = nil
 	startOnce = sync.Once{}
 	stopOnce = sync.Once{}
+	refsMu.Lock()
+	refs = 0
+	refsMu.Unlock()
 }
 
 func (c *Client) updateState() {
@@ -313,6 +344,17 @@ func Subscribe(product string, callback ProductCallback, capabilities ...Capabil
 	return nil
 }
 
+// Unsubscribe removes a product subscribed with Subscribe, along with its callback.
+func Unsubscribe(product string) error {
+	if client == nil {
+		return ErrClientNotStarted
+	}
+	client.productsWithCallbacksMu.Lock()
+	defer client.productsWithCallbacksMu.Unlock()
+	delete(client.productsWithCallbacks, product)
+	return nil
+}
+
 // RegisterCallback allows registering a callback that will be invoked when the client
 // receives configuration updates. It is up to that callback to then decide what to do
 // depending on the product related to the configuration update.
@@ -344,6 +344,12 @@ func TestSubscribe(t *testing.T) {
 	require.Len(t, client.callbacks, 1)
 	require.Len(t, client.productsWithCallbacks, 1)
 	require.Equal(t, reflect.ValueOf(callback), reflect.ValueOf(client.callbacks[0]))
+
+	err = Unsubscribe("my-product")
+	require.NoError(t, err)
+	require.Len(t, client.productsWithCallbacks, 0)
+	err = Subscribe("my-product", pCallback)
+	require.NoError(t, err)
 }
 
 func TestNewUpdateRequest(t *testing.T) {
@@ -449,3 +455,25 @@ func TestAsync(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestStartStopRefs(t *testing.T) {
+	Reset()
+	defer Reset()
+	stopped := func() bool {
+		select {
+		case <-client.stop:
+			return true
+		default:
+			return false
+		}
+	}
+
+	Stop() // noop before Start
+	require.NoError(t, Start(DefaultClientConfig()))
+	require.NoError(t, Start(DefaultClientConfig()))
+	Stop()
+	require.False(t, stopped())
+	Stop()
+	require.True(t, stopped())
+	Stop() // noop once stopped
+}
@@ -42,4 +42,10 @@ const (
 	// RemoteDynamicRule specifies that the span was sampled by a rule configured by Datadog
 	// Dynamic Sampling.
 	RemoteDynamicRule SamplerName = 12
+	// TailSampling specifies that the trace was kept by the local tail sampler,
+	// once complete, because it matched one of the tail sampling rules.
+	TailSampling SamplerName = 13
+	// Adaptive specifies that the trace was sampled by the local adaptive
+	// sampler, with a rate computed for its service and resource.
+	Adaptive SamplerName = 14
 )
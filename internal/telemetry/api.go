@@ -59,6 +59,7 @@ const (
 	OriginDDConfig     Origin = transport.OriginDDConfig
 	OriginEnvVar       Origin = transport.OriginEnvVar
 	OriginRemoteConfig Origin = transport.OriginRemoteConfig
+	OriginLocalFile    Origin = transport.OriginLocalFile
 )
 
 // LogLevel describes the level of a log message
@@ -14,4 +14,5 @@ const (
 	OriginDDConfig     Origin = "dd_config"
 	OriginEnvVar       Origin = "env_var"
 	OriginRemoteConfig Origin = "remote_config"
+	OriginLocalFile    Origin = "local_file"
 )
@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package traceprof
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// globalSlowSpanWatcher is shared between the profiler and the tracer.
+var globalSlowSpanWatcher = &SlowSpanWatcher{}
+
+// GlobalSlowSpanWatcher returns the slow span watcher that is shared between
+// tracing and profiling to support execution traces triggered by slow
+// endpoints.
+func GlobalSlowSpanWatcher() *SlowSpanWatcher {
+	return globalSlowSpanWatcher
+}
+
+// SlowSpanWatcher notifies the profiler of the local root spans of given
+// endpoints which are still running after the threshold of their endpoint.
+// It is disabled until SetThresholds is called, and watching a span is then
+// nearly free for the tracer.
+type SlowSpanWatcher struct {
+	cfg atomic.Pointer[slowSpanConfig]
+}
+
+type slowSpanConfig struct {
+	thresholds map[string]time.Duration
+	onSlow     func(endpoint string, spanID uint64, elapsed time.Duration)
+}
+
+// SetThresholds sets the thresholds of the watched endpoints, and the function
+// called with the local root spans exceeding them. Calling it with no
+// thresholds or a nil onSlow disables the watcher.
+func (w *SlowSpanWatcher) SetThresholds(thresholds map[string]time.Duration, onSlow func(endpoint string, spanID uint64, elapsed time.Duration)) {
+	if len(thresholds) == 0 || onSlow == nil {
+		w.cfg.Store(nil)
+		return
+	}
+	w.cfg.Store(&slowSpanConfig{thresholds: thresholds, onSlow: onSlow})
+}
+
+// Watch starts watching the local root span spanID of endpoint, and returns a
+// function to call when the span finishes. It returns nil if the endpoint isn't
+// watched.
+func (w *SlowSpanWatcher) Watch(endpoint string, spanID uint64) (done func()) {
+	cfg := w.cfg.Load()
+	if cfg == nil {
+		return nil
+	}
+	threshold, ok := cfg.thresholds[endpoint]
+	if !ok {
+		return nil
+	}
+	t := time.AfterFunc(threshold, func() { cfg.onSlow(endpoint, spanID, threshold) })
+	return func() { t.Stop() }
+}
@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package traceprof
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowSpanWatcher(t *testing.T) {
+	var w SlowSpanWatcher
+	require.Nil(t, w.Watch("slow", 1)) // disabled by default
+
+	slow := make(chan uint64, 2)
+	w.SetThresholds(map[string]time.Duration{"slow": time.Millisecond, "fast": time.Hour},
+		func(endpoint string, spanID uint64, elapsed time.Duration) {
+			require.Equal(t, "slow", endpoint)
+			require.Equal(t, time.Millisecond, elapsed)
+			slow <- spanID
+		})
+	require.Nil(t, w.Watch("other", 1))
+	w.Watch("slow", 2)
+	require.Equal(t, uint64(2), <-slow)
+
+	// spans finishing in time aren't reported
+	done := w.Watch("fast", 3)
+	require.NotNil(t, done)
+	done()
+
+	w.SetThresholds(nil, nil)
+	require.Nil(t, w.Watch("slow", 4))
+	require.Empty(t, slow)
+}
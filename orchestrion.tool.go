@@ -34,6 +34,7 @@ import (
 	_ "gopkg.in/DataDog/dd-trace-go.v1/contrib/go-redis/redis.v7"                        // integration
 	_ "gopkg.in/DataDog/dd-trace-go.v1/contrib/go-redis/redis.v8"                        // integration
 	_ "gopkg.in/DataDog/dd-trace-go.v1/contrib/go.mongodb.org/mongo-driver/mongo"        // integration
+	_ "gopkg.in/DataDog/dd-trace-go.v1/contrib/go.uber.org/zap"                          // integration
 	_ "gopkg.in/DataDog/dd-trace-go.v1/contrib/gocql/gocql"                              // integration
 	_ "gopkg.in/DataDog/dd-trace-go.v1/contrib/gofiber/fiber.v2"                         // integration
 	_ "gopkg.in/DataDog/dd-trace-go.v1/contrib/gomodule/redigo"                          // integration
@@ -53,6 +54,7 @@ import (
 	_ "gopkg.in/DataDog/dd-trace-go.v1/contrib/os"                                       // integration
 	_ "gopkg.in/DataDog/dd-trace-go.v1/contrib/redis/go-redis.v9"                        // integration
 	_ "gopkg.in/DataDog/dd-trace-go.v1/contrib/redis/rueidis"                            // integration
+	_ "gopkg.in/DataDog/dd-trace-go.v1/contrib/rs/zerolog"                               // integration
 	_ "gopkg.in/DataDog/dd-trace-go.v1/contrib/segmentio/kafka.go.v0"                    // integration
 	_ "gopkg.in/DataDog/dd-trace-go.v1/contrib/sirupsen/logrus"                          // integration
 	_ "gopkg.in/DataDog/dd-trace-go.v1/contrib/twitchtv/twirp"                           // integration
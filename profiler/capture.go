@@ -0,0 +1,321 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package profiler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/remoteconfig"
+
+	"github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
+)
+
+// maxCaptureDuration is the maximum duration of a capture, see CaptureNow.
+const maxCaptureDuration = 5 * time.Minute
+
+// captureTaskType is the type of the AGENT_TASK remote configurations
+// triggering a capture. Their "duration" argument is the duration of the
+// capture, and their "profile_types" argument the comma-separated names of
+// the profile types to collect, e.g. "cpu,goroutine".
+const captureTaskType = "profiling_capture"
+
+// errCaptureInProgress is returned when a capture is requested while another
+// one is pending or running.
+var errCaptureInProgress = errors.New("a profile capture is already in progress")
+
+// captureRequest is a request for an out-of-band profile collection.
+type captureRequest struct {
+	ctx      context.Context
+	types    []ProfileType
+	duration time.Duration
+	done     chan error // done receives the outcome of the capture
+	// trigger is the anomaly which triggered the capture of an execution
+	// trace, if any.
+	trigger *traceTrigger
+}
+
+// CaptureNow collects the given profile types for the given duration right
+// away, out of band of the periodic profiling cycle, and enqueues them for
+// upload with the triggered:true tag. If types is empty, a CPU profile is
+// collected. An execution trace is recorded along with the profiles if
+// execution tracing is enabled.
+//
+// Since the CPU profiler and the execution tracer can't run twice at the same
+// time, the current profiling period is cut short, and its profiles uploaded as
+// usual, before the capture starts. The profiling cycle resumes once the
+// capture is done.
+//
+// CaptureNow returns once the capture has been enqueued for upload, or an
+// error if the profiler isn't running, if another capture is in progress, or
+// if ctx is done before the capture ends, in which case it is abandoned. The
+// duration is limited to 5 minutes.
+func CaptureNow(ctx context.Context, types []ProfileType, duration time.Duration) error {
+	mu.Lock()
+	p := activeProfiler
+	mu.Unlock()
+	if p == nil {
+		return errors.New("profiler not started")
+	}
+	return p.captureNow(ctx, types, duration)
+}
+
+func (p *profiler) captureNow(ctx context.Context, types []ProfileType, duration time.Duration) error {
+	if duration <= 0 || duration > maxCaptureDuration {
+		return fmt.Errorf("invalid capture duration, must be > 0 and <= %s: %s", maxCaptureDuration, duration)
+	}
+	if len(types) == 0 {
+		types = []ProfileType{CPUProfile}
+	}
+	for _, t := range types {
+		if _, ok := profileTypes[t]; !ok || t == executionTrace {
+			return fmt.Errorf("unknown profile type: %d", t)
+		}
+	}
+	return p.requestCapture(&captureRequest{ctx: ctx, types: types, duration: duration, done: make(chan error, 1)})
+}
+
+// requestCapture cuts the current profiling period short to run the capture
+// req, and waits for its outcome.
+func (p *profiler) requestCapture(req *captureRequest) error {
+	ctx := req.ctx
+	p.captureMu.Lock()
+	if p.capture != nil {
+		p.captureMu.Unlock()
+		return errCaptureInProgress
+	}
+	p.capture = req
+	close(p.cut)
+	p.captureMu.Unlock()
+
+	select {
+	case err := <-req.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.exit:
+		return errProfilerStopped
+	}
+}
+
+// captureRequested returns a channel which is closed when a capture is pending.
+func (p *profiler) captureRequested() <-chan struct{} {
+	p.captureMu.Lock()
+	defer p.captureMu.Unlock()
+	return p.cut
+}
+
+// takeCapture returns the pending capture, if any.
+func (p *profiler) takeCapture() *captureRequest {
+	p.captureMu.Lock()
+	defer p.captureMu.Unlock()
+	return p.capture
+}
+
+// finishCapture allows the next capture to be requested.
+func (p *profiler) finishCapture() {
+	p.captureMu.Lock()
+	defer p.captureMu.Unlock()
+	p.capture = nil
+	p.cut = make(chan struct{})
+}
+
+// runCapture collects the profiles of the capture req and enqueues them for
+// upload. It is called by the collect loop, between two profiling periods.
+func (p *profiler) runCapture(req *captureRequest) error {
+	defer p.finishCapture()
+	ctx, cancel := context.WithCancel(req.ctx)
+	defer cancel()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	go func() {
+		select {
+		case <-p.exit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// The capture is collected by a profiler of its own, so that it has its
+	// own period and doesn't modify the state of the delta profiles.
+	cfg := *p.cfg
+	cfg.period = req.duration
+	cfg.cpuDuration = req.duration
+	cfg.deltaProfiles = false
+	cfg.types = make(map[ProfileType]struct{}, len(req.types))
+	for _, t := range req.types {
+		cfg.types[t] = struct{}{}
+	}
+	exit := make(chan struct{})
+	context.AfterFunc(ctx, func() { close(exit) })
+	cp := &profiler{
+		cfg:       &cfg,
+		exit:      exit,
+		met:       newMetrics(),
+		deltas:    make(map[ProfileType]*fastDeltaProfiler),
+		testHooks: p.testHooks,
+	}
+	cp.met.reset(now())
+
+	profileTypes := cp.enabledProfileTypes()
+	if cfg.traceConfig.Enabled {
+		profileTypes = append(profileTypes, executionTrace)
+	}
+	bat := batch{
+		seq:   p.seq,
+		host:  p.cfg.hostname,
+		start: now(),
+		extraTags: []string{
+			fmt.Sprintf("_dd.profiler.go_execution_trace_enabled:%v", cfg.traceConfig.Enabled),
+			pgoTag(),
+			"triggered:true",
+		},
+		customAttributes: p.cfg.customProfilerLabels,
+		trigger:          req.trigger,
+	}
+	p.seq++
+	cp.runProfiles(&bat, profileTypes)
+	select {
+	case <-p.exit:
+		return errProfilerStopped
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(bat.profiles) == 0 {
+		return errors.New("no profile could be captured")
+	}
+	bat.end = time.Now()
+	p.enqueueUpload(bat)
+	return nil
+}
+
+// startRemoteCapture subscribes to the AGENT_TASK remote configuration product,
+// to trigger captures remotely. The remote configuration client is started if
+// the tracer hasn't started it already.
+//
+// The profiler owns the AGENT_TASK product: a product has a single subscriber,
+// so any other subscription to it is replaced, and it is unsubscribed when the
+// profiler stops, see stopRemoteCapture. The client itself is shared: when the
+// profiler starts it, it keeps its reference on it, so that the tracer can't
+// stop it from under the profiler, and so that the profiler can subscribe again
+// if it is restarted, since a stopped client can't be restarted.
+func (p *profiler) startRemoteCapture() {
+	if p.cfg.agentless {
+		log.Warn("profiler: remote capture is not available in agentless mode")
+		return
+	}
+	err := remoteconfig.Subscribe(state.ProductAgentTask, p.onAgentTask)
+	if errors.Is(err, remoteconfig.ErrClientNotStarted) {
+		cfg := remoteconfig.DefaultClientConfig()
+		cfg.AgentURL = strings.TrimSuffix(p.cfg.agentURL, "/profiling/v1/input")
+		cfg.AppVersion = p.cfg.version
+		cfg.Env = p.cfg.env
+		cfg.HTTP = p.cfg.httpClient
+		cfg.ServiceName = p.cfg.service
+		if err = remoteconfig.Start(cfg); err == nil {
+			err = remoteconfig.Subscribe(state.ProductAgentTask, p.onAgentTask)
+		}
+	}
+	if err != nil {
+		log.Warn("profiler: unable to enable remote capture: %v", err)
+		return
+	}
+	p.remoteCapture = true
+}
+
+// stopRemoteCapture unsubscribes from the AGENT_TASK remote configuration
+// product. The remote configuration client is left running, as it may be used
+// by the tracer.
+func (p *profiler) stopRemoteCapture() {
+	if !p.remoteCapture {
+		return
+	}
+	if err := remoteconfig.Unsubscribe(state.ProductAgentTask); err != nil {
+		log.Debug("profiler: unable to disable remote capture: %v", err)
+	}
+}
+
+// onAgentTask starts the captures requested by the AGENT_TASK remote
+// configurations of type profiling_capture. Each task is run once.
+func (p *profiler) onAgentTask(update remoteconfig.ProductUpdate) map[string]state.ApplyStatus {
+	statuses := make(map[string]state.ApplyStatus)
+	for path, raw := range update {
+		if raw == nil {
+			// the task was removed
+			continue
+		}
+		var task state.AgentTaskData
+		if err := json.Unmarshal(raw, &task); err != nil {
+			statuses[path] = state.ApplyStatus{State: state.ApplyStateError, Error: err.Error()}
+			continue
+		}
+		if task.TaskType != captureTaskType {
+			// the task is meant for another consumer
+			continue
+		}
+		p.captureMu.Lock()
+		handled := p.tasks[task.UUID]
+		p.tasks[task.UUID] = true
+		p.captureMu.Unlock()
+		if handled {
+			statuses[path] = state.ApplyStatus{State: state.ApplyStateAcknowledged}
+			continue
+		}
+		types, duration, err := parseCaptureTask(task.TaskArgs)
+		if err != nil {
+			statuses[path] = state.ApplyStatus{State: state.ApplyStateError, Error: err.Error()}
+			continue
+		}
+		log.Info("profiler: starting remotely triggered capture %s", task.UUID)
+		go func() {
+			if err := p.captureNow(context.Background(), types, duration); err != nil {
+				log.Error("profiler: remotely triggered capture %s failed: %v", task.UUID, err)
+			}
+		}()
+		statuses[path] = state.ApplyStatus{State: state.ApplyStateAcknowledged}
+	}
+	return statuses
+}
+
+// parseCaptureTask returns the profile types and duration of a capture from
+// the arguments of its task.
+func parseCaptureTask(args map[string]string) ([]ProfileType, time.Duration, error) {
+	duration, err := time.ParseDuration(args["duration"])
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid capture duration: %v", err)
+	}
+	var types []ProfileType
+	for _, name := range strings.Split(args["profile_types"], ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		t, ok := profileTypeByName(name)
+		if !ok {
+			return nil, 0, fmt.Errorf("unknown profile type: %s", name)
+		}
+		types = append(types, t)
+	}
+	return types, duration, nil
+}
+
+// profileTypeByName returns the public profile type named name.
+func profileTypeByName(name string) (ProfileType, bool) {
+	for t, pt := range profileTypes {
+		if pt.Name == name && t != executionTrace && t != expGoroutineWaitProfile {
+			return t, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,179 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package profiler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/remoteconfig"
+)
+
+func TestCaptureNow(t *testing.T) {
+	t.Setenv("DD_PROFILING_EXECUTION_TRACE_ENABLED", "true")
+	t.Setenv("DD_PROFILING_EXECUTION_TRACE_PERIOD", "1h")
+	profiles := startTestProfiler(t, 10,
+		WithProfileTypes(CPUProfile, HeapProfile),
+		WithPeriod(time.Hour),
+	)
+
+	// the capture cuts the hour-long profiling period short
+	start := time.Now()
+	err := CaptureNow(context.Background(), []ProfileType{CPUProfile, GoroutineProfile}, 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Minute)
+
+	periodic := <-profiles
+	assert.NotContains(t, periodic.tags, "triggered:true")
+	assert.Contains(t, periodic.event.Attachments, "delta-heap.pprof")
+
+	captured := <-profiles
+	assert.Contains(t, captured.tags, "triggered:true")
+	assert.Contains(t, captured.tags, "go_execution_traced:yes")
+	assert.Contains(t, captured.tags, "profile_seq:1")
+	attachments := captured.event.Attachments
+	sort.Strings(attachments)
+	assert.Equal(t, []string{"cpu.pprof", "go.trace", "goroutines.pprof"}, attachments)
+
+	// the profiling cycle resumes, and another capture can be requested
+	require.NoError(t, CaptureNow(context.Background(), nil, 10*time.Millisecond))
+	<-profiles
+	captured = <-profiles
+	assert.Contains(t, captured.tags, "triggered:true")
+	assert.ElementsMatch(t, []string{"cpu.pprof", "go.trace"}, captured.event.Attachments)
+}
+
+func TestCaptureNowErrors(t *testing.T) {
+	ctx := context.Background()
+	assert.Error(t, CaptureNow(ctx, nil, time.Second)) // profiler not started
+
+	p, err := unstartedProfiler()
+	require.NoError(t, err)
+	assert.Error(t, p.captureNow(ctx, nil, 0))
+	assert.Error(t, p.captureNow(ctx, nil, time.Hour))
+	assert.Error(t, p.captureNow(ctx, []ProfileType{executionTrace}, time.Second))
+	assert.Error(t, p.captureNow(ctx, []ProfileType{ProfileType(-1)}, time.Second))
+
+	t.Run("in-progress", func(t *testing.T) {
+		p, err := unstartedProfiler()
+		require.NoError(t, err)
+		p.capture = &captureRequest{}
+		assert.Equal(t, errCaptureInProgress, p.captureNow(ctx, nil, time.Second))
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		p, err := unstartedProfiler()
+		require.NoError(t, err)
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		assert.Equal(t, context.Canceled, p.captureNow(ctx, nil, time.Second))
+		// the pending capture is abandoned by the collect loop
+		assert.Equal(t, context.Canceled, p.runCapture(p.takeCapture()))
+		assert.Nil(t, p.takeCapture())
+	})
+}
+
+func TestCaptureAgentTask(t *testing.T) {
+	p, err := unstartedProfiler()
+	require.NoError(t, err)
+	defer p.stop()
+
+	update := remoteconfig.ProductUpdate{
+		"datadog/2/AGENT_TASK/flare/config":   []byte(`{"task_type":"tracer_flare","uuid":"1","args":{}}`),
+		"datadog/2/AGENT_TASK/invalid/config": []byte(`{`),
+		"datadog/2/AGENT_TASK/badargs/config": []byte(`{"task_type":"profiling_capture","uuid":"2","args":{"duration":"30s","profile_types":"cpu,nope"}}`),
+		"datadog/2/AGENT_TASK/capture/config": []byte(`{"task_type":"profiling_capture","uuid":"3","args":{"duration":"30s","profile_types":"cpu, goroutine"}}`),
+		"datadog/2/AGENT_TASK/removed/config": nil,
+	}
+	statuses := p.onAgentTask(update)
+	assert.Len(t, statuses, 3)
+	assert.Equal(t, state.ApplyStateError, statuses["datadog/2/AGENT_TASK/invalid/config"].State)
+	assert.Equal(t, state.ApplyStateError, statuses["datadog/2/AGENT_TASK/badargs/config"].State)
+	assert.Equal(t, state.ApplyStateAcknowledged, statuses["datadog/2/AGENT_TASK/capture/config"].State)
+
+	assert.Eventually(t, func() bool { return p.takeCapture() != nil }, time.Second, time.Millisecond)
+	req := p.takeCapture()
+	assert.Equal(t, []ProfileType{CPUProfile, GoroutineProfile}, req.types)
+	assert.Equal(t, 30*time.Second, req.duration)
+
+	// tasks are only run once
+	p.finishCapture()
+	statuses = p.onAgentTask(remoteconfig.ProductUpdate{
+		"datadog/2/AGENT_TASK/capture/config": update["datadog/2/AGENT_TASK/capture/config"],
+	})
+	assert.Equal(t, state.ApplyStateAcknowledged, statuses["datadog/2/AGENT_TASK/capture/config"].State)
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, p.takeCapture())
+}
+
+func TestCaptureRemoteClient(t *testing.T) {
+	remoteconfig.Reset()
+	defer remoteconfig.Reset()
+	p, err := unstartedProfiler(WithRemoteCapture(true))
+	require.NoError(t, err)
+
+	// the remote configuration client isn't started by the tracer
+	p.startRemoteCapture()
+	assert.True(t, p.remoteCapture)
+	assert.ErrorContains(t, remoteconfig.RegisterProduct(state.ProductAgentTask), "already registered")
+
+	p.stop()
+	// the product is left to other subscribers, and the client keeps running
+	assert.NoError(t, remoteconfig.RegisterProduct(state.ProductAgentTask))
+	remoteconfig.Stop()
+}
+
+func TestCaptureRemoteClientBeforeTracer(t *testing.T) {
+	t.Setenv("DD_REMOTE_CONFIG_POLL_INTERVAL_SECONDS", "0.01")
+	remoteconfig.Reset()
+	defer remoteconfig.Reset()
+	var polls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v0.7/config" {
+			polls.Add(1)
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	polled := func() bool {
+		n := polls.Load()
+		return assert.Eventually(t, func() bool { return polls.Load() > n }, time.Second, time.Millisecond)
+	}
+
+	// the profiler starts the remote configuration client
+	require.NoError(t, Start(
+		WithAgentAddr(server.Listener.Addr().String()),
+		WithProfileTypes(),
+		WithPeriod(time.Hour),
+		WithRemoteCapture(true),
+	))
+	polled()
+
+	// the tracer shares the client, and stopping it doesn't stop the client
+	tracer.Start(tracer.WithAgentAddr(server.Listener.Addr().String()), tracer.WithLogger(log.DiscardLogger{}))
+	tracer.Stop()
+	polled()
+	assert.ErrorContains(t, remoteconfig.RegisterProduct(state.ProductAgentTask), "already registered")
+
+	// the profiler only unsubscribes
+	Stop()
+	assert.NoError(t, remoteconfig.RegisterProduct(state.ProductAgentTask))
+	polled()
+
+	// release the reference of the profiler
+	remoteconfig.Stop()
+}
@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package profiler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// serveLatest is set once Handler is called, so that the profiler only keeps
+// the latest profiles in memory when they can be served.
+var serveLatest atomic.Bool
+
+// latestProfile is the latest profile of a given name, see Handler.
+type latestProfile struct {
+	data []byte
+	end  time.Time // end is the end of the batch of the profile
+}
+
+// Handler returns an HTTP handler serving the latest profiles collected by the
+// running profiler, as they would be uploaded: pprof profiles holding the
+// deltas of the last profiling period for the .pprof files, and the latest
+// runtime/trace execution trace for go.trace. The handler serves the profile
+// named after the last element of the request path, e.g. cpu.pprof or
+// delta-heap.pprof, and the list of the available profiles otherwise.
+//
+// For example, after registering the handler with
+//
+//	http.Handle("/debug/datadog/profiles/", profiler.Handler())
+//
+// the latest CPU profile can be viewed with
+//
+//	go tool pprof http://localhost:8080/debug/datadog/profiles/cpu.pprof
+//
+// The handler can be registered before the profiler is started, and responds
+// with 503 Service Unavailable while no profiler is running. The profiles
+// collected before Handler is first called aren't available.
+func Handler() http.Handler {
+	serveLatest.Store(true)
+	return http.HandlerFunc(serveProfiles)
+}
+
+func serveProfiles(w http.ResponseWriter, r *http.Request) {
+	mu.Lock()
+	p := activeProfiler
+	mu.Unlock()
+	if p == nil {
+		http.Error(w, "profiler not started", http.StatusServiceUnavailable)
+		return
+	}
+	profiles := p.latestProfiles()
+	name := path.Base(r.URL.Path)
+	if name == "/" || name == "." {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, name := range names {
+			fmt.Fprintf(w, "%s\t%s\n", name, profiles[name].end.UTC().Format(time.RFC3339))
+		}
+		return
+	}
+	prof, ok := profiles[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeContent(w, r, name, prof.end, bytes.NewReader(prof.data))
+}
+
+// keepLatest records the profiles of bat as the latest ones, if they can be
+// served by Handler.
+func (p *profiler) keepLatest(bat batch) {
+	if !serveLatest.Load() {
+		return
+	}
+	p.latestMu.Lock()
+	defer p.latestMu.Unlock()
+	if p.latest == nil {
+		p.latest = make(map[string]latestProfile)
+	}
+	for _, prof := range bat.profiles {
+		p.latest[prof.name] = latestProfile{data: prof.data, end: bat.end}
+	}
+}
+
+// latestProfiles returns a copy of the latest profiles, by name.
+func (p *profiler) latestProfiles() map[string]latestProfile {
+	p.latestMu.Lock()
+	defer p.latestMu.Unlock()
+	profiles := make(map[string]latestProfile, len(p.latest))
+	for name, prof := range p.latest {
+		profiles[name] = prof
+	}
+	return profiles
+}
@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package profiler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pprofile "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(Handler())
+	defer srv.Close()
+	get := func(path string) (*http.Response, []byte) {
+		resp, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, body
+	}
+
+	resp, _ := get("/cpu.pprof")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	profiles := startTestProfiler(t, 1,
+		WithProfileTypes(HeapProfile),
+		WithPeriod(10*time.Millisecond),
+		WithUpload(false),
+	)
+	assert.Eventually(t, func() bool {
+		_, body := get("/")
+		return strings.HasPrefix(string(body), "delta-heap.pprof\t")
+	}, 10*time.Second, 10*time.Millisecond)
+
+	resp, body := get("/delta-heap.pprof")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/octet-stream", resp.Header.Get("Content-Type"))
+	prof, err := pprofile.ParseData(body)
+	require.NoError(t, err)
+	assert.NotEmpty(t, prof.SampleType)
+
+	resp, _ = get("/cpu.pprof")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// nothing is uploaded
+	assert.Empty(t, profiles)
+}
@@ -51,6 +51,10 @@ const (
 	// DefaultDuration specifies the default length of the CPU profile snapshot.
 	DefaultDuration = time.Minute
 
+	// DefaultWallInterval specifies the default interval between two samples
+	// of the goroutine stacks of the wall-clock profile, see WallProfile.
+	DefaultWallInterval = time.Second
+
 	// DefaultUploadTimeout specifies the default timeout for uploading profiles.
 	// It can be overwritten using the DD_PROFILING_UPLOAD_TIMEOUT env variable
 	// or the WithUploadTimeout option.
@@ -104,15 +108,24 @@ type config struct {
 	cpuProfileRate       int
 	uploadTimeout        time.Duration
 	maxGoroutinesWait    int
+	wallInterval         time.Duration
 	mutexFraction        int
 	blockRate            int
 	outputDir            string
+	outputMaxBatches     int
+	outputMaxAge         time.Duration
+	upload               bool
+	pgoProfile           bool
+	pgoPath              string
+	pgoHalfLife          time.Duration
 	deltaProfiles        bool
 	logStartup           bool
 	traceConfig          executionTraceConfig
 	endpointCountEnabled bool
 	enabled              bool
 	flushOnExit          bool
+	remoteCapture        bool
+	traceTriggers        traceTriggerConfig
 }
 
 // logStartup records the configuration to the configured logger in JSON format
@@ -122,34 +135,42 @@ func logStartup(c *config) {
 		enabledProfiles = append(enabledProfiles, t.String())
 	}
 	info := map[string]any{
-		"date":                       time.Now().Format(time.RFC3339),
-		"os_name":                    osinfo.OSName(),
-		"os_version":                 osinfo.OSVersion(),
-		"version":                    version.Tag,
-		"lang":                       "Go",
-		"lang_version":               runtime.Version(),
-		"hostname":                   c.hostname,
-		"delta_profiles":             c.deltaProfiles,
-		"service":                    c.service,
-		"env":                        c.env,
-		"target_url":                 c.targetURL,
-		"agentless":                  c.agentless,
-		"tags":                       c.tags.Slice(),
-		"profile_period":             c.period.String(),
-		"enabled_profiles":           enabledProfiles,
-		"cpu_duration":               c.cpuDuration.String(),
-		"cpu_profile_rate":           c.cpuProfileRate,
-		"block_profile_rate":         c.blockRate,
-		"mutex_profile_fraction":     c.mutexFraction,
-		"max_goroutines_wait":        c.maxGoroutinesWait,
-		"upload_timeout":             c.uploadTimeout.String(),
-		"execution_trace_enabled":    c.traceConfig.Enabled,
-		"execution_trace_period":     c.traceConfig.Period.String(),
-		"execution_trace_size_limit": c.traceConfig.Limit,
-		"endpoint_count_enabled":     c.endpointCountEnabled,
-		"custom_profiler_label_keys": c.customProfilerLabels,
-		"enabled":                    c.enabled,
-		"flush_on_exit":              c.flushOnExit,
+		"date":                        time.Now().Format(time.RFC3339),
+		"os_name":                     osinfo.OSName(),
+		"os_version":                  osinfo.OSVersion(),
+		"version":                     version.Tag,
+		"lang":                        "Go",
+		"lang_version":                runtime.Version(),
+		"hostname":                    c.hostname,
+		"delta_profiles":              c.deltaProfiles,
+		"service":                     c.service,
+		"env":                         c.env,
+		"target_url":                  c.targetURL,
+		"agentless":                   c.agentless,
+		"tags":                        c.tags.Slice(),
+		"profile_period":              c.period.String(),
+		"enabled_profiles":            enabledProfiles,
+		"cpu_duration":                c.cpuDuration.String(),
+		"cpu_profile_rate":            c.cpuProfileRate,
+		"block_profile_rate":          c.blockRate,
+		"mutex_profile_fraction":      c.mutexFraction,
+		"max_goroutines_wait":         c.maxGoroutinesWait,
+		"wall_profile_interval":       c.wallInterval.String(),
+		"upload_timeout":              c.uploadTimeout.String(),
+		"execution_trace_enabled":     c.traceConfig.Enabled,
+		"execution_trace_period":      c.traceConfig.Period.String(),
+		"execution_trace_size_limit":  c.traceConfig.Limit,
+		"endpoint_count_enabled":      c.endpointCountEnabled,
+		"custom_profiler_label_keys":  c.customProfilerLabels,
+		"enabled":                     c.enabled,
+		"flush_on_exit":               c.flushOnExit,
+		"remote_capture_enabled":      c.remoteCapture,
+		"output_dir_enabled":          c.outputDir != "",
+		"upload_enabled":              c.upload,
+		"pgo_profile_enabled":         c.pgoProfile,
+		"slow_endpoint_trace_count":   len(c.traceTriggers.slowEndpoints),
+		"goroutine_spike_trace_ratio": c.traceTriggers.goroutineSpike,
+		"gc_pause_trace_threshold":    c.traceTriggers.gcPause.String(),
 	}
 	b, err := json.Marshal(info)
 	if err != nil {
@@ -197,9 +218,14 @@ func defaultConfig() (*config, error) {
 		mutexFraction:        DefaultMutexFraction,
 		uploadTimeout:        DefaultUploadTimeout,
 		maxGoroutinesWait:    1000, // arbitrary value, should limit STW to ~30ms
+		wallInterval:         DefaultWallInterval,
 		deltaProfiles:        internal.BoolEnv("DD_PROFILING_DELTA", true),
 		logStartup:           internal.BoolEnv("DD_TRACE_STARTUP_LOGS", true),
 		endpointCountEnabled: internal.BoolEnv(traceprof.EndpointCountEnvVar, false),
+		outputDir:            os.Getenv("DD_PROFILING_OUTPUT_DIR"),
+		outputMaxBatches:     internal.IntEnv("DD_PROFILING_OUTPUT_DIR_MAX_BATCHES", 0),
+		outputMaxAge:         internal.DurationEnv("DD_PROFILING_OUTPUT_DIR_MAX_AGE", 0),
+		upload:               internal.BoolEnv("DD_PROFILING_UPLOAD_ENABLED", true),
 	}
 	c.tags = c.tags.Append(fmt.Sprintf("process_id:%d", os.Getpid()))
 	for _, t := range defaultProfileTypes {
@@ -245,6 +271,7 @@ func defaultConfig() (*config, error) {
 		WithVersion(v)(&c)
 	}
 	c.flushOnExit = internal.BoolEnv("DD_PROFILING_FLUSH_ON_EXIT", false)
+	c.remoteCapture = internal.BoolEnv("DD_PROFILING_REMOTE_CAPTURE_ENABLED", false)
 
 	tags := make(map[string]string)
 	if v := os.Getenv("DD_TAGS"); v != "" {
@@ -274,10 +301,6 @@ func defaultConfig() (*config, error) {
 	if v := os.Getenv("DD_PROFILING_URL"); v != "" {
 		WithURL(v)(&c)
 	}
-	// not for public use
-	if v := os.Getenv("DD_PROFILING_OUTPUT_DIR"); v != "" {
-		withOutputDir(v)(&c)
-	}
 	if v := os.Getenv("DD_PROFILING_WAIT_PROFILE_MAX_GOROUTINES"); v != "" {
 		n, err := strconv.Atoi(v)
 		if err != nil {
@@ -393,6 +416,19 @@ func BlockProfileRate(rate int) Option {
 	}
 }
 
+// WithWallProfileInterval turns on the wall-clock profile, sampling the stacks
+// of all goroutines every d, 1 second by default. Each sample costs CPU time
+// proportional to the number of goroutines, see WallProfile, so a shorter
+// interval gives a more precise profile at a higher overhead.
+func WithWallProfileInterval(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.addProfileType(WallProfile)
+		if d > 0 {
+			cfg.wallInterval = d
+		}
+	}
+}
+
 // WithProfileTypes specifies the profile types to be collected by the profiler.
 func WithProfileTypes(types ...ProfileType) Option {
 	return func(cfg *config) {
@@ -497,15 +533,42 @@ func WithUDS(socketPath string) Option {
 	}
 }
 
-// withOutputDir writes a copy of all uploaded profiles to the given
-// directory. This is intended for local development or debugging uploading
-// issues. The directory will keep growing, no cleanup is performed.
-func withOutputDir(dir string) Option {
+// WithOutputDir writes a copy of every profile batch to the given directory,
+// for environments without a Datadog agent or intake, local development, or
+// building PGO profiles. Each batch is written to a sub-directory named after
+// the end of the batch and its profile_seq tag, e.g.
+// 20240102T030405Z-12/cpu.pprof. The directory keeps growing unless
+// WithOutputRetention is used. This option takes precedence over the
+// DD_PROFILING_OUTPUT_DIR environment variable.
+func WithOutputDir(dir string) Option {
 	return func(cfg *config) {
 		cfg.outputDir = dir
 	}
 }
 
+// WithOutputRetention limits the profile batches kept in the directory set by
+// WithOutputDir to the maxBatches most recent ones, and to the ones which ended
+// less than maxAge ago. Older batches are removed whenever a batch is written.
+// A zero value disables the corresponding limit, which is the default. This
+// option takes precedence over the DD_PROFILING_OUTPUT_DIR_MAX_BATCHES and
+// DD_PROFILING_OUTPUT_DIR_MAX_AGE environment variables.
+func WithOutputRetention(maxBatches int, maxAge time.Duration) Option {
+	return func(cfg *config) {
+		cfg.outputMaxBatches = maxBatches
+		cfg.outputMaxAge = maxAge
+	}
+}
+
+// WithUpload enables uploading profiles to the Datadog agent or intake. It is
+// enabled by default, and can be disabled when profiles are only kept locally,
+// see WithOutputDir and Handler. This option takes precedence over the
+// DD_PROFILING_UPLOAD_ENABLED environment variable.
+func WithUpload(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.upload = enabled
+	}
+}
+
 // WithLogStartup toggles logging the configuration of the profiler to standard
 // error when profiling is started. The configuration is logged in a JSON
 // format. This option is enabled by default.
@@ -515,6 +578,84 @@ func WithLogStartup(enabled bool) Option {
 	}
 }
 
+// WithRemoteCapture enables triggering captures (see CaptureNow) remotely,
+// through AGENT_TASK remote configurations of type "profiling_capture". It is
+// disabled by default. This option takes precedence over the
+// DD_PROFILING_REMOTE_CAPTURE_ENABLED environment variable.
+//
+// The profiler owns the AGENT_TASK product of the remote configuration client,
+// which it starts if the tracer didn't, until the profiler is stopped.
+func WithRemoteCapture(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.remoteCapture = enabled
+	}
+}
+
+// WithSlowEndpointTrace records an execution trace when a local root span of
+// the given endpoint, i.e. the resource name of the span, is still running
+// after threshold. The ID of the slow span is recorded with the trace, so that
+// the trace can be found from the span. This option can be given once per
+// endpoint.
+//
+// The endpoint of a span is its resource name when it is started: spans whose
+// resource is set or changed afterwards, e.g. by a router once the request is
+// matched, are watched under their initial resource name, or not at all if it
+// doesn't match the endpoint.
+//
+// Like the other anomaly-triggered traces, the trace is recorded for up to 10
+// seconds, within the execution trace size limit, at most once per profiling
+// period, and only if execution tracing is enabled.
+func WithSlowEndpointTrace(endpoint string, threshold time.Duration) Option {
+	return func(cfg *config) {
+		if threshold <= 0 {
+			return
+		}
+		if cfg.traceTriggers.slowEndpoints == nil {
+			cfg.traceTriggers.slowEndpoints = make(map[string]time.Duration)
+		}
+		cfg.traceTriggers.slowEndpoints[endpoint] = threshold
+	}
+}
+
+// WithGoroutineSpikeTrace records an execution trace when the number of
+// goroutines reaches ratio times its recent average, and exceeds it by at least
+// 100 goroutines. A ratio of 0 disables it, which is the default. See
+// WithSlowEndpointTrace for the limits of anomaly-triggered traces.
+func WithGoroutineSpikeTrace(ratio float64) Option {
+	return func(cfg *config) {
+		cfg.traceTriggers.goroutineSpike = ratio
+	}
+}
+
+// WithGCPauseTrace records an execution trace when a GC pause lasts threshold
+// or longer. A threshold of 0 disables it, which is the default. The pauses are
+// read from the /sched/pauses/total/gc:seconds runtime metric, whose buckets
+// bound the precision of the threshold. See WithSlowEndpointTrace for the
+// limits of anomaly-triggered traces.
+func WithGCPauseTrace(threshold time.Duration) Option {
+	return func(cfg *config) {
+		cfg.traceTriggers.gcPause = threshold
+	}
+}
+
+// WithPGOProfile keeps a CPU profile merged across all profiling periods, for
+// building the program with profile-guided optimization from production
+// profiles. The weight of the CPU samples is halved every halfLife, so that the
+// profile follows changes of the workload, and a default of 24 hours is used if
+// halfLife is 0. The profile is bounded in size, and stripped of the labels,
+// such as span IDs and endpoints, which aren't used by PGO.
+//
+// The profile can be exported at any time with WritePGOProfile, and is written
+// to path when the profiler stops, if path isn't empty, ready to be committed
+// as the default.pgo file of the main package.
+func WithPGOProfile(path string, halfLife time.Duration) Option {
+	return func(cfg *config) {
+		cfg.pgoProfile = true
+		cfg.pgoPath = path
+		cfg.pgoHalfLife = halfLife
+	}
+}
+
 // WithHostname sets the hostname which will be added to uploaded profiles
 // through the "host:<hostname>" tag. If no hostname is given, the hostname will
 // default to the output of os.Hostname()
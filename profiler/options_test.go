@@ -439,3 +439,40 @@ func TestWith_outputDir(t *testing.T) {
 	want := map[string]string{"foo.pprof": "foo", "bar.pprof": "bar"}
 	require.Equal(t, want, fileData)
 }
+
+func TestWithOutputRetention(t *testing.T) {
+	dir := t.TempDir()
+	p, err := unstartedProfiler(WithOutputDir(dir), WithOutputRetention(2, time.Hour))
+	require.NoError(t, err)
+	// not a batch directory, left untouched
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "other"), 0755))
+
+	end := time.Now()
+	for seq, d := range []time.Duration{2 * time.Hour, 3 * time.Minute, 2 * time.Minute, time.Minute, 0} {
+		bat := batch{seq: uint64(seq), end: end.Add(-d), profiles: []*profile{{name: "cpu.pprof"}}}
+		require.NoError(t, p.outputDir(bat))
+	}
+	// batches written within the same second don't overwrite each other
+	require.NoError(t, p.outputDir(batch{seq: 5, end: end, profiles: []*profile{{name: "cpu.pprof"}}}))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	ts := end.UTC().Format(outputDirTimeFormat)
+	assert.ElementsMatch(t, []string{ts + "-4", ts + "-5", "other"}, names)
+
+	t.Run("age", func(t *testing.T) {
+		dir := t.TempDir()
+		p, err := unstartedProfiler(WithOutputDir(dir), WithOutputRetention(0, time.Hour))
+		require.NoError(t, err)
+		require.NoError(t, p.outputDir(batch{seq: 1, end: end.Add(-2 * time.Hour)}))
+		require.NoError(t, p.outputDir(batch{seq: 2, end: end.Add(-time.Minute)}))
+		require.NoError(t, p.outputDir(batch{seq: 3, end: end}))
+		matches, err := filepath.Glob(filepath.Join(dir, "*"))
+		require.NoError(t, err)
+		assert.Len(t, matches, 2)
+	})
+}
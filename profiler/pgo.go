@@ -6,8 +6,20 @@
 package profiler
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"os"
 	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	pprofile "github.com/google/pprof/profile"
 )
 
 // pgoTag returns a tag indicating whether the program was built with
@@ -29,3 +41,158 @@ func pgoEnabled() bool {
 	}
 	return false
 }
+
+const (
+	// defaultPGOHalfLife is the default duration after which the weight of
+	// the CPU samples merged into the PGO profile is halved.
+	defaultPGOHalfLife = 24 * time.Hour
+	// maxPGOSamples bounds the size of the PGO profile. The lightest samples
+	// are dropped beyond it, as they matter the least for optimizations.
+	maxPGOSamples = 20000
+	// maxPGOHalfLives is the number of half-lives after which the merged
+	// profile is rescaled, see pgoAggregator.add.
+	maxPGOHalfLives = 16
+)
+
+// WritePGOProfile writes the CPU profile merged across profiling periods by the
+// running profiler to w, in the pprof format expected by "go build -pgo", e.g.
+// to be committed as the default.pgo file of the main package. It returns an
+// error if the profiler isn't running or wasn't started with WithPGOProfile, or
+// if no CPU profile has been collected yet.
+func WritePGOProfile(w io.Writer) error {
+	mu.Lock()
+	p := activeProfiler
+	mu.Unlock()
+	if p == nil {
+		return errors.New("profiler not started")
+	}
+	if p.pgo == nil {
+		return errors.New("PGO profile aggregation is disabled, see WithPGOProfile")
+	}
+	return p.pgo.write(w)
+}
+
+// pgoAggregator merges the CPU profiles of successive profiling periods into a
+// PGO profile. Older samples are down-weighted exponentially, and everything
+// PGO doesn't use, such as labels holding span IDs and endpoints, is stripped.
+//
+// The values of a profile are integers, so down-weighting the merged profile
+// at every merge would round the small values, which would then never decay.
+// Instead, the new profiles are up-weighted by the time elapsed since the epoch
+// of the aggregator, and the merged profile is only down-weighted, and the
+// epoch moved, every maxPGOHalfLives half-lives, when its small values can be
+// rounded away.
+type pgoAggregator struct {
+	halfLife time.Duration
+
+	mu    sync.Mutex
+	prof  *pprofile.Profile // prof is the merged profile, nil until the first merge
+	epoch time.Time         // epoch is the time at which the samples of prof have their actual weight
+	last  time.Time         // last is the time of the last merge
+}
+
+func newPGOAggregator(halfLife time.Duration) *pgoAggregator {
+	if halfLife <= 0 {
+		halfLife = defaultPGOHalfLife
+	}
+	return &pgoAggregator{halfLife: halfLife}
+}
+
+// halfLives returns the number of half-lives from the epoch to t.
+func (a *pgoAggregator) halfLives(t time.Time) float64 {
+	return float64(t.Sub(a.epoch)) / float64(a.halfLife)
+}
+
+// add merges the CPU profile data collected at time t.
+func (a *pgoAggregator) add(data []byte, t time.Time) error {
+	prof, err := pprofile.ParseData(data)
+	if err != nil {
+		return err
+	}
+	stripPGOProfile(prof)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.prof == nil {
+		a.epoch = t
+	} else {
+		if n := a.halfLives(t); n > maxPGOHalfLives {
+			a.prof.Scale(math.Exp2(-n))
+			a.epoch = t
+		} else if n > 0 {
+			prof.Scale(math.Exp2(n))
+		}
+		if prof, err = pprofile.Merge([]*pprofile.Profile{a.prof, prof}); err != nil {
+			return err
+		}
+	}
+	if len(prof.Sample) > maxPGOSamples {
+		// The last value is the CPU time, or the sample count for
+		// profiles without one.
+		v := len(prof.SampleType) - 1
+		sort.Slice(prof.Sample, func(i, j int) bool {
+			return prof.Sample[i].Value[v] > prof.Sample[j].Value[v]
+		})
+		prof.Sample = prof.Sample[:maxPGOSamples]
+		// drop the locations and functions of the dropped samples
+		prof = prof.Compact()
+	}
+	a.prof = prof
+	a.last = t
+	return nil
+}
+
+// write writes the merged profile to w, with the weights of the samples as of
+// the last merge.
+func (a *pgoAggregator) write(w io.Writer) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.prof == nil {
+		return errors.New("no CPU profile collected yet")
+	}
+	prof := a.prof
+	if n := a.halfLives(a.last); n > 0 {
+		prof = prof.Copy()
+		prof.Scale(math.Exp2(-n))
+	}
+	return prof.Write(w)
+}
+
+// writeFile atomically replaces the file at path with the merged profile.
+func (a *pgoAggregator) writeFile(path string) error {
+	var buf bytes.Buffer
+	if err := a.write(&buf); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// stripPGOProfile removes the data of prof which isn't used by PGO.
+func stripPGOProfile(prof *pprofile.Profile) {
+	for _, s := range prof.Sample {
+		s.Label = nil
+		s.NumLabel = nil
+		s.NumUnit = nil
+	}
+	prof.Comments = nil
+	prof.DropFrames = ""
+	prof.KeepFrames = ""
+}
+
+// aggregatePGO merges the CPU profile of bat into the PGO profile, if enabled.
+func (p *profiler) aggregatePGO(bat batch) {
+	if p.pgo == nil {
+		return
+	}
+	for _, prof := range bat.profiles {
+		if prof.pt != CPUProfile {
+			continue
+		}
+		if err := p.pgo.add(prof.data, bat.end); err != nil {
+			log.Warn("profiler: unable to merge the CPU profile into the PGO profile: %v", err)
+		}
+	}
+}
@@ -19,7 +19,10 @@ import (
 	"strings"
 	"testing"
 	"text/template"
+	"time"
 
+	pprofile "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -114,3 +117,120 @@ func (pgoTestHelper) goRun(t *testing.T, src string, cpuProfile []byte) string {
 	require.NoError(t, cmd.Run(), "out=%s", out.String())
 	return strings.TrimSpace(out.String())
 }
+
+// testCPUProfile returns a CPU profile with one sample per given value, each
+// with its own function and a span ID label.
+func testCPUProfile(t *testing.T, values ...int64) []byte {
+	prof := &pprofile.Profile{
+		SampleType: []*pprofile.ValueType{{Type: "samples", Unit: "count"}, {Type: "cpu", Unit: "nanoseconds"}},
+		PeriodType: &pprofile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Period:     10000000,
+		Comments:   []string{"comment"},
+	}
+	for i, v := range values {
+		fn := &pprofile.Function{ID: uint64(i + 1), Name: fmt.Sprintf("fn%d", i)}
+		loc := &pprofile.Location{ID: uint64(i + 1), Line: []pprofile.Line{{Function: fn}}}
+		prof.Function = append(prof.Function, fn)
+		prof.Location = append(prof.Location, loc)
+		prof.Sample = append(prof.Sample, &pprofile.Sample{
+			Location: []*pprofile.Location{loc},
+			Value:    []int64{1, v},
+			Label:    map[string][]string{"span id": {fmt.Sprint(i)}},
+		})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, prof.Write(&buf))
+	return buf.Bytes()
+}
+
+func TestPGOAggregator(t *testing.T) {
+	a := newPGOAggregator(time.Hour)
+	assert.Error(t, a.write(&bytes.Buffer{}))
+
+	start := time.Now()
+	require.NoError(t, a.add(testCPUProfile(t, 100, 10), start))
+	// the first profile weighs half as much an hour later
+	require.NoError(t, a.add(testCPUProfile(t, 100), start.Add(time.Hour)))
+
+	var buf bytes.Buffer
+	require.NoError(t, a.write(&buf))
+	prof, err := pprofile.ParseData(buf.Bytes())
+	require.NoError(t, err)
+	cpu := map[string]int64{}
+	for _, s := range prof.Sample {
+		assert.Empty(t, s.Label)
+		cpu[s.Location[0].Line[0].Function.Name] = s.Value[1]
+	}
+	assert.Equal(t, map[string]int64{"fn0": 150, "fn1": 5}, cpu)
+	assert.Empty(t, prof.Comments)
+
+	t.Run("bounded", func(t *testing.T) {
+		a := newPGOAggregator(0)
+		values := make([]int64, maxPGOSamples+10)
+		for i := range values {
+			values[i] = int64(i + 1)
+		}
+		require.NoError(t, a.add(testCPUProfile(t, values...), start))
+		assert.Len(t, a.prof.Sample, maxPGOSamples)
+		assert.Len(t, a.prof.Function, maxPGOSamples)
+		for _, s := range a.prof.Sample {
+			assert.Greater(t, s.Value[1], int64(10))
+		}
+	})
+	t.Run("decay", func(t *testing.T) {
+		a := newPGOAggregator(time.Hour)
+		// fn0 is only sampled at first, fn1 every period
+		require.NoError(t, a.add(testCPUProfile(t, 100, 1), start))
+		weights := func() map[string]int64 {
+			var buf bytes.Buffer
+			require.NoError(t, a.write(&buf))
+			prof, err := pprofile.ParseData(buf.Bytes())
+			require.NoError(t, err)
+			w := map[string]int64{}
+			for _, s := range prof.Sample {
+				w[s.Location[0].Line[0].Function.Name] = s.Value[1]
+			}
+			return w
+		}
+		var prev int64 = 100
+		for i := 1; i <= 4*maxPGOHalfLives; i++ {
+			require.NoError(t, a.add(testCPUProfile(t, 0, 1), start.Add(time.Duration(i)*time.Hour)))
+			w := weights()
+			if i < 8 {
+				// the old weight halves every period
+				assert.InDelta(t, float64(prev)/2, float64(w["fn0"]), 1)
+			}
+			assert.LessOrEqual(t, w["fn0"], prev)
+			prev = w["fn0"]
+		}
+		// the old weight is eventually rounded away, unlike the small
+		// weights of the recent samples
+		assert.Zero(t, prev)
+		assert.Positive(t, weights()["fn1"])
+	})
+}
+
+func TestWithPGOProfile(t *testing.T) {
+	assert.Error(t, WritePGOProfile(&bytes.Buffer{})) // profiler not started
+
+	path := filepath.Join(t.TempDir(), "default.pgo")
+	profiles := startTestProfiler(t, 1,
+		WithProfileTypes(CPUProfile),
+		WithPeriod(10*time.Millisecond),
+		CPUDuration(10*time.Millisecond),
+		WithPGOProfile(path, 0),
+	)
+	<-profiles
+	var buf bytes.Buffer
+	require.NoError(t, WritePGOProfile(&buf))
+	_, err := pprofile.ParseData(buf.Bytes())
+	require.NoError(t, err)
+
+	Stop()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	// the profile is usable by the compiler
+	var h pgoTestHelper
+	require.Equal(t, "pgo:true", h.goRun(t, h.mainSource(t), data))
+}
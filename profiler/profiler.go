@@ -14,6 +14,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"runtime/pprof"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -100,6 +101,20 @@ type profiler struct {
 
 	// lastTrace is the last time an execution trace was collected
 	lastTrace time.Time
+
+	captureMu    sync.Mutex      // guards below fields
+	capture      *captureRequest // capture is the pending capture, see CaptureNow
+	cut          chan struct{}   // cut is closed when a capture is pending, to cut the profiling period short
+	tasks        map[string]bool // tasks holds the UUIDs of the remote capture tasks already handled
+	lastTrigger  time.Time       // lastTrigger is the last time an anomaly triggered an execution trace
+	traceEnabled bool            // traceEnabled is cfg.traceConfig.Enabled, as of its last refresh
+
+	pgo *pgoAggregator // pgo merges the CPU profiles into a PGO profile, see WithPGOProfile
+
+	remoteCapture bool // remoteCapture is set when subscribed to the AGENT_TASK product
+
+	latestMu sync.Mutex               // guards below fields
+	latest   map[string]latestProfile // latest holds the latest profiles by name, see Handler
 }
 
 // testHooks are functions that are replaced during testing which would normally
@@ -230,17 +245,26 @@ func newProfiler(opts ...Option) (*profiler, error) {
 	cfg.tags = immutable.NewStringSlice(tags)
 
 	p := profiler{
-		cfg:    cfg,
-		out:    make(chan batch, outChannelSize),
-		exit:   make(chan struct{}),
-		met:    newMetrics(),
-		deltas: make(map[ProfileType]*fastDeltaProfiler),
+		cfg:          cfg,
+		out:          make(chan batch, outChannelSize),
+		exit:         make(chan struct{}),
+		met:          newMetrics(),
+		deltas:       make(map[ProfileType]*fastDeltaProfiler),
+		cut:          make(chan struct{}),
+		tasks:        make(map[string]bool),
+		traceEnabled: cfg.traceConfig.Enabled,
 	}
 	for pt := range cfg.types {
 		if d := profileTypes[pt].DeltaValues; len(d) > 0 {
 			p.deltas[pt] = newFastDeltaProfiler(d...)
 		}
 	}
+	if cfg.pgoProfile {
+		if _, ok := cfg.types[CPUProfile]; !ok {
+			log.Warn("profiler: the CPU profile is disabled, the PGO profile will be empty")
+		}
+		p.pgo = newPGOAggregator(cfg.pgoHalfLife)
+	}
 	p.uploadFunc = p.upload
 	return &p, nil
 }
@@ -258,6 +282,10 @@ func (p *profiler) run() {
 		runtime.SetBlockProfileRate(p.cfg.blockRate)
 	}
 	startTelemetry(p.cfg)
+	if p.cfg.remoteCapture {
+		p.startRemoteCapture()
+	}
+	p.startTraceTriggers()
 	p.wg.Add(1)
 	go func() {
 		defer p.wg.Done()
@@ -277,12 +305,6 @@ func (p *profiler) run() {
 // an item.
 func (p *profiler) collect(ticker <-chan time.Time) {
 	defer close(p.out)
-	var (
-		// mu guards completed
-		mu        sync.Mutex
-		completed []*profile
-		wg        sync.WaitGroup
-	)
 
 	// Enable endpoint counting (if configured). This causes some minimal
 	// overhead to the tracer, see BenchmarkEndpointCounter.
@@ -313,18 +335,13 @@ func (p *profiler) collect(ticker <-chan time.Time) {
 		}
 		p.seq++
 
-		completed = completed[:0]
-		// We need to increment pendingProfiles for every non-CPU
-		// profile _before_ entering the next loop so that we know CPU
-		// profiling will not complete until every other profile is
-		// finished (because p.pendingProfiles will have been
-		// incremented to count every non-CPU profile before CPU
-		// profiling starts)
-
 		profileTypes := p.enabledProfileTypes()
 
 		// Decide whether we should record an execution trace
 		p.cfg.traceConfig.Refresh()
+		p.captureMu.Lock()
+		p.traceEnabled = p.cfg.traceConfig.Enabled
+		p.captureMu.Unlock()
 		// Randomly record a trace with probability (profile period) / (trace period).
 		// Note that if the trace period is equal to or less than the profile period,
 		// we will always record a trace
@@ -339,42 +356,7 @@ func (p *profiler) collect(ticker <-chan time.Time) {
 		if shouldTrace {
 			profileTypes = append(profileTypes, executionTrace)
 		}
-
-		for _, t := range profileTypes {
-			if t != CPUProfile {
-				p.pendingProfiles.Add(1)
-			}
-		}
-		for _, t := range profileTypes {
-			wg.Add(1)
-			go func(t ProfileType) {
-				defer wg.Done()
-				if t != CPUProfile {
-					defer p.pendingProfiles.Done()
-				}
-				profs, err := p.runProfile(t)
-				if err != nil {
-					if err != errProfilerStopped {
-						log.Error("Error getting %s profile: %v; skipping.", t, err)
-						tags := append(p.cfg.tags.Slice(), t.Tag())
-						p.cfg.statsd.Count("datadog.profiling.go.collect_error", 1, tags, 1)
-					}
-					return
-				}
-				mu.Lock()
-				defer mu.Unlock()
-				completed = append(completed, profs...)
-			}(t)
-		}
-		wg.Wait()
-		for _, prof := range completed {
-			if prof.pt == executionTrace {
-				// If the profile batch includes a runtime execution trace, add a tag so
-				// that the uploads are more easily discoverable in the UI.
-				bat.extraTags = append(bat.extraTags, "go_execution_traced:yes")
-			}
-			bat.addProfile(prof)
-		}
+		p.runProfiles(&bat, profileTypes)
 
 		// Wait until the next profiling period starts or the profiler is stopped.
 		select {
@@ -384,6 +366,9 @@ func (p *profiler) collect(ticker <-chan time.Time) {
 			// Edge case: If only the CPU profile is enabled, and the cpu duration is
 			// is less than the configured profiling period, the ticker will block
 			// until the end of the profiling period.
+		case <-p.captureRequested():
+			// A capture cut the profiling period short, see CaptureNow. The
+			// profiles collected so far are uploaded as usual.
 		case <-p.exit:
 			if !p.cfg.flushOnExit {
 				return
@@ -405,8 +390,65 @@ func (p *profiler) collect(ticker <-chan time.Time) {
 		// The default configuration of the profiler (cpu duration = profiling
 		// period) results in a factor of 1.
 		bat.end = time.Now()
+		p.aggregatePGO(bat)
 		// Upload profiling data.
 		p.enqueueUpload(bat)
+
+		if req := p.takeCapture(); req != nil {
+			req.done <- p.runCapture(req)
+		}
+	}
+}
+
+// runProfiles collects the given profile types concurrently and adds them to
+// bat.
+func (p *profiler) runProfiles(bat *batch, profileTypes []ProfileType) {
+	var (
+		// mu guards completed
+		mu        sync.Mutex
+		completed []*profile
+		wg        sync.WaitGroup
+	)
+	// We need to increment pendingProfiles for every non-CPU
+	// profile _before_ entering the next loop so that we know CPU
+	// profiling will not complete until every other profile is
+	// finished (because p.pendingProfiles will have been
+	// incremented to count every non-CPU profile before CPU
+	// profiling starts)
+	for _, t := range profileTypes {
+		if t != CPUProfile {
+			p.pendingProfiles.Add(1)
+		}
+	}
+	for _, t := range profileTypes {
+		wg.Add(1)
+		go func(t ProfileType) {
+			defer wg.Done()
+			if t != CPUProfile {
+				defer p.pendingProfiles.Done()
+			}
+			profs, err := p.runProfile(t)
+			if err != nil {
+				if err != errProfilerStopped {
+					log.Error("Error getting %s profile: %v; skipping.", t, err)
+					tags := append(p.cfg.tags.Slice(), t.Tag())
+					p.cfg.statsd.Count("datadog.profiling.go.collect_error", 1, tags, 1)
+				}
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			completed = append(completed, profs...)
+		}(t)
+	}
+	wg.Wait()
+	for _, prof := range completed {
+		if prof.pt == executionTrace {
+			// If the profile batch includes a runtime execution trace, add a tag so
+			// that the uploads are more easily discoverable in the UI.
+			bat.extraTags = append(bat.extraTags, "go_execution_traced:yes")
+		}
+		bat.addProfile(prof)
 	}
 }
 
@@ -423,6 +465,7 @@ func (p *profiler) enabledProfileTypes() []ProfileType {
 		MutexProfile,
 		GoroutineProfile,
 		expGoroutineWaitProfile,
+		WallProfile,
 		MetricsProfile,
 		executionTrace,
 	}
@@ -469,9 +512,13 @@ func (p *profiler) send() {
 			if !ok {
 				return
 			}
+			p.keepLatest(bat)
 			if err := p.outputDir(bat); err != nil {
 				log.Error("Failed to output profile to dir: %v", err)
 			}
+			if !p.cfg.upload {
+				continue
+			}
 			if err := p.uploadFunc(bat); err != nil {
 				log.Error("Failed to upload profile: %v", err)
 			}
@@ -483,8 +530,9 @@ func (p *profiler) outputDir(bat batch) error {
 	if p.cfg.outputDir == "" {
 		return nil
 	}
-	// Basic ISO 8601 Format in UTC as the name for the directories.
-	dir := bat.end.UTC().Format("20060102T150405Z")
+	// Basic ISO 8601 Format in UTC as the name for the directories, followed
+	// by the sequence number as captures may end within the same second.
+	dir := fmt.Sprintf("%s-%d", bat.end.UTC().Format(outputDirTimeFormat), bat.seq)
 	dirPath := filepath.Join(p.cfg.outputDir, dir)
 	// 0755 is what mkdir does, should be reasonable for the use cases here.
 	if err := os.MkdirAll(dirPath, 0755); err != nil {
@@ -498,16 +546,63 @@ func (p *profiler) outputDir(bat batch) error {
 			return err
 		}
 	}
+	return p.pruneOutputDir()
+}
+
+// outputDirTimeFormat is the time format of the batch directories written to
+// the output directory.
+const outputDirTimeFormat = "20060102T150405Z"
+
+// pruneOutputDir removes the batch directories exceeding the retention limits
+// of the output directory. Other files are left untouched.
+func (p *profiler) pruneOutputDir() error {
+	if p.cfg.outputMaxBatches <= 0 && p.cfg.outputMaxAge <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(p.cfg.outputDir)
+	if err != nil {
+		return err
+	}
+	type batchDir struct {
+		name string
+		end  time.Time
+	}
+	var dirs []batchDir
+	for _, e := range entries {
+		ts, _, ok := strings.Cut(e.Name(), "-")
+		if !ok || !e.IsDir() {
+			continue
+		}
+		end, err := time.Parse(outputDirTimeFormat, ts)
+		if err != nil {
+			continue
+		}
+		dirs = append(dirs, batchDir{name: e.Name(), end: end})
+	}
+	// most recent first
+	sort.SliceStable(dirs, func(i, j int) bool { return dirs[i].end.After(dirs[j].end) })
+	for i, d := range dirs {
+		tooMany := p.cfg.outputMaxBatches > 0 && i >= p.cfg.outputMaxBatches
+		tooOld := p.cfg.outputMaxAge > 0 && now().Sub(d.end) > p.cfg.outputMaxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(p.cfg.outputDir, d.name)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // interruptibleSleep sleeps for the given duration or until interrupted by the
-// p.exit channel being closed.
+// p.exit channel being closed, or by a capture cutting the profiling period short.
 // Returns whether the sleep was interrupted
 func (p *profiler) interruptibleSleep(d time.Duration) bool {
 	select {
 	case <-p.exit:
 		return true
+	case <-p.captureRequested():
+		return true
 	case <-time.After(d):
 		return false
 	}
@@ -518,7 +613,14 @@ func (p *profiler) stop() {
 	p.stopOnce.Do(func() {
 		close(p.exit)
 	})
+	p.stopTraceTriggers()
+	p.stopRemoteCapture()
 	p.wg.Wait()
+	if p.pgo != nil && p.cfg.pgoPath != "" {
+		if err := p.pgo.writeFile(p.cfg.pgoPath); err != nil {
+			log.Error("profiler: unable to write the PGO profile: %v", err)
+		}
+	}
 	if p.cfg.logStartup {
 		log.Info("Profiling stopped")
 	}
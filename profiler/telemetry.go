@@ -42,6 +42,7 @@ func startTelemetry(c *config) {
 			{Name: "mutex_profile_enabled", Value: profileEnabled(MutexProfile)},
 			{Name: "goroutine_profile_enabled", Value: profileEnabled(GoroutineProfile)},
 			{Name: "goroutine_wait_profile_enabled", Value: profileEnabled(expGoroutineWaitProfile)},
+			{Name: "wall_profile_enabled", Value: profileEnabled(WallProfile)},
 			{Name: "upload_timeout", Value: c.uploadTimeout.String()},
 			{Name: "execution_trace_enabled", Value: c.traceConfig.Enabled},
 			{Name: "execution_trace_period", Value: c.traceConfig.Period.String()},
@@ -50,6 +51,13 @@ func startTelemetry(c *config) {
 			{Name: "num_custom_profiler_label_keys", Value: len(c.customProfilerLabels)},
 			{Name: "enabled", Value: c.enabled},
 			{Name: "flush_on_exit", Value: c.flushOnExit},
+			{Name: "remote_capture_enabled", Value: c.remoteCapture},
+			{Name: "output_dir_enabled", Value: c.outputDir != ""},
+			{Name: "upload_enabled", Value: c.upload},
+			{Name: "pgo_profile_enabled", Value: c.pgoProfile},
+			{Name: "slow_endpoint_trace_count", Value: len(c.traceTriggers.slowEndpoints)},
+			{Name: "goroutine_spike_trace_ratio", Value: c.traceTriggers.goroutineSpike},
+			{Name: "gc_pause_trace_threshold", Value: c.traceTriggers.gcPause.String()},
 		}...,
 	)
 	if telemetry.GlobalClient() == nil {
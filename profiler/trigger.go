@@ -0,0 +1,216 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package profiler
+
+import (
+	"context"
+	"math"
+	"runtime"
+	runtimemetrics "runtime/metrics"
+	"strconv"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/traceprof"
+)
+
+const (
+	// triggeredTraceDuration is the maximum duration of an execution trace
+	// triggered by an anomaly. The trace is also bounded by the execution
+	// trace size limit.
+	triggeredTraceDuration = 10 * time.Second
+	// anomalyCheckInterval is the interval between two checks of the
+	// goroutine count and the GC pauses.
+	anomalyCheckInterval = time.Second
+	// minGoroutineSpike is the minimum increase of the goroutine count over
+	// its baseline for a spike to trigger a trace, so that small programs
+	// don't trigger traces as they start a handful of goroutines.
+	minGoroutineSpike = 100
+	// goroutineBaselineWeight is the weight of the latest goroutine count in
+	// the moving average of the goroutine count baseline.
+	goroutineBaselineWeight = 0.1
+	// gcPausesMetric is the runtime metric of the GC pauses.
+	gcPausesMetric = "/sched/pauses/total/gc:seconds"
+)
+
+// Reasons for a triggered execution trace, see traceTrigger.
+const (
+	triggerSlowSpan       = "slow_span"
+	triggerGoroutineSpike = "goroutine_spike"
+	triggerGCPause        = "gc_pause"
+)
+
+// traceTriggerConfig configures the anomalies triggering execution traces.
+type traceTriggerConfig struct {
+	// slowEndpoints are the latency thresholds of the local root spans of
+	// the watched endpoints.
+	slowEndpoints map[string]time.Duration
+	// goroutineSpike is the ratio of the goroutine count to its baseline
+	// which is considered a spike, or 0 if disabled.
+	goroutineSpike float64
+	// gcPause is the GC pause duration which is considered an outlier, or 0
+	// if disabled.
+	gcPause time.Duration
+}
+
+// traceTrigger describes the anomaly which triggered an execution trace. It is
+// recorded in the event metadata of the upload.
+type traceTrigger struct {
+	// Reason is one of slow_span, goroutine_spike or gc_pause.
+	Reason string `json:"reason"`
+	// Endpoint is the endpoint of the slow span.
+	Endpoint string `json:"endpoint,omitempty"`
+	// SpanID is the ID of the slow local root span, so that the trace can
+	// be linked from the span.
+	SpanID string `json:"span_id,omitempty"`
+	// Value is the value which crossed the threshold: the running time of
+	// the span in seconds, the goroutine count, or the GC pause in seconds.
+	Value float64 `json:"value"`
+}
+
+// startTraceTriggers starts watching for the anomalies configured in
+// p.cfg.traceTriggers. Nothing is watched when execution tracing is disabled.
+func (p *profiler) startTraceTriggers() {
+	cfg := p.cfg.traceTriggers
+	if !p.cfg.traceConfig.Enabled {
+		if len(cfg.slowEndpoints) > 0 || cfg.goroutineSpike > 0 || cfg.gcPause > 0 {
+			log.Warn("profiler: execution trace triggers are ignored as execution tracing is disabled")
+		}
+		return
+	}
+	if len(cfg.slowEndpoints) > 0 {
+		traceprof.GlobalSlowSpanWatcher().SetThresholds(cfg.slowEndpoints, p.onSlowSpan)
+	}
+	if cfg.goroutineSpike > 0 || cfg.gcPause > 0 {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			tick := time.NewTicker(anomalyCheckInterval)
+			defer tick.Stop()
+			p.watchAnomalies(tick.C)
+		}()
+	}
+}
+
+// stopTraceTriggers stops watching for slow spans. The other anomalies are
+// watched until the profiler exits.
+func (p *profiler) stopTraceTriggers() {
+	if len(p.cfg.traceTriggers.slowEndpoints) > 0 {
+		traceprof.GlobalSlowSpanWatcher().SetThresholds(nil, nil)
+	}
+}
+
+// onSlowSpan is called by the tracer when the local root span spanID of
+// endpoint has been running for longer than the threshold of the endpoint.
+func (p *profiler) onSlowSpan(endpoint string, spanID uint64, elapsed time.Duration) {
+	p.triggerTrace(&traceTrigger{
+		Reason:   triggerSlowSpan,
+		Endpoint: endpoint,
+		SpanID:   strconv.FormatUint(spanID, 10),
+		Value:    elapsed.Seconds(),
+	})
+}
+
+// watchAnomalies checks the goroutine count and the GC pauses whenever tick
+// receives, until the profiler exits.
+func (p *profiler) watchAnomalies(tick <-chan time.Time) {
+	cfg := p.cfg.traceTriggers
+	spikes := goroutineSpikeDetector{ratio: cfg.goroutineSpike}
+	// Unlike ReadMemStats, reading the GC pauses histogram doesn't stop the
+	// world. Read may reuse the memory of the histogram, so the counts are
+	// copied to be compared with the next read.
+	pauses := []runtimemetrics.Sample{{Name: gcPausesMetric}}
+	var lastCounts []uint64
+	if cfg.gcPause > 0 {
+		runtimemetrics.Read(pauses)
+		if pauses[0].Value.Kind() != runtimemetrics.KindFloat64Histogram {
+			log.Warn("profiler: GC pauses aren't available, the GC pause trace trigger is disabled")
+			cfg.gcPause = 0
+		} else {
+			lastCounts = append(lastCounts, pauses[0].Value.Float64Histogram().Counts...)
+		}
+	}
+	for {
+		select {
+		case <-p.exit:
+			return
+		case <-tick:
+		}
+		if cfg.goroutineSpike > 0 {
+			if n := runtime.NumGoroutine(); spikes.observe(n) {
+				p.triggerTrace(&traceTrigger{Reason: triggerGoroutineSpike, Value: float64(n)})
+			}
+		}
+		if cfg.gcPause > 0 {
+			runtimemetrics.Read(pauses)
+			h := pauses[0].Value.Float64Histogram()
+			if pause, ok := maxNewPause(lastCounts, h); ok && pause >= cfg.gcPause.Seconds() {
+				p.triggerTrace(&traceTrigger{Reason: triggerGCPause, Value: pause})
+			}
+			lastCounts = append(lastCounts[:0], h.Counts...)
+		}
+	}
+}
+
+// maxNewPause returns the lower bound, in seconds, of the highest bucket of the
+// GC pauses histogram h whose count increased since prev, and false if there
+// was no pause since then.
+func maxNewPause(prev []uint64, h *runtimemetrics.Float64Histogram) (float64, bool) {
+	for i := len(h.Counts) - 1; i >= 0; i-- {
+		if i < len(prev) && h.Counts[i] <= prev[i] {
+			continue
+		}
+		if math.IsInf(h.Buckets[i], -1) {
+			return 0, true
+		}
+		return h.Buckets[i], true
+	}
+	return 0, false
+}
+
+// goroutineSpikeDetector detects spikes of the goroutine count over its
+// moving average.
+type goroutineSpikeDetector struct {
+	ratio    float64
+	baseline float64
+}
+
+// observe records the goroutine count n and returns whether it is a spike.
+func (d *goroutineSpikeDetector) observe(n int) bool {
+	v := float64(n)
+	if d.baseline == 0 {
+		d.baseline = v
+		return false
+	}
+	spike := v >= d.baseline*d.ratio && v-d.baseline >= minGoroutineSpike
+	d.baseline += goroutineBaselineWeight * (v - d.baseline)
+	return spike
+}
+
+// triggerTrace records an execution trace for the anomaly t, out of band of the
+// profiling cycle like CaptureNow. At most one trace is triggered per profiling
+// period, and anomalies are ignored while a capture is in progress.
+func (p *profiler) triggerTrace(t *traceTrigger) {
+	p.captureMu.Lock()
+	if !p.traceEnabled || p.capture != nil || (!p.lastTrigger.IsZero() && now().Sub(p.lastTrigger) < p.cfg.period) {
+		p.captureMu.Unlock()
+		return
+	}
+	p.lastTrigger = now()
+	p.captureMu.Unlock()
+	log.Debug("profiler: recording an execution trace triggered by %s", t.Reason)
+	go func() {
+		req := &captureRequest{
+			ctx:      context.Background(),
+			duration: triggeredTraceDuration,
+			trigger:  t,
+			done:     make(chan error, 1),
+		}
+		if err := p.requestCapture(req); err != nil && err != errProfilerStopped {
+			log.Debug("profiler: execution trace triggered by %s failed: %v", t.Reason, err)
+		}
+	}()
+}
@@ -0,0 +1,137 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package profiler
+
+import (
+	"math"
+	"runtime"
+	runtimemetrics "runtime/metrics"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/traceprof"
+)
+
+func TestSlowEndpointTrace(t *testing.T) {
+	t.Setenv("DD_PROFILING_EXECUTION_TRACE_ENABLED", "true")
+	t.Setenv("DD_PROFILING_EXECUTION_TRACE_PERIOD", "1h")
+	// the triggered trace is cut short by the size limit
+	t.Setenv("DD_PROFILING_EXECUTION_TRACE_LIMIT_BYTES", "1")
+	tracer.Start(tracer.WithLogger(log.DiscardLogger{}))
+	defer tracer.Stop()
+	profiles := startTestProfiler(t, 10,
+		WithProfileTypes(HeapProfile),
+		WithPeriod(time.Hour),
+		WithSlowEndpointTrace("GET /slow", 10*time.Millisecond),
+	)
+
+	fast := tracer.StartSpan("http.request", tracer.ResourceName("GET /fast"))
+	slow := tracer.StartSpan("http.request", tracer.ResourceName("GET /slow"))
+	periodic := <-profiles
+	assert.Nil(t, periodic.event.Info.Profiler.Trigger)
+	triggered := <-profiles
+	slow.Finish()
+	fast.Finish()
+
+	assert.Contains(t, triggered.tags, "triggered:true")
+	assert.Equal(t, []string{"go.trace"}, triggered.event.Attachments)
+	assert.Equal(t, &traceTrigger{
+		Reason:   triggerSlowSpan,
+		Endpoint: "GET /slow",
+		SpanID:   strconv.FormatUint(slow.Context().SpanID(), 10),
+		Value:    0.01,
+	}, triggered.event.Info.Profiler.Trigger)
+
+	Stop()
+	assert.Nil(t, traceprof.GlobalSlowSpanWatcher().Watch("GET /slow", 1))
+}
+
+func TestTriggerTraceRateLimit(t *testing.T) {
+	p, err := unstartedProfiler(WithPeriod(time.Hour))
+	require.NoError(t, err)
+	defer p.stop()
+
+	p.triggerTrace(&traceTrigger{Reason: triggerGCPause})
+	assert.Eventually(t, func() bool { return p.takeCapture() != nil }, time.Second, time.Millisecond)
+	req := p.takeCapture()
+	assert.Equal(t, triggerGCPause, req.trigger.Reason)
+	assert.Empty(t, req.types)
+	p.finishCapture()
+
+	// only one trace is triggered per profiling period
+	p.triggerTrace(&traceTrigger{Reason: triggerGCPause})
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, p.takeCapture())
+}
+
+func TestTriggerTraceDisabled(t *testing.T) {
+	t.Setenv("DD_PROFILING_EXECUTION_TRACE_ENABLED", "false")
+	p, err := unstartedProfiler(WithSlowEndpointTrace("GET /slow", time.Millisecond))
+	require.NoError(t, err)
+	defer p.stop()
+
+	p.startTraceTriggers()
+	assert.Nil(t, traceprof.GlobalSlowSpanWatcher().Watch("GET /slow", 1))
+	p.triggerTrace(&traceTrigger{Reason: triggerGCPause})
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, p.takeCapture())
+}
+
+func TestGoroutineSpikeDetector(t *testing.T) {
+	d := goroutineSpikeDetector{ratio: 2}
+	assert.False(t, d.observe(50)) // the first count is the baseline
+	assert.False(t, d.observe(90)) // below 2x the baseline, which is now 54
+	// 2x the baseline, but less than minGoroutineSpike more goroutines
+	assert.False(t, d.observe(110))
+	assert.True(t, d.observe(1000))
+}
+
+func TestMaxNewPause(t *testing.T) {
+	h := &runtimemetrics.Float64Histogram{
+		Counts:  []uint64{1, 2, 0, 0},
+		Buckets: []float64{math.Inf(-1), 0.001, 0.01, 0.1, math.Inf(1)},
+	}
+	_, ok := maxNewPause(h.Counts, h)
+	assert.False(t, ok)
+
+	prev := append([]uint64(nil), h.Counts...)
+	h.Counts[2]++
+	pause, ok := maxNewPause(prev, h)
+	assert.True(t, ok)
+	assert.Equal(t, 0.01, pause)
+
+	// the older pauses of the higher buckets are ignored
+	prev = append(prev[:0], h.Counts...)
+	h.Counts[0]++
+	pause, ok = maxNewPause(prev, h)
+	assert.True(t, ok)
+	assert.Equal(t, 0.0, pause)
+}
+
+func TestGCPauseTrace(t *testing.T) {
+	t.Setenv("DD_PROFILING_EXECUTION_TRACE_ENABLED", "true")
+	p, err := unstartedProfiler(WithPeriod(time.Hour), WithGCPauseTrace(time.Nanosecond))
+	require.NoError(t, err)
+	defer p.stop()
+
+	tick := make(chan time.Time)
+	go p.watchAnomalies(tick)
+	assert.Eventually(t, func() bool {
+		runtime.GC()
+		tick <- time.Now()
+		return p.takeCapture() != nil
+	}, time.Second, 10*time.Millisecond)
+	req := p.takeCapture()
+	assert.Equal(t, triggerGCPause, req.trigger.Reason)
+	assert.Greater(t, req.trigger.Value, 0.0)
+	p.finishCapture()
+}
@@ -165,6 +165,9 @@ type profilerInfo struct {
 	// Activation distinguishes how the profiler was enabled, either "auto"
 	// (env var set via admission controller) or "manual"
 	Activation string `json:"activation"`
+	// Trigger is the anomaly which triggered the execution trace of the
+	// upload, if any
+	Trigger *traceTrigger `json:"trigger,omitempty"`
 }
 
 // encode encodes the profile as a multipart mime request.
@@ -204,6 +207,8 @@ func encode(bat batch, tags []string) (contentType string, body io.Reader, err e
 		event.Info.Profiler.SSI.Mechanism = "none"
 	}
 
+	event.Info.Profiler.Trigger = bat.trigger
+
 	for _, p := range bat.profiles {
 		event.Attachments = append(event.Attachments, p.name)
 		f, err := mw.CreateFormFile(p.name, p.name)
@@ -0,0 +1,201 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package profiler
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/traceprof"
+
+	pprofile "github.com/google/pprof/profile"
+)
+
+const (
+	// wallMergeBatch is the number of snapshots which are merged at once, to
+	// bound the memory used by pending snapshots.
+	wallMergeBatch = 50
+	// waitReasonLabel is the label holding the wait reason of the goroutines
+	// in the wall-clock profile.
+	waitReasonLabel = "wait reason"
+)
+
+// waitReasons maps the runtime functions parking goroutines to their wait
+// reasons, named like the wait reasons of the runtime goroutine dumps. The
+// goroutine profile doesn't include the wait reasons, so they are derived from
+// the callers of runtime.gopark.
+var waitReasons = map[string]string{
+	"runtime.selectgo":                 "select",
+	"runtime.block":                    "select (no cases)",
+	"runtime.chanrecv":                 "chan receive",
+	"runtime.chansend":                 "chan send",
+	"internal/poll.runtime_pollWait":   "IO wait",
+	"time.Sleep":                       "sleep",
+	"sync.runtime_SemacquireMutex":     "sync.Mutex.Lock",
+	"sync.runtime_SemacquireRWMutexR":  "sync.RWMutex.RLock",
+	"sync.runtime_SemacquireRWMutex":   "sync.RWMutex.Lock",
+	"sync.runtime_SemacquireWaitGroup": "sync.WaitGroup.Wait",
+	"sync.runtime_Semacquire":          "semacquire",
+	"sync.runtime_notifyListWait":      "sync.Cond.Wait",
+	"runtime.gcBgMarkWorker":           "GC worker (idle)",
+	"runtime.bgsweep":                  "GC sweep wait",
+	"runtime.bgscavenge":               "GC scavenge wait",
+	"runtime.forcegchelper":            "force gc (idle)",
+	"runtime.runfinq":                  "finalizer wait",
+	"os/signal.signal_recv":            "signal wait",
+}
+
+// collectWallProfile snapshots the stacks of all goroutines every
+// p.cfg.wallInterval until the end of the profiling period, and returns the
+// wall time spent by goroutines per stack, wait reason and trace labels.
+func collectWallProfile(p *profiler) ([]byte, error) {
+	tick := time.NewTicker(p.cfg.wallInterval)
+	defer tick.Stop()
+	end := time.After(p.cfg.period)
+	var (
+		merged  *pprofile.Profile
+		pending []*pprofile.Profile
+		start   = now()
+		last    = start
+	)
+	flush := func() error {
+		if merged != nil {
+			pending = append(pending, merged)
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		m, err := pprofile.Merge(pending)
+		if err != nil {
+			return err
+		}
+		merged, pending = m, pending[:0]
+		return nil
+	}
+	for done := false; !done; {
+		select {
+		case <-tick.C:
+		case <-end:
+			done = true
+		case <-p.captureRequested():
+			done = true
+		case <-p.exit:
+			done = true
+		}
+		t := now()
+		elapsed := t.Sub(last)
+		last = t
+		if elapsed <= 0 || runtime.NumGoroutine() > p.cfg.maxGoroutinesWait {
+			// The goroutine profile stops the world for a duration
+			// proportional to the number of goroutines.
+			continue
+		}
+		snapshot, err := p.wallSnapshot(elapsed)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, snapshot)
+		if len(pending) >= wallMergeBatch {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	if merged == nil {
+		merged = &pprofile.Profile{SampleType: wallSampleTypes(), PeriodType: wallPeriodType()}
+	}
+	// the period may be cut short by a capture or the profiler stopping
+	merged.TimeNanos = start.UnixNano()
+	merged.DurationNanos = last.Sub(start).Nanoseconds()
+	var buf bytes.Buffer
+	err := merged.Write(&buf)
+	return buf.Bytes(), err
+}
+
+// wallSnapshot returns the goroutine profile of the running goroutines as a
+// wall-clock profile, attributing elapsed to each of them.
+func (p *profiler) wallSnapshot(elapsed time.Duration) (*pprofile.Profile, error) {
+	var buf bytes.Buffer
+	if err := p.lookupProfile("goroutine", &buf, 0); err != nil {
+		return nil, err
+	}
+	prof, err := pprofile.ParseData(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	keep := map[string]bool{
+		traceprof.SpanID:          true,
+		traceprof.LocalRootSpanID: true,
+		traceprof.TraceEndpoint:   true,
+	}
+	for _, k := range p.cfg.customProfilerLabels {
+		keep[k] = true
+	}
+	prof.SampleType = wallSampleTypes()
+	prof.PeriodType = wallPeriodType()
+	prof.Period = p.cfg.wallInterval.Nanoseconds()
+	for _, s := range prof.Sample {
+		n := s.Value[0]
+		s.Value = []int64{n, n * elapsed.Nanoseconds()}
+		labels := map[string][]string{waitReasonLabel: {waitReason(s)}}
+		for k, v := range s.Label {
+			if keep[k] {
+				labels[k] = v
+			}
+		}
+		s.Label = labels
+		s.NumLabel = nil
+		s.NumUnit = nil
+	}
+	return prof, nil
+}
+
+// waitReason returns the wait reason of the goroutines of the sample s:
+// "syscall" if they are in a system call, "running" if they are running or
+// runnable, and the reason they are parked otherwise.
+func waitReason(s *pprofile.Sample) string {
+	parked := false
+	for i, loc := range s.Location {
+		for _, line := range loc.Line {
+			if line.Function == nil {
+				continue
+			}
+			name := line.Function.Name
+			switch {
+			case parked:
+				if reason, ok := waitReasons[name]; ok {
+					return reason
+				}
+				// e.g. runtime.chanrecv1 and runtime.chanrecv2
+				if reason, ok := waitReasons[strings.TrimRight(name, "12")]; ok {
+					return reason
+				}
+			case name == "runtime.gopark" || name == "runtime.goparkunlock":
+				parked = true
+			case i == 0 && strings.Contains(name, "syscall."):
+				return "syscall"
+			}
+		}
+		if !parked {
+			// only parked goroutines have runtime.gopark as a leaf
+			return "running"
+		}
+	}
+	return "waiting"
+}
+
+func wallSampleTypes() []*pprofile.ValueType {
+	return []*pprofile.ValueType{{Type: "samples", Unit: "count"}, {Type: "wall", Unit: "nanoseconds"}}
+}
+
+func wallPeriodType() *pprofile.ValueType {
+	return &pprofile.ValueType{Type: "wall", Unit: "nanoseconds"}
+}
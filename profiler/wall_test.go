@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016 Datadog, Inc.
+
+package profiler
+
+import (
+	"context"
+	"fmt"
+	"runtime/pprof"
+	"testing"
+	"time"
+
+	pprofile "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/traceprof"
+)
+
+func TestWallProfile(t *testing.T) {
+	p, err := unstartedProfiler(WithPeriod(500*time.Millisecond), WithWallProfileInterval(100*time.Millisecond))
+	require.NoError(t, err)
+
+	// a request waiting on a channel, which doesn't show in the CPU profile
+	unblock := make(chan struct{})
+	defer close(unblock)
+	labels := pprof.Labels(traceprof.TraceEndpoint, "GET /slow", "unknown", "dropped")
+	go pprof.Do(context.Background(), labels, func(context.Context) { <-unblock })
+
+	data, err := collectWallProfile(p)
+	require.NoError(t, err)
+	prof, err := pprofile.ParseData(data)
+	require.NoError(t, err)
+	assert.Equal(t, "wall", prof.SampleType[1].Type)
+
+	var found bool
+	for _, s := range prof.Sample {
+		if s.Label[traceprof.TraceEndpoint] == nil {
+			continue
+		}
+		found = true
+		assert.Equal(t, []string{"chan receive"}, s.Label[waitReasonLabel])
+		assert.NotContains(t, s.Label, "unknown")
+		// the goroutine waited during the whole period
+		assert.InDelta(t, 500*time.Millisecond, time.Duration(s.Value[1]), float64(150*time.Millisecond))
+		assert.GreaterOrEqual(t, s.Value[0], int64(3))
+	}
+	assert.True(t, found)
+	assert.InDelta(t, 500*time.Millisecond, time.Duration(prof.DurationNanos), float64(150*time.Millisecond))
+
+	t.Run("interval", func(t *testing.T) {
+		p, err := unstartedProfiler()
+		require.NoError(t, err)
+		assert.Equal(t, DefaultWallInterval, p.cfg.wallInterval)
+		assert.NotContains(t, p.cfg.types, WallProfile)
+
+		p, err = unstartedProfiler(WithWallProfileInterval(0))
+		require.NoError(t, err)
+		assert.Equal(t, DefaultWallInterval, p.cfg.wallInterval)
+		assert.Contains(t, p.cfg.types, WallProfile)
+	})
+
+	t.Run("cut-short", func(t *testing.T) {
+		p, err := unstartedProfiler(WithPeriod(time.Hour))
+		require.NoError(t, err)
+		time.AfterFunc(200*time.Millisecond, func() { p.stop() })
+		start := time.Now()
+		data, err := collectWallProfile(p)
+		require.NoError(t, err)
+		prof, err := pprofile.ParseData(data)
+		require.NoError(t, err)
+		// the profile covers the time it was collected for, not the period
+		assert.InDelta(t, 200*time.Millisecond, time.Duration(prof.DurationNanos), float64(100*time.Millisecond))
+		assert.InDelta(t, start.UnixNano(), prof.TimeNanos, float64(50*time.Millisecond))
+	})
+}
+
+func TestWaitReason(t *testing.T) {
+	sample := func(fns ...string) *pprofile.Sample {
+		s := &pprofile.Sample{}
+		for _, fn := range fns {
+			s.Location = append(s.Location, &pprofile.Location{
+				Line: []pprofile.Line{{Function: &pprofile.Function{Name: fn}}},
+			})
+		}
+		return s
+	}
+	assert.Equal(t, "running", waitReason(sample("main.work", "main.main")))
+	assert.Equal(t, "syscall", waitReason(sample("syscall.Syscall6", "os.(*File).Read")))
+	assert.Equal(t, "chan receive", waitReason(sample("runtime.gopark", "runtime.chanrecv", "runtime.chanrecv1", "main.main")))
+	assert.Equal(t, "IO wait", waitReason(sample("runtime.gopark", "runtime.netpollblock", "internal/poll.runtime_pollWait")))
+	assert.Equal(t, "select", waitReason(sample("runtime.gopark", "runtime.selectgo", "main.main")))
+	assert.Equal(t, "waiting", waitReason(sample("runtime.gopark", "main.unknown")))
+}
+
+// BenchmarkWallSnapshot measures the cost of a sample of the wall-clock
+// profile, taken every wall profile interval, for various numbers of parked
+// goroutines. The overhead of the profile is this cost over the interval.
+func BenchmarkWallSnapshot(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("goroutines=%d", n), func(b *testing.B) {
+			unblock := make(chan struct{})
+			defer close(unblock)
+			for i := 0; i < n; i++ {
+				go func() { <-unblock }()
+			}
+			p, err := unstartedProfiler()
+			require.NoError(b, err)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := p.wallSnapshot(time.Second); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}